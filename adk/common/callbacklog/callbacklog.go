@@ -0,0 +1,435 @@
+package callbacklog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/usage"
+)
+
+// Format 日志的输出格式
+type Format string
+
+const (
+	// FormatText 每条记录一行，便于在终端中阅读
+	FormatText Format = "text"
+	// FormatJSONL 每条记录一个 JSON 对象（JSON Lines），便于用 jq 等工具处理
+	FormatJSONL Format = "jsonl"
+)
+
+// 记录的事件类型
+const (
+	EventStart     = "start"
+	EventEnd       = "end"
+	EventError     = "error"
+	EventStreamEnd = "stream_end" // 流式输出读完
+)
+
+// Config 回调日志的配置，0 值字段使用默认值
+type Config struct {
+	Writer        io.Writer // 输出位置，默认 os.Stdout
+	Format        Format    // 输出格式，默认 FormatText
+	MaxContentLen int       // 输入输出内容按字符截断的长度，默认 500，为负数时不截断
+}
+
+// Record 一条回调日志
+type Record struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Component  string    `json:"component"`             // 组件类型，如 ChatModel、Tool、Lambda、Graph
+	Type       string    `json:"type,omitempty"`        // 组件实现，如 Ark、OpenAI
+	Name       string    `json:"name,omitempty"`        // 节点名称
+	Agent      string    `json:"agent,omitempty"`       // 所在的 agent
+	RunPath    string    `json:"run_path,omitempty"`    // 执行地址，见 compose.GetCurrentAddress
+	DurationMS float64   `json:"duration_ms,omitempty"` // 从 start 到当前事件的耗时
+	Usage      *Usage    `json:"usage,omitempty"`
+	Input      string    `json:"input,omitempty"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Usage 一次调用的 token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	ReasoningTokens  int `json:"reasoning_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// NewHandler 创建覆盖所有组件类型（ChatModel、Tool、Retriever、Embedding、Lambda、Graph 等）的回调日志
+//
+// 每次调用输出 start 和 end（或 error）两条记录，流式输出在读完后再输出一条 stream_end，
+// 记录中带有耗时、token 用量、执行地址和 agent 名称
+func NewHandler(cfg *Config) callbacks.Handler {
+	l := &logger{w: os.Stdout, format: FormatText, maxLen: 500}
+	if cfg != nil {
+		if cfg.Writer != nil {
+			l.w = cfg.Writer
+		}
+		if cfg.Format != "" {
+			l.format = cfg.Format
+		}
+		if cfg.MaxContentLen != 0 {
+			l.maxLen = cfg.MaxContentLen
+		}
+	}
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(l.onStart).
+		OnEndFn(l.onEnd).
+		OnErrorFn(l.onError).
+		OnStartWithStreamInputFn(l.onStartWithStreamInput).
+		OnEndWithStreamOutputFn(l.onEndWithStreamOutput).
+		Build()
+}
+
+// NewHandlerFromEnv 按环境变量创建回调日志，未设置 CALLBACK_LOG 时返回 nil
+//
+//	CALLBACK_LOG=stdout|stderr|文件路径   输出位置，文件以追加方式打开
+//	CALLBACK_LOG_FORMAT=text|jsonl        默认 text
+//	CALLBACK_LOG_MAX_LEN=500              内容截断长度，-1 表示不截断
+//
+// 返回的 closeFn 用于关闭日志文件
+func NewHandlerFromEnv() (h callbacks.Handler, closeFn func() error, err error) {
+	closeFn = func() error { return nil }
+	dest := os.Getenv("CALLBACK_LOG")
+	if dest == "" {
+		return nil, closeFn, nil
+	}
+	cfg := &Config{Format: Format(strings.ToLower(os.Getenv("CALLBACK_LOG_FORMAT")))}
+	switch cfg.Format {
+	case "", FormatText, FormatJSONL:
+	default:
+		return nil, closeFn, fmt.Errorf("unknown CALLBACK_LOG_FORMAT %q", cfg.Format)
+	}
+	if v := os.Getenv("CALLBACK_LOG_MAX_LEN"); v != "" {
+		if cfg.MaxContentLen, err = strconv.Atoi(v); err != nil {
+			return nil, closeFn, fmt.Errorf("parse CALLBACK_LOG_MAX_LEN: %w", err)
+		}
+	}
+	switch dest {
+	case "stdout":
+		cfg.Writer = os.Stdout
+	case "stderr":
+		cfg.Writer = os.Stderr
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, closeFn, fmt.Errorf("open callback log: %w", err)
+		}
+		cfg.Writer, closeFn = f, f.Close
+	}
+	return NewHandler(cfg), closeFn, nil
+}
+
+type startTimeKey struct{}
+
+type logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+	maxLen int
+}
+
+func (l *logger) onStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	l.write(l.newRecord(ctx, info, EventStart, func(r *Record) {
+		r.Input = l.truncate(describeInput(info, input))
+	}))
+	return context.WithValue(ctx, startTimeKey{}, time.Now())
+}
+
+func (l *logger) onEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	l.write(l.newRecord(ctx, info, EventEnd, func(r *Record) {
+		r.Output = l.truncate(describeOutput(info, output))
+		r.Usage = usageOf(info, output)
+	}))
+	return ctx
+}
+
+func (l *logger) onError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	l.write(l.newRecord(ctx, info, EventError, func(r *Record) {
+		r.Error = l.truncate(err.Error())
+	}))
+	return ctx
+}
+
+func (l *logger) onStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	// 流式输入只记录开始，不读取内容；回调拿到的是副本，必须关闭
+	input.Close()
+	l.write(l.newRecord(ctx, info, EventStart, func(r *Record) {
+		r.Input = "<stream>"
+	}))
+	return context.WithValue(ctx, startTimeKey{}, time.Now())
+}
+
+func (l *logger) onEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	l.write(l.newRecord(ctx, info, EventEnd, func(r *Record) {
+		r.Output = "<stream>"
+	}))
+	go func() {
+		defer output.Close()
+		var chunks []callbacks.CallbackOutput
+		var streamErr error
+		for {
+			chunk, err := output.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				streamErr = err
+				break
+			}
+			chunks = append(chunks, chunk)
+		}
+		l.write(l.newRecord(ctx, info, EventStreamEnd, func(r *Record) {
+			r.Output = l.truncate(describeStream(info, chunks))
+			r.Usage = streamUsage(info, chunks)
+			if streamErr != nil {
+				r.Error = l.truncate(streamErr.Error())
+			}
+		}))
+	}()
+	return ctx
+}
+
+func (l *logger) newRecord(ctx context.Context, info *callbacks.RunInfo, event string, fill func(r *Record)) *Record {
+	r := &Record{Time: time.Now(), Event: event}
+	if info != nil {
+		r.Component, r.Type, r.Name = string(info.Component), info.Type, info.Name
+	}
+	addr := compose.GetCurrentAddress(ctx)
+	r.RunPath = addr.String()
+	for _, seg := range addr {
+		if seg.Type == adk.AddressSegmentAgent {
+			r.Agent = seg.ID
+		}
+	}
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok && event != EventStart {
+		r.DurationMS = float64(r.Time.Sub(start).Microseconds()) / 1000
+	}
+	fill(r)
+	return r
+}
+
+func (l *logger) write(r *Record) {
+	var line string
+	if l.format == FormatJSONL {
+		bs, err := json.Marshal(r)
+		if err != nil {
+			return
+		}
+		line = string(bs)
+	} else {
+		line = formatText(r)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = fmt.Fprintln(l.w, line)
+}
+
+func formatText(r *Record) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s [%s] %s", r.Time.Format("2006-01-02 15:04:05.000"), strings.ToUpper(r.Event), r.Component)
+	if r.Type != "" {
+		sb.WriteString("/" + r.Type)
+	}
+	if r.Name != "" {
+		fmt.Fprintf(&sb, " name=%s", r.Name)
+	}
+	if r.Agent != "" {
+		fmt.Fprintf(&sb, " agent=%s", r.Agent)
+	}
+	if r.RunPath != "" {
+		fmt.Fprintf(&sb, " path=%s", r.RunPath)
+	}
+	if r.DurationMS > 0 {
+		fmt.Fprintf(&sb, " cost=%.1fms", r.DurationMS)
+	}
+	if u := r.Usage; u != nil {
+		fmt.Fprintf(&sb, " tokens=%d/%d/%d", u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+		if u.ReasoningTokens > 0 {
+			fmt.Fprintf(&sb, " reasoning=%d", u.ReasoningTokens)
+		}
+	}
+	if r.Input != "" {
+		fmt.Fprintf(&sb, " input=%q", r.Input)
+	}
+	if r.Output != "" {
+		fmt.Fprintf(&sb, " output=%q", r.Output)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&sb, " error=%q", r.Error)
+	}
+	return sb.String()
+}
+
+// truncate 按字符截断，保留被截掉的字符数便于判断原始长度
+func (l *logger) truncate(s string) string {
+	if l.maxLen < 0 || utf8.RuneCountInString(s) <= l.maxLen {
+		return s
+	}
+	rs := []rune(s)
+	return fmt.Sprintf("%s...(%d more)", string(rs[:l.maxLen]), len(rs)-l.maxLen)
+}
+
+// describeInput 把各类组件的输入转换成便于阅读的文本，未知类型按 JSON 输出
+func describeInput(info *callbacks.RunInfo, input callbacks.CallbackInput) string {
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		if in := model.ConvCallbackInput(input); in != nil {
+			return describeMessages(in.Messages)
+		}
+	case components.ComponentOfTool:
+		if in := tool.ConvCallbackInput(input); in != nil {
+			return in.ArgumentsInJSON
+		}
+	case components.ComponentOfRetriever:
+		if in := retriever.ConvCallbackInput(input); in != nil {
+			return in.Query
+		}
+	case components.ComponentOfEmbedding:
+		if in := embedding.ConvCallbackInput(input); in != nil {
+			return fmt.Sprintf("%d texts: %s", len(in.Texts), strings.Join(in.Texts, " | "))
+		}
+	}
+	return toJSON(input)
+}
+
+// describeOutput 把各类组件的输出转换成便于阅读的文本，未知类型按 JSON 输出
+func describeOutput(info *callbacks.RunInfo, output callbacks.CallbackOutput) string {
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		if out := model.ConvCallbackOutput(output); out != nil {
+			return describeMessages([]*schema.Message{out.Message})
+		}
+	case components.ComponentOfTool:
+		if out := tool.ConvCallbackOutput(output); out != nil {
+			return out.Response
+		}
+	case components.ComponentOfRetriever:
+		if out := retriever.ConvCallbackOutput(output); out != nil {
+			parts := make([]string, 0, len(out.Docs))
+			for _, d := range out.Docs {
+				parts = append(parts, fmt.Sprintf("[%s %.3f] %s", d.ID, d.Score(), d.Content))
+			}
+			return fmt.Sprintf("%d docs: %s", len(out.Docs), strings.Join(parts, " | "))
+		}
+	case components.ComponentOfEmbedding:
+		if out := embedding.ConvCallbackOutput(output); out != nil {
+			dim := 0
+			if len(out.Embeddings) > 0 {
+				dim = len(out.Embeddings[0])
+			}
+			return fmt.Sprintf("%d vectors, dim=%d", len(out.Embeddings), dim)
+		}
+	}
+	return toJSON(output)
+}
+
+// describeStream 流式输出读完后的内容，ChatModel 拼接成完整消息，其他组件只记录 chunk 数
+func describeStream(info *callbacks.RunInfo, chunks []callbacks.CallbackOutput) string {
+	if info.Component != components.ComponentOfChatModel {
+		return fmt.Sprintf("%d chunks", len(chunks))
+	}
+	msgs := make([]*schema.Message, 0, len(chunks))
+	for _, c := range chunks {
+		if out := model.ConvCallbackOutput(c); out != nil && out.Message != nil {
+			msgs = append(msgs, out.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return ""
+	}
+	msg, err := schema.ConcatMessages(msgs)
+	if err != nil {
+		return fmt.Sprintf("%d chunks", len(chunks))
+	}
+	return describeMessages([]*schema.Message{msg})
+}
+
+func describeMessages(msgs []*schema.Message) string {
+	parts := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		var sb strings.Builder
+		sb.WriteString(string(m.Role) + ": ")
+		if m.ReasoningContent != "" {
+			sb.WriteString("<reasoning>" + m.ReasoningContent + "</reasoning> ")
+		}
+		sb.WriteString(m.Content)
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(&sb, " <call %s %s>", tc.Function.Name, tc.Function.Arguments)
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+func toJSON(v any) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
+
+func usageOf(info *callbacks.RunInfo, output callbacks.CallbackOutput) *Usage {
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		if out := model.ConvCallbackOutput(output); out != nil {
+			return chatUsage(out)
+		}
+	case components.ComponentOfEmbedding:
+		if out := embedding.ConvCallbackOutput(output); out != nil && out.TokenUsage != nil {
+			return &Usage{PromptTokens: out.TokenUsage.PromptTokens, TotalTokens: out.TokenUsage.TotalTokens}
+		}
+	}
+	return nil
+}
+
+func streamUsage(info *callbacks.RunInfo, chunks []callbacks.CallbackOutput) *Usage {
+	var u *Usage
+	for _, c := range chunks {
+		if cu := usageOf(info, c); cu != nil {
+			u = cu
+		}
+	}
+	return u
+}
+
+// chatUsage 把 ChatModel 回调输出中的用量转换为日志中的 Usage
+func chatUsage(out *model.CallbackOutput) *Usage {
+	tu := usage.TokenUsage(out)
+	if tu == nil {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     tu.PromptTokens,
+		CachedTokens:     tu.PromptTokenDetails.CachedTokens,
+		CompletionTokens: tu.CompletionTokens,
+		ReasoningTokens:  tu.CompletionTokensDetails.ReasoningTokens,
+		TotalTokens:      tu.TotalTokens,
+	}
+}
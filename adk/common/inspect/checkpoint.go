@@ -0,0 +1,240 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/redis/go-redis/v9"
+
+	"eino-learn/adk/common/store"
+)
+
+const checkpointUsage = `用法: checkpoint [-dir 目录 | -sqlite 文件 | -redis 地址] <命令> [参数]
+
+命令:
+  list [前缀]      列出检查点
+  show <id>        把检查点解码成 JSON：中断点、运行路径、消息历史和状态
+  diff <id1> <id2> 比较两个检查点解码后的内容
+
+不指定 -dir、-sqlite 和 -redis 时按 CHECKPOINT_* 环境变量打开存储，设置了 CHECKPOINT_KEYS 时自动解密
+`
+
+// RunCheckpoint 执行 checkpoint 子命令，args 不包含子命令名本身
+func RunCheckpoint(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprint(w, checkpointUsage) }
+	dir := fs.String("dir", "", "文件存储目录")
+	sqlitePath := fs.String("sqlite", "", "SQLite 数据库文件")
+	redisURL := fs.String("redis", "", "Redis 地址，例如 redis://localhost:6379/0")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing checkpoint command")
+	}
+
+	s, err := openStore(*dir, *sqlitePath, *redisURL)
+	if err != nil {
+		return err
+	}
+	if c, ok := s.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		prefix := ""
+		if len(rest) > 0 {
+			prefix = rest[0]
+		}
+		return listCheckpoints(ctx, s, prefix, w)
+	case "show":
+		if len(rest) != 1 {
+			return errors.New("usage: checkpoint show <id>")
+		}
+		snap, err := loadSnapshot(ctx, s, rest[0])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(snap)
+	case "diff":
+		if len(rest) != 2 {
+			return errors.New("usage: checkpoint diff <id1> <id2>")
+		}
+		return diffCheckpoints(ctx, s, rest[0], rest[1], w)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown checkpoint command %q", cmd)
+	}
+}
+
+func openStore(dir, sqlitePath, redisURL string) (compose.CheckPointStore, error) {
+	var s compose.CheckPointStore
+	var err error
+	switch {
+	case sqlitePath != "":
+		s, err = store.NewSQLiteStore(sqlitePath)
+	case redisURL != "":
+		var opts *redis.Options
+		if opts, err = redis.ParseURL(redisURL); err != nil {
+			return nil, err
+		}
+		s, err = store.NewRedisStore(store.RedisConfig{Client: redis.NewClient(opts), Prefix: os.Getenv("CHECKPOINT_REDIS_PREFIX")})
+	case dir != "":
+		s, err = store.NewFileStore(dir)
+	default:
+		return store.NewStoreFromEnv()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.WrapFromEnv(s)
+}
+
+func listCheckpoints(ctx context.Context, s compose.CheckPointStore, prefix string, w io.Writer) error {
+	admin, ok := s.(store.Admin)
+	if !ok {
+		return errors.New("checkpoint store does not support listing")
+	}
+	infos, err := admin.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSIZE\tUPDATED\tAGENT\tSESSION")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", info.ID, info.Size, info.UpdatedAt.Format(time.DateTime), info.Agent, info.SessionID)
+	}
+	return tw.Flush()
+}
+
+// loadSnapshot 只读取检查点，不占用它，查看时不影响其他副本恢复运行
+func loadSnapshot(ctx context.Context, s compose.CheckPointStore, id string) (*store.Snapshot, error) {
+	bs, ok, err := s.Get(store.WithReadOnly(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("checkpoint %q not found", id)
+	}
+	return store.DecodeCheckpoint(bs)
+}
+
+// diffCheckpoints 把两个检查点展开成 "路径 -> 值" 后逐项比较
+// 中断点按地址而不是随机生成的 ID 对齐，否则每次运行的检查点都会完全不同
+func diffCheckpoints(ctx context.Context, s compose.CheckPointStore, a, b string, w io.Writer) error {
+	left, err := loadSnapshot(ctx, s, a)
+	if err != nil {
+		return err
+	}
+	right, err := loadSnapshot(ctx, s, b)
+	if err != nil {
+		return err
+	}
+	lf, err := flattenSnapshot(left)
+	if err != nil {
+		return err
+	}
+	rf, err := flattenSnapshot(right)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(lf)+len(rf))
+	for p := range lf {
+		paths = append(paths, p)
+	}
+	for p := range rf {
+		if _, ok := lf[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", a, b)
+	changed := 0
+	for _, p := range paths {
+		lv, inLeft := lf[p]
+		rv, inRight := rf[p]
+		switch {
+		case !inRight:
+			fmt.Fprintf(w, "- %s: %s\n", p, lv)
+		case !inLeft:
+			fmt.Fprintf(w, "+ %s: %s\n", p, rv)
+		case lv != rv:
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", p, lv, rv)
+		default:
+			continue
+		}
+		changed++
+	}
+	if changed == 0 {
+		fmt.Fprintln(w, "(no differences)")
+	}
+	return nil
+}
+
+func flattenSnapshot(snap *store.Snapshot) (map[string]string, error) {
+	interrupts := make(map[string]store.SnapshotInterrupt, len(snap.Interrupts))
+	for _, it := range snap.Interrupts {
+		it.ID = ""
+		interrupts[it.Address] = it
+	}
+	view := struct {
+		*store.Snapshot
+		Interrupts map[string]store.SnapshotInterrupt `json:"interrupts"`
+	}{snap, interrupts}
+
+	bs, err := json.Marshal(view)
+	if err != nil {
+		return nil, err
+	}
+	// UseNumber 保留时间戳等大整数的精度
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	flatten("", v, out)
+	return out, nil
+}
+
+// flatten 把 JSON 值展开成叶子节点，路径形如 messages[0].message.content
+func flatten(path string, v any, out map[string]string) {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, cv := range x {
+			// 地址中含有 ';' 和 ':'，加引号以免与路径分隔混淆
+			if strings.ContainsAny(k, ".;:[") {
+				k = fmt.Sprintf("%q", k)
+			}
+			flatten(strings.TrimPrefix(path+"."+k, "."), cv, out)
+		}
+	case []any:
+		for i, cv := range x {
+			flatten(fmt.Sprintf("%s[%d]", path, i), cv, out)
+		}
+	default:
+		bs, _ := json.Marshal(x)
+		out[path] = string(bs)
+	}
+}
@@ -0,0 +1,70 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eino-learn/adk/common/transcript"
+)
+
+const replayUsage = `用法: replay [-format text|markdown|html] [-o 输出文件] <会话记录文件>
+
+重新渲染 TRANSCRIPT_DIR 中保存的会话记录：
+  text      与运行时的终端输出相同（默认）
+  markdown  Markdown 对话记录，便于贴到评审中
+  html      单个静态 HTML 页面，思考过程和工具调用可以折叠
+
+不指定 -format 时按 -o 的扩展名（.md / .html）选择格式；不指定 -o 时输出到标准输出
+`
+
+// RunReplay 执行 replay 子命令，args 不包含子命令名本身
+func RunReplay(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprint(w, replayUsage) }
+	format := fs.String("format", "", "输出格式")
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("usage: replay <transcript>")
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".md", ".markdown":
+			*format = transcript.FormatMarkdown
+		case ".html", ".htm":
+			*format = transcript.FormatHTML
+		}
+	}
+
+	t, err := transcript.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return transcript.Render(w, t, *format)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := transcript.Render(f, t, *format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d entries to %s\n", len(t.Entries), *output)
+	return nil
+}
@@ -0,0 +1,100 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"eino-learn/adk/common/trace"
+)
+
+const traceUsage = `用法: trace [-o 输出文件] [-trace 链路 ID 前缀] <命令> [JSONL 文件]
+
+命令:
+  html    生成单个静态 HTML 页面：可折叠的 Span 树和时间轴，点击 Span 查看输入输出
+  chrome  导出 Chrome trace event JSON，可以在 chrome://tracing 或 https://ui.perfetto.dev 中打开
+
+JSONL 文件由 TRACE_FILE 或 TRACE_BACKENDS=file 生成，默认 traces.jsonl；不指定 -o 时输出到标准输出
+`
+
+// RunTrace 执行 trace 子命令，args 不包含子命令名本身
+func RunTrace(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprint(w, traceUsage) }
+	output := fs.String("o", "", "输出文件")
+	traceID := fs.String("trace", "", "只导出 ID 以此开头的链路")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		fs.Usage()
+		return errors.New("usage: trace <html|chrome> [file]")
+	}
+
+	var write func(io.Writer, []*trace.SpanRecord) error
+	switch cmd := fs.Arg(0); cmd {
+	case "html":
+		write = trace.WriteHTML
+	case "chrome":
+		write = trace.WriteChromeTrace
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown trace command %q", cmd)
+	}
+
+	path := "traces.jsonl"
+	if fs.NArg() == 2 {
+		path = fs.Arg(1)
+	}
+	spans, err := readSpans(path, *traceID)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return write(w, spans)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := write(f, spans); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d spans to %s\n", len(spans), *output)
+	return nil
+}
+
+func readSpans(path, traceID string) ([]*trace.SpanRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	spans, err := trace.ReadSpans(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if traceID == "" {
+		return spans, nil
+	}
+	filtered := spans[:0]
+	for _, s := range spans {
+		if strings.HasPrefix(s.TraceID, traceID) {
+			filtered = append(filtered, s)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no trace matching %q in %s", traceID, path)
+	}
+	return filtered, nil
+}
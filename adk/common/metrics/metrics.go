@@ -0,0 +1,326 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"eino-learn/adk/common/usage"
+)
+
+// 调用结果，用作 status 标签
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Config 指标的配置，0 值字段使用默认值
+type Config struct {
+	Registerer prometheus.Registerer // 默认 prometheus.DefaultRegisterer
+	Namespace  string                // 指标名前缀，默认 eino
+	Buckets    []float64             // 耗时直方图的桶（秒），默认覆盖 10ms 到 2min，适合模型调用
+}
+
+// DefaultBuckets 耗时直方图的默认桶，模型调用从几百毫秒到几分钟不等，prometheus.DefBuckets 最大只到 10s
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+// NewHandler 创建记录 Prometheus 指标的回调，指标注册到 cfg.Registerer
+//
+// 所有指标都带有 agent、node 和 component 标签：agent 为所在的 Agent，node 为所在的图节点，component 为组件类型
+//
+//	{ns}_node_duration_seconds              所有组件的耗时，流式输出读完才算结束，额外标签 status
+//	{ns}_node_errors_total                  所有组件的错误数
+//	{ns}_model_duration_seconds             ChatModel 调用耗时，额外标签 model、status
+//	{ns}_model_time_to_first_token_seconds  ChatModel 流式输出的首 token 时间，额外标签 model
+//	{ns}_model_tokens_total                 token 用量，额外标签 model、type（prompt、cached、completion、reasoning）
+//	{ns}_tool_duration_seconds              工具调用耗时，额外标签 tool、status
+//	{ns}_tool_errors_total                  工具调用错误数，额外标签 tool
+//	{ns}_retriever_duration_seconds         检索耗时，额外标签 status
+//	{ns}_retriever_documents                每次检索命中的文档数，le="0" 的桶即未命中的次数
+//
+// 同一个 Registerer 上多次创建时复用已注册的指标
+func NewHandler(cfg *Config) (callbacks.Handler, error) {
+	reg, ns, buckets := prometheus.DefaultRegisterer, "eino", DefaultBuckets
+	if cfg != nil {
+		if cfg.Registerer != nil {
+			reg = cfg.Registerer
+		}
+		if cfg.Namespace != "" {
+			ns = cfg.Namespace
+		}
+		if len(cfg.Buckets) > 0 {
+			buckets = cfg.Buckets
+		}
+	}
+
+	labels := func(extra ...string) []string {
+		return append([]string{"agent", "node", "component"}, extra...)
+	}
+	var regErr error
+	histogram := func(name, help string, buckets []float64, extra ...string) *prometheus.HistogramVec {
+		h, err := register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: ns, Name: name, Help: help, Buckets: buckets}, labels(extra...)))
+		regErr = errors.Join(regErr, err)
+		return h
+	}
+	counter := func(name, help string, extra ...string) *prometheus.CounterVec {
+		c, err := register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: ns, Name: name, Help: help}, labels(extra...)))
+		regErr = errors.Join(regErr, err)
+		return c
+	}
+
+	r := &recorder{
+		nodeDuration:      histogram("node_duration_seconds", "Duration of every component callback, including streamed output.", buckets, "status"),
+		nodeErrors:        counter("node_errors_total", "Errors returned by components."),
+		modelDuration:     histogram("model_duration_seconds", "Duration of chat model calls.", buckets, "model", "status"),
+		modelTTFT:         histogram("model_time_to_first_token_seconds", "Time to the first chunk of streamed chat model output.", buckets, "model"),
+		modelTokens:       counter("model_tokens_total", "Tokens used by chat models.", "model", "type"),
+		toolDuration:      histogram("tool_duration_seconds", "Duration of tool calls.", buckets, "tool", "status"),
+		toolErrors:        counter("tool_errors_total", "Errors returned by tools.", "tool"),
+		retrieverDuration: histogram("retriever_duration_seconds", "Duration of retriever calls.", buckets, "status"),
+		retrieverDocs:     histogram("retriever_documents", "Documents returned per retriever call.", []float64{0, 1, 2, 5, 10, 20, 50, 100}),
+	}
+	if regErr != nil {
+		return nil, fmt.Errorf("register metrics: %w", regErr)
+	}
+
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(r.onStart).
+		OnEndFn(r.onEnd).
+		OnErrorFn(r.onError).
+		OnStartWithStreamInputFn(r.onStartWithStreamInput).
+		OnEndWithStreamOutputFn(r.onEndWithStreamOutput).
+		Build(), nil
+}
+
+// NewHandlerFromEnv 按环境变量创建指标回调并启动 HTTP 服务，未设置 METRICS_ADDR 时返回 nil
+//
+//	METRICS_ADDR=:9090   在该地址的 /metrics 上提供 Prometheus 文本格式的指标
+//
+// 返回的 closeFn 用于关闭 HTTP 服务
+func NewHandlerFromEnv() (h callbacks.Handler, closeFn func() error, err error) {
+	closeFn = func() error { return nil }
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		return nil, closeFn, nil
+	}
+	if h, err = NewHandler(nil); err != nil {
+		return nil, closeFn, err
+	}
+	if closeFn, err = Serve(addr, prometheus.DefaultGatherer); err != nil {
+		return nil, func() error { return nil }, err
+	}
+	return h, closeFn, nil
+}
+
+// Serve 在 addr 上启动 HTTP 服务，在 /metrics 上提供 gatherer 中的指标，返回关闭服务的函数
+// 监听失败时直接返回错误，而不是在后台协程中才发现端口被占用
+func Serve(addr string, gatherer prometheus.Gatherer) (closeFn func() error, err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen metrics addr: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "metrics server: %v\n", err)
+		}
+	}()
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}, nil
+}
+
+// register 注册指标，已经注册过同名指标时改用已注册的那个
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	err := reg.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing, nil
+		}
+	}
+	return c, err
+}
+
+type callState struct {
+	start time.Time
+	agent string
+	node  string
+	model string
+}
+
+type stateKey struct{}
+
+type recorder struct {
+	nodeDuration      *prometheus.HistogramVec
+	nodeErrors        *prometheus.CounterVec
+	modelDuration     *prometheus.HistogramVec
+	modelTTFT         *prometheus.HistogramVec
+	modelTokens       *prometheus.CounterVec
+	toolDuration      *prometheus.HistogramVec
+	toolErrors        *prometheus.CounterVec
+	retrieverDuration *prometheus.HistogramVec
+	retrieverDocs     *prometheus.HistogramVec
+}
+
+func (r *recorder) onStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	st := newCallState(ctx)
+	if info != nil && info.Component == components.ComponentOfChatModel {
+		if in := model.ConvCallbackInput(input); in != nil && in.Config != nil {
+			st.model = in.Config.Model
+		}
+	}
+	return context.WithValue(ctx, stateKey{}, st)
+}
+
+func (r *recorder) onStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	// 指标只需要开始时间，关闭不读的输入副本
+	input.Close()
+	return context.WithValue(ctx, stateKey{}, newCallState(ctx))
+}
+
+func (r *recorder) onEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	st, ok := ctx.Value(stateKey{}).(*callState)
+	if !ok || info == nil {
+		return ctx
+	}
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		if out := model.ConvCallbackOutput(output); out != nil {
+			r.recordTokens(st, info, out)
+		}
+	case components.ComponentOfRetriever:
+		if out := retriever.ConvCallbackOutput(output); out != nil {
+			r.retrieverDocs.WithLabelValues(st.agent, st.node, string(info.Component)).Observe(float64(len(out.Docs)))
+		}
+	}
+	r.observe(st, info, time.Since(st.start), nil)
+	return ctx
+}
+
+func (r *recorder) onError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	st, ok := ctx.Value(stateKey{}).(*callState)
+	if !ok || info == nil {
+		return ctx
+	}
+	r.observe(st, info, time.Since(st.start), err)
+	return ctx
+}
+
+// onEndWithStreamOutput 流读完后才记录耗时，ChatModel 额外记录首 token 时间和最后一个 chunk 中的用量
+func (r *recorder) onEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	st, ok := ctx.Value(stateKey{}).(*callState)
+	if !ok || info == nil {
+		output.Close()
+		return ctx
+	}
+	go func() {
+		defer output.Close()
+		var last *model.CallbackOutput
+		var streamErr error
+		first := true
+		for {
+			chunk, err := output.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				streamErr = err
+				break
+			}
+			if info.Component != components.ComponentOfChatModel {
+				continue
+			}
+			if first {
+				first = false
+				r.modelTTFT.WithLabelValues(st.agent, st.node, string(info.Component), st.model).Observe(time.Since(st.start).Seconds())
+			}
+			if out := model.ConvCallbackOutput(chunk); out != nil && usage.TokenUsage(out) != nil {
+				last = out
+			}
+		}
+		if last != nil {
+			r.recordTokens(st, info, last)
+		}
+		r.observe(st, info, time.Since(st.start), streamErr)
+	}()
+	return ctx
+}
+
+// observe 记录一次调用的耗时和错误，err 为 nil 时即成功
+func (r *recorder) observe(st *callState, info *callbacks.RunInfo, d time.Duration, err error) {
+	component := string(info.Component)
+	status := StatusOK
+	if err != nil {
+		status = StatusError
+		r.nodeErrors.WithLabelValues(st.agent, st.node, component).Inc()
+	}
+	r.nodeDuration.WithLabelValues(st.agent, st.node, component, status).Observe(d.Seconds())
+
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		r.modelDuration.WithLabelValues(st.agent, st.node, component, st.model, status).Observe(d.Seconds())
+	case components.ComponentOfTool:
+		r.toolDuration.WithLabelValues(st.agent, st.node, component, info.Name, status).Observe(d.Seconds())
+		if err != nil {
+			r.toolErrors.WithLabelValues(st.agent, st.node, component, info.Name).Inc()
+		}
+	case components.ComponentOfRetriever:
+		r.retrieverDuration.WithLabelValues(st.agent, st.node, component, status).Observe(d.Seconds())
+	}
+}
+
+func (r *recorder) recordTokens(st *callState, info *callbacks.RunInfo, out *model.CallbackOutput) {
+	u := usage.TokenUsage(out)
+	if u == nil {
+		return
+	}
+	// 输入中没有模型名时（例如使用默认模型）取输出中的
+	if st.model == "" && out.Config != nil {
+		st.model = out.Config.Model
+	}
+	component := string(info.Component)
+	r.modelTokens.WithLabelValues(st.agent, st.node, component, st.model, "prompt").Add(float64(u.PromptTokens))
+	r.modelTokens.WithLabelValues(st.agent, st.node, component, st.model, "completion").Add(float64(u.CompletionTokens))
+	if u.PromptTokenDetails.CachedTokens > 0 {
+		r.modelTokens.WithLabelValues(st.agent, st.node, component, st.model, "cached").Add(float64(u.PromptTokenDetails.CachedTokens))
+	}
+	if u.CompletionTokensDetails.ReasoningTokens > 0 {
+		r.modelTokens.WithLabelValues(st.agent, st.node, component, st.model, "reasoning").Add(float64(u.CompletionTokensDetails.ReasoningTokens))
+	}
+}
+
+// newCallState 从执行地址中取出所在的 Agent 和图节点
+func newCallState(ctx context.Context) *callState {
+	st := &callState{start: time.Now()}
+	for _, seg := range compose.GetCurrentAddress(ctx) {
+		switch seg.Type {
+		case adk.AddressSegmentAgent:
+			st.agent = seg.ID
+		case compose.AddressSegmentNode:
+			st.node = seg.ID
+		}
+	}
+	return st
+}
@@ -0,0 +1,346 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// CacheStore 响应缓存的存储后端，接口形式与 compose.CheckPointStore 保持一致
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// NewLRUCache 创建内存 LRU 缓存，超过 capacity 时淘汰最久未使用的条目；ttl 为 0 表示不过期
+func NewLRUCache(capacity int, ttl time.Duration) CacheStore {
+	return &lruCache{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ll       *list.List // 队头为最近使用
+	items    map[string]*list.Element
+}
+
+func (c *lruCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (c *lruCache) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value = &lruEntry{key: key, value: value, expiresAt: expiresAt}
+		c.ll.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.capacity > 0 && c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// NewDiskCache 创建磁盘缓存，每个条目一个文件；ttl 为 0 表示不过期
+func NewDiskCache(dir string, ttl time.Duration) (CacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	return &diskCache{dir: dir, ttl: ttl}, nil
+}
+
+type diskEntry struct {
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Value     []byte    `json:"value"`
+}
+
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// path 按 key 的前两位分目录，避免单个目录下文件过多
+func (c *diskCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key+".json")
+	}
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+func (c *diskCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	bs, err := os.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	e := &diskEntry{}
+	if err := json.Unmarshal(bs, e); err != nil {
+		// 文件损坏当作未命中，下次 Set 会覆盖
+		return nil, false, nil
+	}
+	if !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt) {
+		_ = os.Remove(c.path(key))
+		return nil, false, nil
+	}
+	return e.Value, true, nil
+}
+
+// Set 先写临时文件再 rename，并发读取时不会读到写了一半的文件
+func (c *diskCache) Set(_ context.Context, key string, value []byte) error {
+	e := &diskEntry{Value: value}
+	if c.ttl > 0 {
+		e.ExpiresAt = time.Now().Add(c.ttl)
+	}
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// cachedResponse 缓存中保存的模型响应，Generate 保存 Message，Stream 保存 Chunks
+type cachedResponse struct {
+	Message *schema.Message   `json:"message,omitempty"`
+	Chunks  []*schema.Message `json:"chunks,omitempty"`
+}
+
+// NewCachedChatModel 为 ChatModel 加上响应缓存
+//
+// key 由 scope、归一化后的消息、工具定义和模型参数计算（见 ChatKey）；
+// 流式调用命中时按缓存的 chunk 逐个返回。命中缓存不产生费用，返回的消息不带 Usage
+func NewCachedChatModel(cm model.ToolCallingChatModel, store CacheStore, scope string) model.ToolCallingChatModel {
+	return &cachedChatModel{inner: cm, store: store, scope: scope}
+}
+
+type cachedChatModel struct {
+	inner model.ToolCallingChatModel
+	store CacheStore
+	scope string
+	tools []*schema.ToolInfo
+}
+
+func (m *cachedChatModel) lookup(ctx context.Context, key string) (*cachedResponse, bool) {
+	bs, ok, err := m.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	resp := &cachedResponse{}
+	if err := json.Unmarshal(bs, resp); err != nil {
+		return nil, false
+	}
+	return resp, resp.Message != nil || len(resp.Chunks) > 0
+}
+
+func (m *cachedChatModel) save(ctx context.Context, key string, resp *cachedResponse) {
+	bs, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	// 缓存写入失败不影响本次调用
+	_ = m.store.Set(ctx, key, bs)
+}
+
+func (m *cachedChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	key, _, err := ChatKey(m.scope, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if resp, ok := m.lookup(ctx, key); ok {
+		return generateWithCallbacks(ctx, m.GetType(), input, m.tools, func(context.Context) (*schema.Message, error) {
+			if resp.Message != nil {
+				return withoutUsage(resp.Message), nil
+			}
+			msg, err := schema.ConcatMessages(resp.Chunks)
+			if err != nil {
+				return nil, err
+			}
+			return withoutUsage(msg), nil
+		})
+	}
+
+	msg, err := callGenerate(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.save(ctx, key, &cachedResponse{Message: msg})
+	return msg, nil
+}
+
+func (m *cachedChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	key, _, err := ChatKey(m.scope, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if resp, ok := m.lookup(ctx, key); ok {
+		return streamWithCallbacks(ctx, m.GetType(), input, m.tools, func(context.Context) (*schema.StreamReader[*schema.Message], error) {
+			chunks := resp.Chunks
+			if len(chunks) == 0 {
+				chunks = []*schema.Message{resp.Message}
+			}
+			out := make([]*schema.Message, 0, len(chunks))
+			for _, c := range chunks {
+				out = append(out, withoutUsage(c))
+			}
+			return schema.StreamReaderFromArray(out), nil
+		})
+	}
+
+	sr, err := callStream(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return teeStream(sr, func(chunks []*schema.Message, err error) {
+		// 只缓存完整的流
+		if err == nil && len(chunks) > 0 {
+			m.save(context.WithoutCancel(ctx), key, &cachedResponse{Chunks: chunks})
+		}
+	}), nil
+}
+
+func (m *cachedChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedChatModel{inner: inner, store: m.store, scope: m.scope, tools: tools}, nil
+}
+
+func (m *cachedChatModel) GetType() string {
+	return "Cached"
+}
+
+func (m *cachedChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+// NewCachedEmbedder 为 Embedder 加上按文本粒度的缓存
+//
+// 每段文本单独缓存，一次调用中只有未命中的文本会发给下游，适合反复重建索引的场景
+func NewCachedEmbedder(e embedding.Embedder, store CacheStore, scope string) embedding.Embedder {
+	return &cachedEmbedder{inner: e, store: store, scope: scope}
+}
+
+type cachedEmbedder struct {
+	inner embedding.Embedder
+	store CacheStore
+	scope string
+}
+
+func (e *cachedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	var missIdx []int
+	var missTexts []string
+	for i, text := range texts {
+		key, _, err := EmbedKey(e.scope, []string{text}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		if bs, ok, err := e.store.Get(ctx, key); err == nil && ok && json.Unmarshal(bs, &vectors[i]) == nil {
+			continue
+		}
+		missIdx = append(missIdx, i)
+		missTexts = append(missTexts, text)
+	}
+	if len(missTexts) == 0 {
+		return embedWithCallbacks(ctx, e.GetType(), texts, func(context.Context) ([][]float64, error) {
+			return vectors, nil
+		})
+	}
+
+	embedded, err := callEmbed(ctx, e.GetType(), e.inner, missTexts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missTexts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(embedded), len(missTexts))
+	}
+	for j, i := range missIdx {
+		vectors[i] = embedded[j]
+		if bs, err := json.Marshal(embedded[j]); err == nil {
+			_ = e.store.Set(ctx, keys[i], bs)
+		}
+	}
+	return vectors, nil
+}
+
+func (e *cachedEmbedder) GetType() string {
+	return "Cached"
+}
+
+func (e *cachedEmbedder) IsCallbacksEnabled() bool {
+	return true
+}
+
+// withoutUsage 返回去掉 Usage 的浅拷贝，避免缓存命中被计入 token 用量
+func withoutUsage(m *schema.Message) *schema.Message {
+	if m == nil || m.ResponseMeta == nil || m.ResponseMeta.Usage == nil {
+		return m
+	}
+	c := *m
+	meta := *m.ResponseMeta
+	meta.Usage = nil
+	c.ResponseMeta = &meta
+	return &c
+}
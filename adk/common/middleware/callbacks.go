@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/usage"
+)
+
+// 中间件都实现了 IsCallbacksEnabled() == true，这样图框架不会在外面再包一层回调。
+// 转发给自带回调的下游组件时由下游触发回调；没有调用下游（回放、命中缓存）
+// 或下游不触发回调时，由下面的函数代为触发，保证每次调用都能被回调看到。
+
+// generateWithCallbacks 触发模型回调并执行 fn
+func generateWithCallbacks(ctx context.Context, typ string, input []*schema.Message, tools []*schema.ToolInfo,
+	fn func(ctx context.Context) (*schema.Message, error)) (*schema.Message, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, typ, components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input, Tools: tools})
+	msg, err := fn(ctx)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: msg, TokenUsage: usage.MessageTokenUsage(msg)})
+	return msg, nil
+}
+
+// streamWithCallbacks 触发模型回调并执行 fn，返回的流需要由调用方读取和关闭
+func streamWithCallbacks(ctx context.Context, typ string, input []*schema.Message, tools []*schema.ToolInfo,
+	fn func(ctx context.Context) (*schema.StreamReader[*schema.Message], error)) (*schema.StreamReader[*schema.Message], error) {
+	ctx = callbacks.EnsureRunInfo(ctx, typ, components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input, Tools: tools})
+	sr, err := fn(ctx)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	_, nsr := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderWithConvert(sr,
+		func(m *schema.Message) (callbacks.CallbackOutput, error) {
+			return &model.CallbackOutput{Message: m, TokenUsage: usage.MessageTokenUsage(m)}, nil
+		}))
+	return schema.StreamReaderWithConvert(nsr, func(o callbacks.CallbackOutput) (*schema.Message, error) {
+		return o.(*model.CallbackOutput).Message, nil
+	}), nil
+}
+
+// embedWithCallbacks 触发向量化回调并执行 fn
+func embedWithCallbacks(ctx context.Context, typ string, texts []string,
+	fn func(ctx context.Context) ([][]float64, error)) ([][]float64, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, typ, components.ComponentOfEmbedding)
+	ctx = callbacks.OnStart(ctx, &embedding.CallbackInput{Texts: texts})
+	vectors, err := fn(ctx)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &embedding.CallbackOutput{Embeddings: vectors})
+	return vectors, nil
+}
+
+// callGenerate 调用下游模型的 Generate，下游不触发回调时代为触发
+func callGenerate(ctx context.Context, typ string, inner model.BaseChatModel, input []*schema.Message,
+	tools []*schema.ToolInfo, opts ...model.Option) (*schema.Message, error) {
+	if components.IsCallbacksEnabled(inner) {
+		return inner.Generate(ctx, input, opts...)
+	}
+	return generateWithCallbacks(ctx, typ, input, tools, func(ctx context.Context) (*schema.Message, error) {
+		return inner.Generate(ctx, input, opts...)
+	})
+}
+
+// callStream 调用下游模型的 Stream，下游不触发回调时代为触发
+func callStream(ctx context.Context, typ string, inner model.BaseChatModel, input []*schema.Message,
+	tools []*schema.ToolInfo, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if components.IsCallbacksEnabled(inner) {
+		return inner.Stream(ctx, input, opts...)
+	}
+	return streamWithCallbacks(ctx, typ, input, tools, func(ctx context.Context) (*schema.StreamReader[*schema.Message], error) {
+		return inner.Stream(ctx, input, opts...)
+	})
+}
+
+// callEmbed 调用下游的 EmbedStrings，下游不触发回调时代为触发
+func callEmbed(ctx context.Context, typ string, inner embedding.Embedder, texts []string,
+	opts ...embedding.Option) ([][]float64, error) {
+	if components.IsCallbacksEnabled(inner) {
+		return inner.EmbedStrings(ctx, texts, opts...)
+	}
+	return embedWithCallbacks(ctx, typ, texts, func(ctx context.Context) ([][]float64, error) {
+		return inner.EmbedStrings(ctx, texts, opts...)
+	})
+}
@@ -0,0 +1,420 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Mode 录制文件（cassette）的工作模式
+type Mode string
+
+const (
+	// ModeRecord 调用真实的模型，并把每一对请求和响应写入录制文件
+	ModeRecord Mode = "record"
+	// ModeReplay 不调用真实的模型，按请求的归一化 key 从录制文件中取出响应
+	ModeReplay Mode = "replay"
+)
+
+// cassetteVersion 录制文件的格式版本，2 起为 JSON Lines
+const cassetteVersion = 2
+
+// ErrNotRecorded 回放时录制文件中没有对应的请求
+var ErrNotRecorded = errors.New("request not found in cassette")
+
+// Interaction 录制文件中的一次调用
+type Interaction struct {
+	Key        string            `json:"key"`
+	Request    json.RawMessage   `json:"request"`              // 归一化后的请求，便于人工查看
+	Message    *schema.Message   `json:"message,omitempty"`    // Generate 的响应
+	Chunks     []*schema.Message `json:"chunks,omitempty"`     // Stream 的全部 chunk
+	Embeddings [][]float64       `json:"embeddings,omitempty"` // EmbedStrings 的响应
+	Error      string            `json:"error,omitempty"`      // 调用失败（或流中途失败）时的错误
+}
+
+// cassetteHeader 录制文件的第一行
+type cassetteHeader struct {
+	Version int `json:"version"`
+}
+
+// Cassette 一个录制文件，可以同时给多个 ChatModel 和 Embedder 使用
+//
+// 文件为 JSON Lines：第一行是 cassetteHeader，之后每行一个 Interaction，按调用结束的顺序排列
+type Cassette struct {
+	path string
+	mode Mode
+
+	mu     sync.Mutex
+	f      *os.File // record 模式下追加写入的文件
+	byKey  map[string][]*Interaction
+	cursor map[string]int // 回放时每个 key 下一次要返回的位置
+}
+
+// OpenCassette 打开录制文件
+//
+// record 模式会清空已有内容重新录制，每录到一次调用就在文件末尾追加一行；
+// replay 模式要求文件已存在，同一个请求出现多次时按录制顺序依次返回，用完后重复返回最后一次
+func OpenCassette(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		mode:   mode,
+		byKey:  make(map[string][]*Interaction),
+		cursor: make(map[string]int),
+	}
+	switch mode {
+	case ModeRecord:
+		f, err := createCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		c.f = f
+		return c, nil
+	case ModeReplay:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read cassette: %w", err)
+		}
+		defer f.Close()
+		its, err := readCassette(f)
+		if err != nil {
+			return nil, fmt.Errorf("parse cassette %q: %w", path, err)
+		}
+		for _, it := range its {
+			c.byKey[it.Key] = append(c.byKey[it.Key], it)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown cassette mode %q", mode)
+}
+
+// createCassette 在 path 所在目录写好只有文件头的临时文件，再 rename 到 path，返回的文件用于追加之后的调用
+// 临时文件名由 os.CreateTemp 生成，多个进程同时录制时不会互相覆盖
+func createCassette(path string) (*os.File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cassette dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create cassette: %w", err)
+	}
+	bs, _ := json.Marshal(&cassetteHeader{Version: cassetteVersion})
+	if _, err = f.Write(append(bs, '\n')); err == nil {
+		if err = f.Chmod(0o644); err == nil {
+			err = os.Rename(f.Name(), path)
+		}
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("create cassette: %w", err)
+	}
+	return f, nil
+}
+
+// readCassette 读取录制文件中的所有调用；最后一行不完整（录制进程被中止）时忽略该行
+func readCassette(r io.Reader) ([]*Interaction, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty cassette")
+	}
+	h := &cassetteHeader{}
+	if err := json.Unmarshal(sc.Bytes(), h); err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+	if h.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported version %d", h.Version)
+	}
+
+	var its []*Interaction
+	var pending error
+	for line := 2; sc.Scan(); line++ {
+		if pending != nil {
+			return nil, pending
+		}
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		it := &Interaction{}
+		if err := json.Unmarshal(sc.Bytes(), it); err != nil {
+			pending = fmt.Errorf("line %d: %w", line, err)
+			continue
+		}
+		its = append(its, it)
+	}
+	return its, sc.Err()
+}
+
+// Mode 返回录制文件的工作模式
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// record 在文件末尾追加一次调用，一次 Write 写入整行
+func (c *Cassette) record(it *Interaction) error {
+	bs, err := json.Marshal(it)
+	if err != nil {
+		return fmt.Errorf("marshal cassette interaction: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil {
+		return errors.New("cassette is closed")
+	}
+	if _, err := c.f.Write(append(bs, '\n')); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭 record 模式下打开的文件，之后的录制会返回错误；已录制的调用都已写入文件
+func (c *Cassette) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil {
+		return nil
+	}
+	err := c.f.Close()
+	c.f = nil
+	return err
+}
+
+// replay 取出 key 对应的下一次调用
+func (c *Cassette) replay(key string) (*Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	its := c.byKey[key]
+	if len(its) == 0 {
+		return nil, fmt.Errorf("%w: key=%s", ErrNotRecorded, key)
+	}
+	i := c.cursor[key]
+	if i >= len(its) {
+		i = len(its) - 1
+	}
+	c.cursor[key] = i + 1
+	return its[i], nil
+}
+
+// WrapChatModel 用录制文件包装 ChatModel
+//
+// scope 参与 key 的计算，用于区分不同的 agent 或模型；replay 模式下 cm 可以为 nil
+func (c *Cassette) WrapChatModel(scope string, cm model.ToolCallingChatModel) model.ToolCallingChatModel {
+	return &cassetteChatModel{c: c, scope: scope, inner: cm}
+}
+
+// WrapEmbedder 用录制文件包装 Embedder，replay 模式下 e 可以为 nil
+func (c *Cassette) WrapEmbedder(scope string, e embedding.Embedder) embedding.Embedder {
+	return &cassetteEmbedder{c: c, scope: scope, inner: e}
+}
+
+type cassetteChatModel struct {
+	c     *Cassette
+	scope string
+	inner model.ToolCallingChatModel
+	tools []*schema.ToolInfo
+}
+
+func (m *cassetteChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	key, req, err := ChatKey(m.scope, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if m.c.mode == ModeReplay {
+		return generateWithCallbacks(ctx, m.GetType(), input, m.tools, func(context.Context) (*schema.Message, error) {
+			it, err := m.c.replay(key)
+			if err != nil {
+				return nil, err
+			}
+			if it.Message == nil && len(it.Chunks) > 0 {
+				// 录制时是流式调用，回放时把 chunk 拼成完整消息
+				msg, cErr := schema.ConcatMessages(it.Chunks)
+				if cErr != nil {
+					return nil, cErr
+				}
+				return msg, replayErr(it)
+			}
+			return it.Message, replayErr(it)
+		})
+	}
+
+	msg, err := callGenerate(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	it := &Interaction{Key: key, Request: req, Message: msg}
+	if err != nil {
+		it.Error = err.Error()
+	}
+	if rErr := m.c.record(it); rErr != nil {
+		return nil, rErr
+	}
+	return msg, err
+}
+
+func (m *cassetteChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	key, req, err := ChatKey(m.scope, input, m.tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if m.c.mode == ModeReplay {
+		return streamWithCallbacks(ctx, m.GetType(), input, m.tools, func(context.Context) (*schema.StreamReader[*schema.Message], error) {
+			it, err := m.c.replay(key)
+			if err != nil {
+				return nil, err
+			}
+			chunks := it.Chunks
+			if len(chunks) == 0 && it.Message != nil {
+				// 录制时是非流式调用，回放时作为单个 chunk 返回
+				chunks = []*schema.Message{it.Message}
+			}
+			if len(chunks) == 0 && it.Error != "" {
+				return nil, replayErr(it)
+			}
+			return chunksToStream(chunks, replayErr(it)), nil
+		})
+	}
+
+	sr, err := callStream(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	if err != nil {
+		if rErr := m.c.record(&Interaction{Key: key, Request: req, Error: err.Error()}); rErr != nil {
+			return nil, rErr
+		}
+		return nil, err
+	}
+	return teeStream(sr, func(chunks []*schema.Message, err error) {
+		it := &Interaction{Key: key, Request: req, Chunks: chunks}
+		if err != nil {
+			it.Error = err.Error()
+		}
+		// 流已经交给调用方，写文件失败只能忽略，下次录制时会暴露出来
+		_ = m.c.record(it)
+	}), nil
+}
+
+func (m *cassetteChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	nm := *m
+	nm.tools = tools
+	if m.inner != nil {
+		inner, err := m.inner.WithTools(tools)
+		if err != nil {
+			return nil, err
+		}
+		nm.inner = inner
+	}
+	return &nm, nil
+}
+
+func (m *cassetteChatModel) GetType() string {
+	return "Cassette"
+}
+
+func (m *cassetteChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+type cassetteEmbedder struct {
+	c     *Cassette
+	scope string
+	inner embedding.Embedder
+}
+
+func (e *cassetteEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	key, req, err := EmbedKey(e.scope, texts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if e.c.mode == ModeReplay {
+		return embedWithCallbacks(ctx, e.GetType(), texts, func(context.Context) ([][]float64, error) {
+			it, err := e.c.replay(key)
+			if err != nil {
+				return nil, err
+			}
+			return it.Embeddings, replayErr(it)
+		})
+	}
+
+	vectors, err := callEmbed(ctx, e.GetType(), e.inner, texts, opts...)
+	it := &Interaction{Key: key, Request: req, Embeddings: vectors}
+	if err != nil {
+		it.Error = err.Error()
+	}
+	if rErr := e.c.record(it); rErr != nil {
+		return nil, rErr
+	}
+	return vectors, err
+}
+
+func (e *cassetteEmbedder) GetType() string {
+	return "Cassette"
+}
+
+func (e *cassetteEmbedder) IsCallbacksEnabled() bool {
+	return true
+}
+
+// replayErr 还原录制时的错误
+func replayErr(it *Interaction) error {
+	if it.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("replayed error: %s", it.Error)
+}
+
+// chunksToStream 把 chunk 依次放入流中，err 不为 nil 时在最后返回该错误
+func chunksToStream(chunks []*schema.Message, err error) *schema.StreamReader[*schema.Message] {
+	if err == nil {
+		return schema.StreamReaderFromArray(chunks)
+	}
+	sr, sw := schema.Pipe[*schema.Message](len(chunks) + 1)
+	go func() {
+		defer sw.Close()
+		for _, c := range chunks {
+			if closed := sw.Send(c, nil); closed {
+				return
+			}
+		}
+		sw.Send(nil, err)
+	}()
+	return sr
+}
+
+// teeStream 边转发边收集 chunk，流结束（EOF 或出错）时调用 done；调用方提前关闭流时不调用
+func teeStream(sr *schema.StreamReader[*schema.Message], done func(chunks []*schema.Message, err error)) *schema.StreamReader[*schema.Message] {
+	out, sw := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer func() {
+			sr.Close()
+			sw.Close()
+		}()
+		var chunks []*schema.Message
+		for {
+			chunk, err := sr.Recv()
+			if err == io.EOF {
+				done(chunks, nil)
+				return
+			}
+			if err != nil {
+				done(chunks, err)
+				sw.Send(nil, err)
+				return
+			}
+			chunks = append(chunks, chunk)
+			if closed := sw.Send(chunk, nil); closed {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+func fakeScript(t *testing.T, responses ...*cmodel.FakeResponse) *cmodel.FakeScript {
+	t.Helper()
+	return &cmodel.FakeScript{ChunkSize: 2, Agents: map[string][]*cmodel.FakeResponse{"a": responses}}
+}
+
+// readAll 读完流，返回拼接的内容和最后的错误
+func readAll(sr *schema.StreamReader[*schema.Message]) (string, error) {
+	defer sr.Close()
+	var sb strings.Builder
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+		sb.WriteString(chunk.Content)
+	}
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sub", "session.jsonl")
+	fake, err := cmodel.NewFakeChatModel(fakeScript(t,
+		&cmodel.FakeResponse{Content: "generated"},
+		&cmodel.FakeResponse{Content: "streamed"},
+		&cmodel.FakeResponse{Error: "status code: 500"},
+	), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := OpenCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := rec.WrapChatModel("a", fake)
+	q1 := []*schema.Message{schema.UserMessage("one")}
+	q2 := []*schema.Message{schema.UserMessage("two")}
+	q3 := []*schema.Message{schema.UserMessage("three")}
+	if _, err := m.Generate(ctx, q1); err != nil {
+		t.Fatal(err)
+	}
+	sr, err := m.Stream(ctx, q2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAll(sr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Generate(ctx, q3); err == nil {
+		t.Fatal("want scripted error")
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件头加三次调用，每次一行；目录中没有留下临时文件
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(bs), "\n"); lines != 4 {
+		t.Fatalf("cassette has %d lines, want 4:\n%s", lines, bs)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("dir has %d entries, want only the cassette", len(entries))
+	}
+
+	play, err := OpenCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = play.WrapChatModel("a", nil)
+	// 录制时的流式调用可以用 Generate 回放，反之亦然
+	msg, err := m.Generate(ctx, q2)
+	if err != nil || msg.Content != "streamed" {
+		t.Fatalf("replay generate = %v, %v", msg, err)
+	}
+	sr, err = m.Stream(ctx, q1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := readAll(sr); err != nil || content != "generated" {
+		t.Fatalf("replay stream = %q, %v", content, err)
+	}
+	if _, err := m.Generate(ctx, q3); err == nil || !strings.Contains(err.Error(), "status code: 500") {
+		t.Fatalf("replay error = %v, want the recorded error", err)
+	}
+	if _, err := m.Generate(ctx, []*schema.Message{schema.UserMessage("unknown")}); !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("err = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestCassetteTruncatedLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"version":2}
+{"key":"k1","request":{},"message":{"role":"assistant","content":"ok"}}
+{"key":"k2","requ`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := OpenCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it, err := c.replay("k1"); err != nil || it.Message.Content != "ok" {
+		t.Fatalf("replay k1 = %+v, %v", it, err)
+	}
+
+	// 中间的行损坏时报错
+	if err := os.WriteFile(path, []byte(content+"\n"+`{"key":"k3"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCassette(path, ModeReplay); err == nil {
+		t.Fatal("want error for a corrupted line in the middle")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":1,"interactions":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCassette(path, ModeReplay); err == nil || !strings.Contains(err.Error(), "unsupported version 1") {
+		t.Fatalf("err = %v, want unsupported version", err)
+	}
+}
+
+// TestCassetteConcurrentRecorders 两个进程同时录制同一个文件时各自使用不同的临时文件，文件不会损坏
+func TestCassetteConcurrentRecorders(t *testing.T) {
+	dir := t.TempDir()
+	a, err := OpenCassette(filepath.Join(dir, "a.jsonl"), ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := OpenCassette(filepath.Join(dir, "a.jsonl"), ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if err := b.record(&Interaction{Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	its, err := func() ([]*Interaction, error) {
+		f, err := os.Open(filepath.Join(dir, "a.jsonl"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readCassette(f)
+	}()
+	if err != nil || len(its) != 1 || its[0].Key != "k" {
+		t.Fatalf("cassette = %+v, %v; want the later recorder's interaction", its, err)
+	}
+}
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+// 通过环境变量开启的中间件：
+//
+//	MODEL_CASSETTE=testdata/session.jsonl 录制文件路径，为空时不开启录制/回放
+//	MODEL_CASSETTE_MODE=record|replay     默认为 replay
+//	MODEL_CACHE=memory|disk               响应缓存，为空时不开启
+//	MODEL_CACHE_DIR=.cache/model          磁盘缓存目录，默认 .cache/model
+//	MODEL_CACHE_TTL=24h                   缓存有效期，默认 24h，0 表示不过期
+//	MODEL_CACHE_SIZE=1024                 内存缓存的最大条目数，默认 1024
+//
+// 限流与重试按 MODEL_CONFIG 配置文件中的 providers 设置，未配置时只按默认策略重试
+
+func init() {
+	// 引入本包后，model.Registry 创建的每个后端都会按提供方策略套上限流和重试，开启缓存时再套上缓存
+	cmodel.RegisterChatModelWrapper(wrapWithPolicy)
+	cmodel.RegisterChatModelWrapper(wrapWithCache)
+}
+
+// wrapWithPolicy 重试套在限流外面，每次重试都重新排队等待配额
+func wrapWithPolicy(p *cmodel.Profile, cm model.ToolCallingChatModel) (model.ToolCallingChatModel, error) {
+	if p.Provider == cmodel.ProviderFake {
+		return cm, nil
+	}
+	if pol := p.Policy; pol != nil && (pol.RequestsPerMinute > 0 || pol.TokensPerMinute > 0) {
+		cm = NewRateLimitedChatModel(cm, limiterFor(p.Provider, pol.RequestsPerMinute, pol.TokensPerMinute))
+	}
+	return NewRetryChatModel(cm, retryConfigFrom(p.Policy)), nil
+}
+
+// wrapWithCache 缓存套在重试外面，命中时不占用限流配额
+// fake 按轮次返回脚本中的响应，缓存会打乱顺序，因此不缓存
+func wrapWithCache(p *cmodel.Profile, cm model.ToolCallingChatModel) (model.ToolCallingChatModel, error) {
+	if p.Provider == cmodel.ProviderFake {
+		return cm, nil
+	}
+	store, err := cacheFromEnv()
+	if err != nil || store == nil {
+		return cm, err
+	}
+	return NewCachedChatModel(cm, store, profileScope(p)), nil
+}
+
+// profileScope 把影响输出的模型配置放进缓存 key，切换模型或调整参数后不会命中旧的缓存
+func profileScope(p *cmodel.Profile) string {
+	parts := []string{p.Provider, p.Model, p.BaseURL, p.Thinking}
+	if p.Temperature != nil {
+		parts = append(parts, "t="+strconv.FormatFloat(float64(*p.Temperature), 'g', -1, 32))
+	}
+	if p.MaxTokens != nil {
+		parts = append(parts, "max="+strconv.Itoa(*p.MaxTokens))
+	}
+	if p.ThinkingEffort != "" {
+		parts = append(parts, "effort="+p.ThinkingEffort)
+	}
+	if p.MaxCompletionTokens != nil {
+		parts = append(parts, "max_completion="+strconv.Itoa(*p.MaxCompletionTokens))
+	}
+	return strings.Join(parts, "|")
+}
+
+func retryConfigFrom(pol *cmodel.ProviderPolicy) *RetryConfig {
+	if pol == nil {
+		return &RetryConfig{}
+	}
+	return &RetryConfig{
+		MaxAttempts:    pol.MaxAttempts,
+		InitialBackoff: pol.InitialBackoff,
+		MaxBackoff:     pol.MaxBackoff,
+	}
+}
+
+var (
+	envCassetteOnce sync.Once
+	envCassette     *Cassette
+	envCassetteErr  error
+)
+
+// cassetteFromEnv 返回环境变量配置的录制文件，进程内所有模型和 Embedder 共用同一个
+func cassetteFromEnv() (*Cassette, error) {
+	envCassetteOnce.Do(func() {
+		path := os.Getenv("MODEL_CASSETTE")
+		if path == "" {
+			return
+		}
+		mode := Mode(os.Getenv("MODEL_CASSETTE_MODE"))
+		if mode == "" {
+			mode = ModeReplay
+		}
+		envCassette, envCassetteErr = OpenCassette(path, mode)
+	})
+	return envCassette, envCassetteErr
+}
+
+var (
+	envCacheOnce sync.Once
+	envCache     CacheStore
+	envCacheErr  error
+)
+
+// cacheFromEnv 返回环境变量配置的响应缓存，未开启时返回 nil
+func cacheFromEnv() (CacheStore, error) {
+	envCacheOnce.Do(func() {
+		kind := strings.ToLower(os.Getenv("MODEL_CACHE"))
+		if kind == "" {
+			return
+		}
+		ttl := 24 * time.Hour
+		if v := os.Getenv("MODEL_CACHE_TTL"); v != "" {
+			if ttl, envCacheErr = time.ParseDuration(v); envCacheErr != nil {
+				envCacheErr = fmt.Errorf("parse MODEL_CACHE_TTL: %w", envCacheErr)
+				return
+			}
+		}
+		switch kind {
+		case "memory":
+			size := 1024
+			if v := os.Getenv("MODEL_CACHE_SIZE"); v != "" {
+				if size, envCacheErr = strconv.Atoi(v); envCacheErr != nil {
+					envCacheErr = fmt.Errorf("parse MODEL_CACHE_SIZE: %w", envCacheErr)
+					return
+				}
+			}
+			envCache = NewLRUCache(size, ttl)
+		case "disk":
+			dir := os.Getenv("MODEL_CACHE_DIR")
+			if dir == "" {
+				dir = ".cache/model"
+			}
+			envCache, envCacheErr = NewDiskCache(dir, ttl)
+		default:
+			envCacheErr = fmt.Errorf("unknown MODEL_CACHE %q", kind)
+		}
+	})
+	return envCache, envCacheErr
+}
+
+// NewAgentChatModel 按 model.NewAgentChatModel 创建 agent 的 ChatModel，并按环境变量套上中间件
+//
+// 回放模式下不会创建真实的模型，因此不需要配置 API Key
+func NewAgentChatModel(ctx context.Context, agentName string) (model.ToolCallingChatModel, error) {
+	c, err := cassetteFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil && c.Mode() == ModeReplay {
+		return c.WrapChatModel(agentName, nil), nil
+	}
+
+	cm, err := cmodel.NewAgentChatModel(ctx, agentName)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		cm = c.WrapChatModel(agentName, cm)
+	}
+	return cm, nil
+}
+
+// WrapEmbedder 按环境变量和 provider 的策略为 Embedder 套上中间件，scope 一般为向量模型名称
+func WrapEmbedder(provider, scope string, e embedding.Embedder) (embedding.Embedder, error) {
+	c, err := cassetteFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.Mode() == ModeRecord {
+		pol, err := cmodel.PolicyFromEnv(provider)
+		if err != nil {
+			return nil, err
+		}
+		if pol != nil && (pol.RequestsPerMinute > 0 || pol.TokensPerMinute > 0) {
+			e = NewRateLimitedEmbedder(e, limiterFor(provider, pol.RequestsPerMinute, pol.TokensPerMinute))
+		}
+		e = NewRetryEmbedder(e, retryConfigFrom(pol))
+		store, err := cacheFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if store != nil {
+			e = NewCachedEmbedder(e, store, provider+"|"+scope)
+		}
+	}
+	if c != nil {
+		e = c.WrapEmbedder(scope, e)
+	}
+	return e, nil
+}
+
+// WrapChatModel 为直接创建的 ChatModel（如 graph 中的模型节点）套上与 NewAgentChatModel 相同的中间件
+// scope 一般为模型名称；回放模式下 cm 不会被调用
+func WrapChatModel(provider, scope string, cm model.ToolCallingChatModel) (model.ToolCallingChatModel, error) {
+	c, err := cassetteFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.Mode() == ModeRecord {
+		p := &cmodel.Profile{Name: scope, Provider: provider, Model: scope}
+		if p.Policy, err = cmodel.PolicyFromEnv(provider); err != nil {
+			return nil, err
+		}
+		if cm, err = wrapWithPolicy(p, cm); err != nil {
+			return nil, err
+		}
+		if cm, err = wrapWithCache(p, cm); err != nil {
+			return nil, err
+		}
+	}
+	if c != nil {
+		cm = c.WrapChatModel(scope, cm)
+	}
+	return cm, nil
+}
+
+// NewChatModel 创建直接使用的 ChatModel（如 graph 中的模型节点）
+//
+// MODEL_TYPE=fake 时按 FAKE_MODEL_FIXTURE 中 name 对应的脚本回复，不调用 create，因此不需要 API Key；
+// 否则调用 create 创建真实模型，并按 WrapChatModel 套上中间件
+func NewChatModel(ctx context.Context, name, provider, scope string, create func() (model.ToolCallingChatModel, error)) (model.ToolCallingChatModel, error) {
+	if cmodel.ProfileFromEnv().Provider == cmodel.ProviderFake {
+		return cmodel.NewAgentChatModel(ctx, name)
+	}
+	cm, err := create()
+	if err != nil {
+		return nil, err
+	}
+	return WrapChatModel(provider, scope, cm)
+}
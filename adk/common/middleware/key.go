@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// chatRequest 归一化后的模型请求，只保留会影响回复内容的字段
+//
+// 工具调用 ID 每次请求都不一样，不参与计算；ResponseMeta、Extra 等元数据也不参与
+type chatRequest struct {
+	Scope       string        `json:"scope,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	Tools       []chatTool    `json:"tools,omitempty"`
+	ToolChoice  string        `json:"tool_choice,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	TopP        *float32      `json:"top_p,omitempty"`
+	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+}
+
+type chatMessage struct {
+	Role      string                    `json:"role"`
+	Name      string                    `json:"name,omitempty"`
+	Content   string                    `json:"content,omitempty"`
+	Parts     []schema.MessageInputPart `json:"parts,omitempty"`
+	ToolCalls []chatToolCall            `json:"tool_calls,omitempty"`
+	ToolName  string                    `json:"tool_name,omitempty"`
+}
+
+type chatToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatTool struct {
+	Name   string          `json:"name"`
+	Desc   string          `json:"desc,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type embedRequest struct {
+	Scope string   `json:"scope,omitempty"`
+	Texts []string `json:"texts"`
+	Model string   `json:"model,omitempty"`
+}
+
+// ChatKey 计算模型请求的归一化 key，返回 key 和归一化后的请求（JSON）
+//
+// scope 用于区分不同的模型或 agent，tools 为通过 WithTools 绑定的工具，
+// opts 中的 model.WithTools 会覆盖 tools
+func ChatKey(scope string, input []*schema.Message, tools []*schema.ToolInfo, opts ...model.Option) (string, []byte, error) {
+	options := model.GetCommonOptions(&model.Options{Tools: tools}, opts...)
+	req := chatRequest{
+		Scope:       scope,
+		Messages:    make([]chatMessage, 0, len(input)),
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   options.MaxTokens,
+		Stop:        options.Stop,
+	}
+	if options.Model != nil {
+		req.Model = *options.Model
+	}
+	if options.ToolChoice != nil {
+		req.ToolChoice = string(*options.ToolChoice)
+	}
+	for _, m := range input {
+		if m == nil {
+			continue
+		}
+		cm := chatMessage{
+			Role:     string(m.Role),
+			Name:     m.Name,
+			Content:  m.Content,
+			Parts:    m.UserInputMultiContent,
+			ToolName: m.ToolName,
+		}
+		for _, tc := range m.ToolCalls {
+			cm.ToolCalls = append(cm.ToolCalls, chatToolCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+		}
+		req.Messages = append(req.Messages, cm)
+	}
+	for _, t := range options.Tools {
+		if t == nil {
+			continue
+		}
+		ct := chatTool{Name: t.Name, Desc: t.Desc}
+		if t.ParamsOneOf != nil {
+			js, err := t.ParamsOneOf.ToJSONSchema()
+			if err != nil {
+				return "", nil, fmt.Errorf("tool %s: %w", t.Name, err)
+			}
+			if ct.Params, err = json.Marshal(js); err != nil {
+				return "", nil, fmt.Errorf("tool %s: %w", t.Name, err)
+			}
+		}
+		req.Tools = append(req.Tools, ct)
+	}
+	return hashRequest(req)
+}
+
+// EmbedKey 计算向量化请求的归一化 key，返回 key 和归一化后的请求（JSON）
+func EmbedKey(scope string, texts []string, opts ...embedding.Option) (string, []byte, error) {
+	options := embedding.GetCommonOptions(&embedding.Options{}, opts...)
+	req := embedRequest{Scope: scope, Texts: texts}
+	if options.Model != nil {
+		req.Model = *options.Model
+	}
+	return hashRequest(req)
+}
+
+func hashRequest(req any) (string, []byte, error) {
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal request: %w", err)
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:]), bs, nil
+}
@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// tokenBucket 令牌桶，容量为每分钟的配额，按秒均匀补充
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // 每秒补充的令牌数
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) refillLocked(now time.Time) {
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait 阻塞直到可以取出 n 个令牌
+// n 超过容量时只要求桶是满的，取出后余额为负，相当于向后面的请求借额度
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	for {
+		b.mu.Lock()
+		b.refillLocked(time.Now())
+		need := min(n, b.capacity)
+		if b.tokens >= need {
+			b.tokens -= n
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// adjust 按实际用量修正：delta > 0 时补扣，delta < 0 时退还
+func (b *tokenBucket) adjust(delta float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked(time.Now())
+	b.tokens = min(b.capacity, b.tokens-delta)
+}
+
+// Limiter 客户端限流器，同时限制每分钟请求数和每分钟 token 数
+//
+// token 数在请求前按输入估算并预扣，拿到响应中的 Usage 后再按实际用量修正
+type Limiter struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+// NewLimiter 创建限流器，requestsPerMinute 或 tokensPerMinute 为 0 时不限制对应的维度
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	l := &Limiter{}
+	if requestsPerMinute > 0 {
+		l.requests = newTokenBucket(requestsPerMinute)
+	}
+	if tokensPerMinute > 0 {
+		l.tokens = newTokenBucket(tokensPerMinute)
+	}
+	return l
+}
+
+// Acquire 等待一个请求的配额和 estimated 个 token 的配额
+func (l *Limiter) Acquire(ctx context.Context, estimated int) error {
+	if l.requests != nil {
+		if err := l.requests.wait(ctx, 1); err != nil {
+			return err
+		}
+	}
+	if l.tokens != nil {
+		if err := l.tokens.wait(ctx, float64(estimated)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Settle 请求结束后按实际 token 用量修正预扣的额度，actual 为 0（没有用量信息）时不修正
+func (l *Limiter) Settle(estimated, actual int) {
+	if l.tokens == nil || actual <= 0 {
+		return
+	}
+	l.tokens.adjust(float64(actual - estimated))
+}
+
+// Refund 请求失败时退还预扣的 estimated 个 token
+func (l *Limiter) Refund(estimated int) {
+	if l.tokens == nil {
+		return
+	}
+	l.tokens.adjust(-float64(estimated))
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+// limiterFor 返回提供方共用的限流器，同一进程内同名提供方只创建一次
+func limiterFor(provider string, requestsPerMinute, tokensPerMinute int) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[provider]; ok {
+		return l
+	}
+	l := NewLimiter(requestsPerMinute, tokensPerMinute)
+	limiters[provider] = l
+	return l
+}
+
+// EstimateTokens 粗略估算输入的 token 数：每个字符按 1 个 token 计
+// 对中文接近实际值，对英文偏高，用于限流时宁多勿少
+func EstimateTokens(input []*schema.Message) int {
+	n := 0
+	for _, m := range input {
+		if m == nil {
+			continue
+		}
+		n += utf8.RuneCountInString(m.Content) + 4
+		for _, tc := range m.ToolCalls {
+			n += utf8.RuneCountInString(tc.Function.Name) + utf8.RuneCountInString(tc.Function.Arguments)
+		}
+	}
+	return n
+}
+
+// NewRateLimitedChatModel 为 ChatModel 加上客户端限流
+func NewRateLimitedChatModel(cm model.ToolCallingChatModel, l *Limiter) model.ToolCallingChatModel {
+	return &rateLimitedChatModel{inner: cm, limiter: l}
+}
+
+type rateLimitedChatModel struct {
+	inner   model.ToolCallingChatModel
+	limiter *Limiter
+	tools   []*schema.ToolInfo
+}
+
+func (m *rateLimitedChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	estimated := EstimateTokens(input)
+	if err := m.limiter.Acquire(ctx, estimated); err != nil {
+		return nil, err
+	}
+	msg, err := callGenerate(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	if err != nil {
+		m.limiter.Refund(estimated)
+		return nil, err
+	}
+	m.limiter.Settle(estimated, totalTokens(msg))
+	return msg, nil
+}
+
+func (m *rateLimitedChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	estimated := EstimateTokens(input)
+	if err := m.limiter.Acquire(ctx, estimated); err != nil {
+		return nil, err
+	}
+	sr, err := callStream(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+	if err != nil {
+		m.limiter.Refund(estimated)
+		return nil, err
+	}
+	return teeStream(sr, func(chunks []*schema.Message, err error) {
+		for i := len(chunks) - 1; i >= 0; i-- {
+			if n := totalTokens(chunks[i]); n > 0 {
+				m.limiter.Settle(estimated, n)
+				return
+			}
+		}
+		// 没有收到任何内容就失败时退还，已经输出了部分内容的按预估值计
+		if err != nil && len(chunks) == 0 {
+			m.limiter.Refund(estimated)
+		}
+	}), nil
+}
+
+func (m *rateLimitedChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedChatModel{inner: inner, limiter: m.limiter, tools: tools}, nil
+}
+
+func (m *rateLimitedChatModel) GetType() string {
+	return "RateLimited"
+}
+
+func (m *rateLimitedChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+// NewRateLimitedEmbedder 为 Embedder 加上客户端限流，token 数按文本字符数估算
+func NewRateLimitedEmbedder(e embedding.Embedder, l *Limiter) embedding.Embedder {
+	return &rateLimitedEmbedder{inner: e, limiter: l}
+}
+
+type rateLimitedEmbedder struct {
+	inner   embedding.Embedder
+	limiter *Limiter
+}
+
+func (e *rateLimitedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	estimated := 0
+	for _, t := range texts {
+		estimated += utf8.RuneCountInString(t)
+	}
+	if err := e.limiter.Acquire(ctx, estimated); err != nil {
+		return nil, err
+	}
+	vectors, err := callEmbed(ctx, e.GetType(), e.inner, texts, opts...)
+	if err != nil {
+		e.limiter.Refund(estimated)
+	}
+	return vectors, err
+}
+
+func (e *rateLimitedEmbedder) GetType() string {
+	return "RateLimited"
+}
+
+func (e *rateLimitedEmbedder) IsCallbacksEnabled() bool {
+	return true
+}
+
+func totalTokens(m *schema.Message) int {
+	if m == nil || m.ResponseMeta == nil || m.ResponseMeta.Usage == nil {
+		return 0
+	}
+	return m.ResponseMeta.Usage.TotalTokens
+}
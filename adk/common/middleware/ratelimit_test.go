@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+// remaining 返回桶中剩余的 token 数，忽略测试期间的补充
+func remaining(l *Limiter) float64 {
+	l.tokens.mu.Lock()
+	defer l.tokens.mu.Unlock()
+	return l.tokens.tokens
+}
+
+func TestRateLimitedChatModelSettle(t *testing.T) {
+	ctx := context.Background()
+	fake, err := cmodel.NewFakeChatModel(fakeScript(t,
+		&cmodel.FakeResponse{Content: "ok", Usage: &cmodel.FakeUsage{PromptTokens: 30, CompletionTokens: 10}},
+		&cmodel.FakeResponse{Error: "status code: 500"},
+		&cmodel.FakeResponse{Content: "streamed", Usage: &cmodel.FakeUsage{PromptTokens: 5, CompletionTokens: 5}},
+		&cmodel.FakeResponse{Error: "status code: 503"},
+	), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每分钟 6000 个 token，每秒补充 100 个，测试期间的补充远小于 1
+	l := NewLimiter(0, 6000)
+	m := NewRateLimitedChatModel(fake, l)
+	input := []*schema.Message{schema.UserMessage("hello")}
+	estimated := float64(EstimateTokens(input))
+
+	assertRemaining := func(step string, want float64) {
+		t.Helper()
+		if got := remaining(l); got < want-1 || got > want+1 {
+			t.Fatalf("%s: remaining = %.1f, want %.0f", step, got, want)
+		}
+	}
+
+	if _, err := m.Generate(ctx, input); err != nil {
+		t.Fatal(err)
+	}
+	assertRemaining("settled by usage", 6000-40)
+
+	// 调用失败时退还预扣的估算值
+	if _, err := m.Generate(ctx, input); err == nil {
+		t.Fatal("want scripted error")
+	}
+	assertRemaining("refunded generate", 6000-40)
+
+	sr, err := m.Stream(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAll(sr); err != nil {
+		t.Fatal(err)
+	}
+	assertRemaining("settled stream", 6000-50)
+
+	if _, err := m.Stream(ctx, input); err == nil {
+		t.Fatal("want scripted error")
+	}
+	assertRemaining("refunded stream", 6000-50)
+
+	// 没有用量信息时保留预扣的估算值
+	if err := l.Acquire(ctx, int(estimated)); err != nil {
+		t.Fatal(err)
+	}
+	l.Settle(int(estimated), 0)
+	assertRemaining("no usage", 6000-50-estimated)
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+// RetryConfig 重试策略，0 值字段使用默认值
+type RetryConfig struct {
+	MaxAttempts    int                  // 含首次在内的最大尝试次数，默认 3
+	InitialBackoff time.Duration        // 首次重试前的等待时间，默认 500ms，之后每次翻倍
+	MaxBackoff     time.Duration        // 等待时间上限，默认 30s
+	IsRetryable    func(err error) bool // 判断错误是否可重试，默认 model.IsRetryableError
+}
+
+func (c *RetryConfig) withDefaults() *RetryConfig {
+	r := RetryConfig{}
+	if c != nil {
+		r = *c
+	}
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 3
+	}
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = 500 * time.Millisecond
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = 30 * time.Second
+	}
+	if r.IsRetryable == nil {
+		r.IsRetryable = cmodel.IsRetryableError
+	}
+	return &r
+}
+
+// backoff 第 attempt 次重试前的等待时间：指数退避，在 [d/2, d] 之间随机抖动，避免多个调用方同时重试
+func (c *RetryConfig) backoff(attempt int) time.Duration {
+	d := c.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// do 按策略重试 fn，直到成功、遇到不可重试的错误或用完次数
+func (c *RetryConfig) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= c.MaxAttempts || !c.IsRetryable(err) {
+			return err
+		}
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			// 等待期间被取消时返回取消原因，上一次的错误已经不是调用失败的原因
+			timer.Stop()
+			return context.Cause(ctx)
+		case <-timer.C:
+		}
+	}
+}
+
+// NewRetryChatModel 为 ChatModel 加上重试
+//
+// Stream 会预读首个 chunk，建立连接或首个 chunk 之前的失败会重试；
+// 已经输出内容后的失败无法重试，直接返回给调用方
+func NewRetryChatModel(cm model.ToolCallingChatModel, cfg *RetryConfig) model.ToolCallingChatModel {
+	return &retryChatModel{inner: cm, cfg: cfg.withDefaults()}
+}
+
+type retryChatModel struct {
+	inner model.ToolCallingChatModel
+	cfg   *RetryConfig
+	tools []*schema.ToolInfo
+}
+
+func (m *retryChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var msg *schema.Message
+	err := m.cfg.do(ctx, func() (err error) {
+		msg, err = callGenerate(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+		return err
+	})
+	return msg, err
+}
+
+func (m *retryChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var out *schema.StreamReader[*schema.Message]
+	err := m.cfg.do(ctx, func() error {
+		sr, err := callStream(ctx, m.GetType(), m.inner, input, m.tools, opts...)
+		if err != nil {
+			return err
+		}
+		out, err = peekStream(sr)
+		return err
+	})
+	return out, err
+}
+
+func (m *retryChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &retryChatModel{inner: inner, cfg: m.cfg, tools: tools}, nil
+}
+
+func (m *retryChatModel) GetType() string {
+	return "Retry"
+}
+
+func (m *retryChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+// NewRetryEmbedder 为 Embedder 加上重试
+func NewRetryEmbedder(e embedding.Embedder, cfg *RetryConfig) embedding.Embedder {
+	return &retryEmbedder{inner: e, cfg: cfg.withDefaults()}
+}
+
+type retryEmbedder struct {
+	inner embedding.Embedder
+	cfg   *RetryConfig
+}
+
+func (e *retryEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	var vectors [][]float64
+	err := e.cfg.do(ctx, func() (err error) {
+		vectors, err = callEmbed(ctx, e.GetType(), e.inner, texts, opts...)
+		return err
+	})
+	return vectors, err
+}
+
+func (e *retryEmbedder) GetType() string {
+	return "Retry"
+}
+
+func (e *retryEmbedder) IsCallbacksEnabled() bool {
+	return true
+}
+
+// peekStream 预读首个 chunk，失败时关闭流并返回错误，成功时返回与原来等价的流
+func peekStream(sr *schema.StreamReader[*schema.Message]) (*schema.StreamReader[*schema.Message], error) {
+	first, err := sr.Recv()
+	if err == io.EOF {
+		sr.Close()
+		return schema.StreamReaderFromArray([]*schema.Message{}), nil
+	}
+	if err != nil {
+		sr.Close()
+		return nil, err
+	}
+	out, sw := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer func() {
+			sr.Close()
+			sw.Close()
+		}()
+		for chunk, err := first, error(nil); ; chunk, err = sr.Recv() {
+			if err == io.EOF {
+				return
+			}
+			if closed := sw.Send(chunk, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -3,71 +3,82 @@ package model
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino-ext/components/model/openai"
-	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
-	cbutils "github.com/cloudwego/eino/utils/callbacks"
 	arkModel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
 )
 
-func NewChatModel() model.ToolCallingChatModel {
-	modelType := strings.ToLower(os.Getenv("MODEL_TYPE"))
+// NewChatModel 创建默认的 ChatModel
+//
+// 设置了 MODEL_CONFIG 时使用配置文件中的默认 profile，否则按 MODEL_TYPE 等环境变量创建；
+// 创建失败时返回错误，由调用方决定如何处理
+func NewChatModel() (model.ToolCallingChatModel, error) {
+	return NewAgentChatModel(context.Background(), "")
+}
 
-	// Create Ark ChatModel when MODEL_TYPE is "ark"
-	if modelType == "ark" {
-		cm, err := ark.NewChatModel(context.Background(), &ark.ChatModelConfig{
-			// Add Ark-specific configuration from environment variables
-			APIKey: os.Getenv("ARK_API_KEY"),
-			Model:  os.Getenv("ARK_MODEL"),
-			// BaseURL: os.Getenv("ARK_BASE_URL"),
-			Thinking: &arkModel.Thinking{
-				Type: arkModel.ThinkingTypeDisabled,
-			},
-		})
-		if err != nil {
-			log.Fatalf("ark.NewChatModel failed: %v", err)
-		}
-		return cm
+// newArkChatModel 按 Profile 创建 Ark ChatModel
+func newArkChatModel(ctx context.Context, p *Profile) (model.ToolCallingChatModel, error) {
+	apiKey, err := p.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ark.ChatModelConfig{
+		APIKey:      apiKey,
+		Model:       p.Model,
+		BaseURL:     p.BaseURL,
+		Temperature: p.Temperature,
+		MaxTokens:   p.MaxTokens,
+	}
+	if p.Timeout > 0 {
+		cfg.Timeout = &p.Timeout
+	}
+	// 未配置时保持原来的行为：关闭思考
+	thinking := arkModel.ThinkingTypeDisabled
+	switch p.Thinking {
+	case "", ThinkingDisabled:
+	case ThinkingEnabled:
+		thinking = arkModel.ThinkingTypeEnabled
+	case ThinkingAuto:
+		thinking = arkModel.ThinkingTypeAuto
+	default:
+		return nil, fmt.Errorf("unknown thinking mode %q", p.Thinking)
+	}
+	cfg.Thinking = &arkModel.Thinking{Type: thinking}
+	if p.ThinkingEffort != "" {
+		effort := arkModel.ReasoningEffort(p.ThinkingEffort)
+		cfg.ReasoningEffort = &effort
 	}
+	cfg.MaxCompletionTokens = p.MaxCompletionTokens
 
-	// Create OpenAI ChatModel (default)
-	cm, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
-		APIKey:  os.Getenv("OPENAI_API_KEY"),
-		Model:   os.Getenv("OPENAI_MODEL"),
-		BaseURL: os.Getenv("OPENAI_BASE_URL"),
-		ByAzure: func() bool {
-			return os.Getenv("OPENAI_BY_AZURE") == "true"
-		}(),
-	})
+	cm, err := ark.NewChatModel(ctx, cfg)
 	if err != nil {
-		log.Fatalf("openai.NewChatModel failed: %v", err)
+		return nil, fmt.Errorf("ark.NewChatModel failed: %w", err)
 	}
-	return cm
+	return cm, nil
 }
 
-func GetInputLoggerCallback() callbacks.Handler {
-	return cbutils.NewHandlerHelper().ChatModel(&cbutils.ModelCallbackHandler{
-		OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *model.CallbackInput) context.Context {
-			// time.Sleep(20 * time.Second)
-			fmt.Printf("\n========================================\n")
-			fmt.Printf("[ChatModel Input] Agent: %s\n", info.Name)
-			fmt.Printf("========================================\n")
-			for i, msg := range input.Messages {
-				fmt.Printf("  Message %d [%s]: %s\n", i+1, msg.Role, msg.Content)
-				if len(msg.ToolCalls) > 0 {
-					fmt.Printf("    Tool Calls: %d\n", len(msg.ToolCalls))
-					for j, tc := range msg.ToolCalls {
-						fmt.Printf("      %d. %s: %s\n", j+1, tc.Function.Name, tc.Function.Arguments)
-					}
-				}
-			}
-			fmt.Printf("========================================\n\n")
-			return ctx
-		},
-	}).Handler()
+// newOpenAIChatModel 按 Profile 创建 OpenAI（或兼容接口）的 ChatModel
+func newOpenAIChatModel(ctx context.Context, p *Profile) (model.ToolCallingChatModel, error) {
+	apiKey, err := p.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &openai.ChatModelConfig{
+		APIKey:          apiKey,
+		Model:           p.Model,
+		BaseURL:         p.BaseURL,
+		ByAzure:         p.ByAzure,
+		Timeout:         p.Timeout,
+		Temperature:     p.Temperature,
+		MaxTokens:       p.MaxTokens,
+		ReasoningEffort: openai.ReasoningEffortLevel(p.ThinkingEffort),
+	}
+	cfg.MaxCompletionTokens = p.MaxCompletionTokens
+	cm, err := openai.NewChatModel(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("openai.NewChatModel failed: %w", err)
+	}
+	return cm, nil
 }
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 模型配置文件的结构
+//
+// 示例（models.yaml）：
+//
+//	default: doubao
+//	agents:
+//	  critique_agent: gpt
+//	providers:
+//	  ark:
+//	    requests_per_minute: 60
+//	    tokens_per_minute: 200000
+//	    max_attempts: 4
+//	profiles:
+//	  doubao:
+//	    provider: ark
+//	    model: doubao-seed-1-8-251228
+//	    timeout: 30s
+//	    thinking: disabled
+//	    api_key_env: ARK_API_KEY
+//	    fallbacks: [gpt]
+//	  gpt:
+//	    provider: openai
+//	    model: gpt-4o
+//	    base_url: https://api.openai.com/v1
+//	    temperature: 0.2
+//	    api_key_env: OPENAI_API_KEY
+type Config struct {
+	Default  string              `yaml:"default"`  // 默认使用的 profile 名称
+	Agents   map[string]string   `yaml:"agents"`   // agent 名称 -> profile 名称
+	Profiles map[string]*Profile `yaml:"profiles"` // profile 名称 -> 模型配置
+
+	Providers map[string]*ProviderPolicy `yaml:"providers"` // 提供方名称 -> 限流与重试策略
+}
+
+// ProviderPolicy 同一提供方下所有模型共用的限流与重试策略，0 值表示使用默认值或不限制
+type ProviderPolicy struct {
+	RequestsPerMinute int           `yaml:"requests_per_minute"` // 每分钟请求数上限
+	TokensPerMinute   int           `yaml:"tokens_per_minute"`   // 每分钟 token 数上限
+	MaxAttempts       int           `yaml:"max_attempts"`        // 含首次在内的最大尝试次数
+	InitialBackoff    time.Duration `yaml:"initial_backoff"`     // 首次重试前的等待时间
+	MaxBackoff        time.Duration `yaml:"max_backoff"`         // 重试等待时间的上限
+}
+
+// Profile 一个命名的模型配置
+type Profile struct {
+	Name        string        `yaml:"-"`           // profile 名称，加载时由 map 的 key 填充
+	Provider    string        `yaml:"provider"`    // 模型提供方，如 ark、openai
+	Model       string        `yaml:"model"`       // 模型名称或 endpoint ID
+	BaseURL     string        `yaml:"base_url"`    // 可选，自定义服务地址
+	Timeout     time.Duration `yaml:"timeout"`     // 可选，请求超时，如 30s
+	Temperature *float32      `yaml:"temperature"` // 可选，采样温度
+	MaxTokens   *int          `yaml:"max_tokens"`  // 可选，最大输出 token 数
+	Thinking    string        `yaml:"thinking"`    // 可选，思考模式：disabled、enabled、auto，仅 ark
+
+	// 可选，思考深度：minimal、low、medium、high，对应接口的 reasoning_effort
+	ThinkingEffort string `yaml:"thinking_effort"`
+	// 可选，思考与回答合计的 token 上限，对应接口的 max_completion_tokens，不能与 max_tokens 同时设置
+	// 方舟和 OpenAI 都没有单独限制思考长度的参数，思考 token 也计入这个上限
+	MaxCompletionTokens *int `yaml:"max_completion_tokens"`
+
+	ByAzure   bool     `yaml:"by_azure"`  // 仅 openai，是否为 Azure 部署
+	Fallbacks []string `yaml:"fallbacks"` // 可选，失败时依次切换的备用 profile
+	Fixture   string   `yaml:"fixture"`   // 仅 fake，脚本文件路径，见 FakeScript
+
+	Policy *ProviderPolicy `yaml:"-"` // 加载时从 providers 中按 provider 填充，可能为 nil
+
+	// API Key 的来源，按优先级依次为：api_key > api_key_env > api_key_file
+	APIKey     string `yaml:"api_key"`      // 直接写在配置中的 key（不推荐）
+	APIKeyEnv  string `yaml:"api_key_env"`  // 从指定环境变量读取
+	APIKeyFile string `yaml:"api_key_file"` // 从指定文件读取
+}
+
+// LoadConfig 从 YAML 文件加载模型配置
+func LoadConfig(path string) (*Config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model config %q: %w", path, err)
+	}
+	return ParseConfig(bs)
+}
+
+// ParseConfig 解析 YAML 格式的模型配置并做基本校验
+func ParseConfig(bs []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(bs, cfg); err != nil {
+		return nil, fmt.Errorf("parse model config: %w", err)
+	}
+	if len(cfg.Profiles) == 0 {
+		return nil, fmt.Errorf("model config has no profiles")
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("profile %q is empty", name)
+		}
+		p.Name = name
+		p.Provider = strings.ToLower(p.Provider)
+		if p.Provider == "" {
+			return nil, fmt.Errorf("profile %q: provider is required", name)
+		}
+		if p.Model == "" && p.Provider != ProviderFake {
+			return nil, fmt.Errorf("profile %q: model is required", name)
+		}
+		if err := p.validateThinking(); err != nil {
+			return nil, err
+		}
+		p.Policy = cfg.Providers[p.Provider]
+	}
+	if cfg.Default != "" {
+		if _, ok := cfg.Profiles[cfg.Default]; !ok {
+			return nil, fmt.Errorf("default profile %q not found", cfg.Default)
+		}
+	}
+	for name, p := range cfg.Profiles {
+		for _, fb := range p.Fallbacks {
+			if _, ok := cfg.Profiles[fb]; !ok || fb == name {
+				return nil, fmt.Errorf("profile %q: invalid fallback %q", name, fb)
+			}
+		}
+	}
+	for agent, name := range cfg.Agents {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return nil, fmt.Errorf("agent %q refers to unknown profile %q", agent, name)
+		}
+	}
+	return cfg, nil
+}
+
+// validateThinking 校验思考相关的配置
+func (p *Profile) validateThinking() error {
+	switch p.Thinking {
+	case "", ThinkingDisabled, ThinkingEnabled, ThinkingAuto:
+	default:
+		return fmt.Errorf("profile %q: unknown thinking mode %q", p.Name, p.Thinking)
+	}
+	switch p.ThinkingEffort {
+	case "", ThinkingEffortMinimal, ThinkingEffortLow, ThinkingEffortMedium, ThinkingEffortHigh:
+	default:
+		return fmt.Errorf("profile %q: unknown thinking effort %q", p.Name, p.ThinkingEffort)
+	}
+	if p.MaxCompletionTokens != nil && *p.MaxCompletionTokens <= 0 {
+		return fmt.Errorf("profile %q: max_completion_tokens must be positive", p.Name)
+	}
+	if p.MaxCompletionTokens != nil && p.MaxTokens != nil {
+		return fmt.Errorf("profile %q: max_completion_tokens and max_tokens cannot both be set", p.Name)
+	}
+	return nil
+}
+
+// ResolveAPIKey 按 Profile 中配置的来源读取 API Key
+func (p *Profile) ResolveAPIKey() (string, error) {
+	switch {
+	case p.APIKey != "":
+		return p.APIKey, nil
+	case p.APIKeyEnv != "":
+		key := os.Getenv(p.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("profile %q: env %s is empty", p.Name, p.APIKeyEnv)
+		}
+		return key, nil
+	case p.APIKeyFile != "":
+		bs, err := os.ReadFile(p.APIKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("profile %q: read api key file: %w", p.Name, err)
+		}
+		return strings.TrimSpace(string(bs)), nil
+	}
+	return "", fmt.Errorf("profile %q: no api key source configured", p.Name)
+}
+
+// ProfileFromEnv 根据旧的环境变量约定构建 Profile，保持与 MODEL_TYPE 的兼容
+//
+//	MODEL_TYPE=ark    -> ARK_API_KEY、ARK_MODEL、ARK_BASE_URL、ARK_THINKING、ARK_THINKING_EFFORT
+//	MODEL_TYPE=fake   -> FAKE_MODEL_FIXTURE
+//	MODEL_TYPE=其他   -> OPENAI_API_KEY、OPENAI_MODEL、OPENAI_BASE_URL、OPENAI_BY_AZURE
+func ProfileFromEnv() *Profile {
+	switch strings.ToLower(os.Getenv("MODEL_TYPE")) {
+	case ProviderFake:
+		return &Profile{
+			Name:     "env",
+			Provider: ProviderFake,
+			Fixture:  os.Getenv("FAKE_MODEL_FIXTURE"),
+		}
+	case ProviderArk:
+		thinking := strings.ToLower(os.Getenv("ARK_THINKING"))
+		if thinking == "" {
+			thinking = ThinkingDisabled
+		}
+		return &Profile{
+			Name:           "env",
+			Provider:       ProviderArk,
+			Model:          os.Getenv("ARK_MODEL"),
+			BaseURL:        os.Getenv("ARK_BASE_URL"),
+			Thinking:       thinking,
+			ThinkingEffort: strings.ToLower(os.Getenv("ARK_THINKING_EFFORT")),
+			APIKeyEnv:      "ARK_API_KEY",
+		}
+	}
+	return &Profile{
+		Name:      "env",
+		Provider:  ProviderOpenAI,
+		Model:     os.Getenv("OPENAI_MODEL"),
+		BaseURL:   os.Getenv("OPENAI_BASE_URL"),
+		ByAzure:   os.Getenv("OPENAI_BY_AZURE") == "true",
+		APIKeyEnv: "OPENAI_API_KEY",
+	}
+}
+
+// PolicyFromEnv 从 MODEL_CONFIG 指定的配置文件中读取提供方的限流与重试策略
+// 未设置 MODEL_CONFIG 或未配置该提供方时返回 nil
+func PolicyFromEnv(provider string) (*ProviderPolicy, error) {
+	path := os.Getenv("MODEL_CONFIG")
+	if path == "" {
+		return nil, nil
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Providers[provider], nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	arkModel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// statusCodeRe 兼容 openai 等 SDK 的错误文本，如 "status code: 429"、"Error code: 503"
+var statusCodeRe = regexp.MustCompile(`(?i)(?:status code|error code):?\s*(\d{3})`)
+
+// StatusCode 尝试从模型调用错误中提取 HTTP 状态码，提取不到时返回 0
+func StatusCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var apiErr *arkModel.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *arkModel.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	if m := statusCodeRe.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code
+	}
+	return 0
+}
+
+// IsRetryableError 判断错误是否属于临时性故障：超时、网络错误、429 限流或 5xx 服务端错误
+//
+// 调用方主动取消（context.Canceled）不算临时性故障
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	code := StatusCode(err)
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
@@ -0,0 +1,280 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// FailoverBackend 故障切换中的一个后端
+type FailoverBackend struct {
+	Name  string // 用于错误信息和回调，一般为 profile 名称
+	Model model.ToolCallingChatModel
+	// Timeout 该后端的超时时间，0 时使用 FailoverConfig.AttemptTimeout
+	Timeout time.Duration
+}
+
+// FailoverConfig 故障切换 ChatModel 的配置
+type FailoverConfig struct {
+	// Backends 按优先级排列，第一个为主模型，其余为备用模型
+	Backends []FailoverBackend
+
+	// AttemptTimeout 没有设置 FailoverBackend.Timeout 的后端的超时时间，0 表示不限制
+	// Generate 中限制整次调用，Stream 中限制等待首个 chunk 的时间（BufferStream 时限制整条流）
+	AttemptTimeout time.Duration
+
+	// BufferStream 为 true 时，流式调用会先把后端的完整输出缓存下来再交给调用方，
+	// 这样流中途失败也能无感地切换到下一个后端，代价是失去逐字输出的效果。
+	// 为 false 时只预读首个 chunk，首个 chunk 之后的失败会以 *StreamInterruptedError 返回给调用方
+	BufferStream bool
+
+	// ShouldFailover 判断一次失败是否应切换到下一个后端，默认见 DefaultShouldFailover
+	ShouldFailover func(err error) bool
+
+	// OnFailover 可选，每次切换前调用，便于打日志
+	OnFailover func(ctx context.Context, from string, err error)
+}
+
+// StreamInterruptedError 流已经输出了部分内容后后端失败
+type StreamInterruptedError struct {
+	Backend string
+	Chunks  int // 失败前已经输出的 chunk 数
+	Err     error
+}
+
+func (e *StreamInterruptedError) Error() string {
+	return fmt.Sprintf("stream from %s interrupted after %d chunks: %v", e.Backend, e.Chunks, e.Err)
+}
+
+func (e *StreamInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultShouldFailover 默认的切换策略
+//
+// 调用方取消时不切换；429、408 和 5xx 切换；其他 4xx（如参数错误）换后端也无济于事，不切换；
+// 其余没有状态码的错误（网络错误、超时、SDK 错误等）都切换
+func DefaultShouldFailover(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if IsRetryableError(err) {
+		return true
+	}
+	code := StatusCode(err)
+	return code < http.StatusBadRequest
+}
+
+// NewFailoverChatModel 创建一个按顺序尝试多个后端的 ChatModel
+//
+// Generate 和 Stream 都会在主模型失败时依次尝试备用模型，全部失败时返回所有后端的错误；
+// WithTools 会在每个后端上分别绑定工具
+func NewFailoverChatModel(cfg *FailoverConfig) (model.ToolCallingChatModel, error) {
+	if cfg == nil || len(cfg.Backends) == 0 {
+		return nil, fmt.Errorf("failover: at least one backend is required")
+	}
+	for i, b := range cfg.Backends {
+		if b.Model == nil {
+			return nil, fmt.Errorf("failover: backend %d (%s) has no model", i, b.Name)
+		}
+	}
+	c := *cfg
+	c.Backends = append([]FailoverBackend(nil), cfg.Backends...)
+	for i := range c.Backends {
+		if c.Backends[i].Name == "" {
+			c.Backends[i].Name = fmt.Sprintf("backend#%d", i)
+		}
+	}
+	if c.ShouldFailover == nil {
+		c.ShouldFailover = DefaultShouldFailover
+	}
+	return &failoverChatModel{cfg: &c}, nil
+}
+
+type failoverChatModel struct {
+	cfg *FailoverConfig
+}
+
+func (f *failoverChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var errs []error
+	for i, b := range f.cfg.Backends {
+		msg, err := f.generateOnce(ctx, b, input, opts...)
+		if err == nil {
+			return msg, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+		if !f.next(ctx, i, b, err) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failover: all backends failed: %w", errors.Join(errs...))
+}
+
+func (f *failoverChatModel) generateOnce(ctx context.Context, b FailoverBackend, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if timeout := f.timeout(b); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return b.Model.Generate(ctx, input, opts...)
+}
+
+func (f *failoverChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var errs []error
+	for i, b := range f.cfg.Backends {
+		var (
+			sr  *schema.StreamReader[*schema.Message]
+			err error
+		)
+		if f.cfg.BufferStream {
+			sr, err = f.streamBuffered(ctx, b, input, opts...)
+		} else {
+			sr, err = f.streamLive(ctx, b, input, opts...)
+		}
+		if err == nil {
+			return sr, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+		if !f.next(ctx, i, b, err) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failover: all backends failed: %w", errors.Join(errs...))
+}
+
+// streamBuffered 读完整条流再返回，中途失败时由 Stream 切换到下一个后端
+func (f *failoverChatModel) streamBuffered(ctx context.Context, b FailoverBackend, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if timeout := f.timeout(b); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	sr, err := b.Model.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Close()
+
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+// streamLive 预读首个 chunk 确认后端可用，然后边读边转发
+func (f *failoverChatModel) streamLive(ctx context.Context, b FailoverBackend, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	timeout := f.timeout(b)
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, cancel)
+	}
+	fail := func(err error) error {
+		cancel()
+		// 超时由我们自己取消 ctx 触发，转换成 DeadlineExceeded 以便判断是否切换
+		if timer != nil && !timer.Stop() && errors.Is(err, context.Canceled) {
+			return fmt.Errorf("no chunk received within %v: %w", timeout, context.DeadlineExceeded)
+		}
+		return err
+	}
+
+	sr, err := b.Model.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, fail(err)
+	}
+	first, err := sr.Recv()
+	if err == io.EOF {
+		sr.Close()
+		cancel()
+		return schema.StreamReaderFromArray([]*schema.Message{}), nil
+	}
+	if err != nil {
+		sr.Close()
+		return nil, fail(err)
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+
+	out, sw := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer func() {
+			sr.Close()
+			sw.Close()
+			cancel()
+		}()
+		if closed := sw.Send(first, nil); closed {
+			return
+		}
+		for n := 1; ; n++ {
+			chunk, err := sr.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				sw.Send(nil, &StreamInterruptedError{Backend: b.Name, Chunks: n, Err: err})
+				return
+			}
+			if closed := sw.Send(chunk, nil); closed {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// timeout 返回后端 b 单次尝试的超时时间
+func (f *failoverChatModel) timeout(b FailoverBackend) time.Duration {
+	if b.Timeout > 0 {
+		return b.Timeout
+	}
+	return f.cfg.AttemptTimeout
+}
+
+// next 判断第 i 个后端失败后是否继续尝试下一个
+func (f *failoverChatModel) next(ctx context.Context, i int, b FailoverBackend, err error) bool {
+	if ctx.Err() != nil || i == len(f.cfg.Backends)-1 || !f.cfg.ShouldFailover(err) {
+		return false
+	}
+	if f.cfg.OnFailover != nil {
+		f.cfg.OnFailover(ctx, b.Name, err)
+	}
+	return true
+}
+
+// WithTools 在每个后端上分别绑定工具，返回新的故障切换 ChatModel
+func (f *failoverChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	c := *f.cfg
+	c.Backends = make([]FailoverBackend, 0, len(f.cfg.Backends))
+	for _, b := range f.cfg.Backends {
+		m, err := b.Model.WithTools(tools)
+		if err != nil {
+			return nil, fmt.Errorf("failover: bind tools on %s: %w", b.Name, err)
+		}
+		c.Backends = append(c.Backends, FailoverBackend{Name: b.Name, Model: m, Timeout: b.Timeout})
+	}
+	return &failoverChatModel{cfg: &c}, nil
+}
+
+func (f *failoverChatModel) GetType() string {
+	return "Failover"
+}
+
+// IsCallbacksEnabled 回调由各个后端自己触发，这样每次尝试都能被追踪到
+func (f *failoverChatModel) IsCallbacksEnabled() bool {
+	return true
+}
@@ -0,0 +1,342 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// stubChatModel 由测试指定 Generate 和 Stream 行为的 ChatModel，回复内容为绑定的工具名
+type stubChatModel struct {
+	generate func(ctx context.Context) (*schema.Message, error)
+	stream   func(ctx context.Context) (*schema.StreamReader[*schema.Message], error)
+	tools    []*schema.ToolInfo
+}
+
+func (s *stubChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if s.generate != nil {
+		return s.generate(ctx)
+	}
+	names := make([]string, 0, len(s.tools))
+	for _, t := range s.tools {
+		names = append(names, t.Name)
+	}
+	return schema.AssistantMessage(strings.Join(names, ","), nil), nil
+}
+
+func (s *stubChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return s.stream(ctx)
+}
+
+func (s *stubChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	ns := *s
+	ns.tools = tools
+	return &ns, nil
+}
+
+// blockUntilDone 一直等到 ctx 结束，模拟没有响应的后端
+func blockUntilDone(ctx context.Context) (*schema.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// newFake 创建只有一个 agent 的假模型
+func newFake(t *testing.T, responses ...*FakeResponse) model.ToolCallingChatModel {
+	t.Helper()
+	m, err := NewFakeChatModel(&FakeScript{Agents: map[string][]*FakeResponse{"a": responses}}, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func newFailover(t *testing.T, cfg *FailoverConfig) model.ToolCallingChatModel {
+	t.Helper()
+	m, err := NewFailoverChatModel(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFailoverGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      string
+		failover bool
+	}{
+		{"rate limited", "status code: 429", true},
+		{"server error", "Error code: 503", true},
+		{"request timeout", "status code: 408", true},
+		{"bad request", "status code: 400", false},
+		{"unauthorized", "status code: 401", false},
+		{"no status code", "connection reset", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var switched []string
+			m := newFailover(t, &FailoverConfig{
+				Backends: []FailoverBackend{
+					{Name: "primary", Model: newFake(t, &FakeResponse{Error: tt.err})},
+					{Name: "backup", Model: newFake(t, &FakeResponse{Content: "from backup"})},
+				},
+				OnFailover: func(ctx context.Context, from string, err error) { switched = append(switched, from) },
+			})
+			msg, err := m.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+			if !tt.failover {
+				if err == nil || !strings.Contains(err.Error(), tt.err) || strings.Contains(err.Error(), "backup") {
+					t.Fatalf("err = %v, want only the primary error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != "from backup" || len(switched) != 1 || switched[0] != "primary" {
+				t.Fatalf("content = %q, switched = %v", msg.Content, switched)
+			}
+		})
+	}
+}
+
+func TestFailoverAllBackendsFail(t *testing.T) {
+	m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+		{Name: "primary", Model: newFake(t, &FakeResponse{Error: "status code: 500"})},
+		{Name: "backup", Model: newFake(t, &FakeResponse{Error: "status code: 502"})},
+	}})
+	_, err := m.Generate(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "primary") || !strings.Contains(err.Error(), "backup") {
+		t.Fatalf("err = %v, want errors of both backends", err)
+	}
+}
+
+func TestFailoverTimeout(t *testing.T) {
+	slowOK := func(ctx context.Context) (*schema.Message, error) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return schema.AssistantMessage("slow backup", nil), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	// 主模型用 AttemptTimeout 超时；备用模型自己的 Timeout 更长，不会被 AttemptTimeout 截断
+	m := newFailover(t, &FailoverConfig{
+		Backends: []FailoverBackend{
+			{Name: "primary", Model: &stubChatModel{generate: blockUntilDone}},
+			{Name: "backup", Model: &stubChatModel{generate: slowOK}, Timeout: time.Second},
+		},
+		AttemptTimeout: 10 * time.Millisecond,
+	})
+	msg, err := m.Generate(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "slow backup" {
+		t.Fatalf("content = %q", msg.Content)
+	}
+
+	// 备用模型自己的 Timeout 更短时按它超时
+	m = newFailover(t, &FailoverConfig{
+		Backends: []FailoverBackend{
+			{Name: "primary", Model: &stubChatModel{generate: blockUntilDone}, Timeout: 10 * time.Millisecond},
+			{Name: "backup", Model: &stubChatModel{generate: slowOK}, Timeout: 10 * time.Millisecond},
+		},
+		AttemptTimeout: time.Second,
+	})
+	if _, err := m.Generate(context.Background(), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestFailoverCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+		{Name: "primary", Model: &stubChatModel{generate: blockUntilDone}},
+		{Name: "backup", Model: &stubChatModel{generate: func(ctx context.Context) (*schema.Message, error) {
+			called = true
+			return schema.AssistantMessage("", nil), nil
+		}}},
+	}})
+	if _, err := m.Generate(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want Canceled", err)
+	}
+	if called {
+		t.Fatal("backup called after the caller canceled")
+	}
+}
+
+// brokenStream 输出 n 个 chunk 后失败
+func brokenStream(n int) func(ctx context.Context) (*schema.StreamReader[*schema.Message], error) {
+	return func(ctx context.Context) (*schema.StreamReader[*schema.Message], error) {
+		sr, sw := schema.Pipe[*schema.Message](n + 1)
+		go func() {
+			defer sw.Close()
+			for i := 0; i < n; i++ {
+				sw.Send(schema.AssistantMessage("x", nil), nil)
+			}
+			sw.Send(nil, errors.New("status code: 502"))
+		}()
+		return sr, nil
+	}
+}
+
+// readStream 读完整条流，返回拼接的内容和遇到的错误
+func readStream(sr *schema.StreamReader[*schema.Message]) (string, error) {
+	defer sr.Close()
+	var sb strings.Builder
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+		sb.WriteString(chunk.Content)
+	}
+}
+
+func TestFailoverStream(t *testing.T) {
+	backup := func(t *testing.T) model.ToolCallingChatModel {
+		return newFake(t, &FakeResponse{Content: "from backup"})
+	}
+
+	t.Run("fails before first chunk", func(t *testing.T) {
+		m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+			{Name: "primary", Model: &stubChatModel{stream: brokenStream(0)}},
+			{Name: "backup", Model: backup(t)},
+		}})
+		sr, err := m.Stream(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, err := readStream(sr); err != nil || content != "from backup" {
+			t.Fatalf("content = %q, err = %v", content, err)
+		}
+	})
+
+	t.Run("no first chunk within timeout", func(t *testing.T) {
+		block := func(ctx context.Context) (*schema.StreamReader[*schema.Message], error) {
+			_, err := blockUntilDone(ctx)
+			return nil, err
+		}
+		m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+			{Name: "primary", Model: &stubChatModel{stream: block}, Timeout: 10 * time.Millisecond},
+			{Name: "backup", Model: backup(t)},
+		}})
+		sr, err := m.Stream(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, err := readStream(sr); err != nil || content != "from backup" {
+			t.Fatalf("content = %q, err = %v", content, err)
+		}
+	})
+
+	t.Run("interrupted after first chunk", func(t *testing.T) {
+		m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+			{Name: "primary", Model: &stubChatModel{stream: brokenStream(2)}},
+			{Name: "backup", Model: backup(t)},
+		}})
+		sr, err := m.Stream(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := readStream(sr)
+		var ie *StreamInterruptedError
+		if !errors.As(err, &ie) || ie.Backend != "primary" || ie.Chunks != 2 {
+			t.Fatalf("err = %v, want StreamInterruptedError from primary after 2 chunks", err)
+		}
+		if content != "xx" {
+			t.Fatalf("content = %q, want the chunks before the failure", content)
+		}
+	})
+
+	t.Run("buffered switches mid-stream", func(t *testing.T) {
+		m := newFailover(t, &FailoverConfig{
+			Backends: []FailoverBackend{
+				{Name: "primary", Model: &stubChatModel{stream: brokenStream(2)}},
+				{Name: "backup", Model: backup(t)},
+			},
+			BufferStream: true,
+		})
+		sr, err := m.Stream(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, err := readStream(sr); err != nil || content != "from backup" {
+			t.Fatalf("content = %q, err = %v", content, err)
+		}
+	})
+}
+
+func TestFailoverWithTools(t *testing.T) {
+	m := newFailover(t, &FailoverConfig{Backends: []FailoverBackend{
+		{Name: "primary", Model: newFake(t, &FakeResponse{Error: "status code: 503"})},
+		{Name: "backup", Model: &stubChatModel{}, Timeout: time.Second},
+	}})
+	bound, err := m.WithTools([]*schema.ToolInfo{{Name: "search"}, {Name: "fetch"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := bound.Generate(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "search,fetch" {
+		t.Fatalf("backup tools = %q, want search,fetch", msg.Content)
+	}
+	if got := bound.(*failoverChatModel).cfg.Backends[1].Timeout; got != time.Second {
+		t.Fatalf("backup timeout after WithTools = %v", got)
+	}
+}
+
+func TestRegistryFailoverTimeouts(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.yaml")
+	if err := os.WriteFile(fixture, []byte("agents:\n  \"*\":\n    - content: ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig([]byte(`
+default: primary
+profiles:
+  primary:
+    provider: fake
+    fixture: ` + fixture + `
+    timeout: 5s
+    fallbacks: [backup, nolimit]
+  backup:
+    provider: fake
+    fixture: ` + fixture + `
+    timeout: 30s
+  nolimit:
+    provider: fake
+    fixture: ` + fixture + `
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm, err := NewRegistry(cfg).ChatModel(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := cm.(*failoverChatModel)
+	if !ok {
+		t.Fatalf("model = %T, want failover", cm)
+	}
+	want := []time.Duration{5 * time.Second, 30 * time.Second, 0}
+	for i, b := range f.cfg.Backends {
+		if got := f.timeout(b); got != want[i] {
+			t.Errorf("%s timeout = %v, want %v", b.Name, got, want[i])
+		}
+	}
+}
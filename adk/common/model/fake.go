@@ -0,0 +1,284 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderFake 离线使用的脚本化假模型，不需要任何 API Key
+const ProviderFake = "fake"
+
+// FakeScript 假模型的脚本，按 agent 名称和轮次匹配预设的回复
+//
+// 示例（fixture.yaml）：
+//
+//	chunk_size: 4
+//	agents:
+//	  main_agent:
+//	    - tool_calls:
+//	        - id: call_1
+//	          name: execute_command
+//	          arguments: '{"command": "ls"}'
+//	      usage: {prompt_tokens: 120, completion_tokens: 18}
+//	    - content: 当前目录下有 main.go 和 go.mod
+//	  critique_agent:
+//	    - reasoning_content: 回答已经完整
+//	      tool_calls:
+//	        - name: submit_critique
+//	          arguments: '{"verdict": "pass", "score": 9, "summary": "done"}'
+//	  "*":
+//	    - content: 没有为该 agent 编写脚本时使用这里的回复
+type FakeScript struct {
+	// ChunkSize 流式输出时每个 chunk 的字符数（按 rune 计），0 表示整段内容作为一个 chunk
+	ChunkSize int `yaml:"chunk_size"`
+	// Agents agent 名称 -> 按轮次排列的回复，"*" 匹配未列出的 agent
+	Agents map[string][]*FakeResponse `yaml:"agents"`
+}
+
+// FakeResponse 一轮预设的回复
+type FakeResponse struct {
+	Content          string          `yaml:"content"`
+	ReasoningContent string          `yaml:"reasoning_content"`
+	ToolCalls        []*FakeToolCall `yaml:"tool_calls"`
+	Usage            *FakeUsage      `yaml:"usage"`
+	// Chunks 可选，显式指定流式输出时 Content 的切分方式，拼接后应与 Content 一致
+	Chunks []string `yaml:"chunks"`
+	// Error 非空时本轮直接返回该错误，用于模拟模型故障
+	Error string `yaml:"error"`
+}
+
+// FakeToolCall 预设的工具调用
+type FakeToolCall struct {
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Arguments string `yaml:"arguments"`
+}
+
+// FakeUsage 预设的 token 用量
+type FakeUsage struct {
+	PromptTokens     int `yaml:"prompt_tokens"`
+	CompletionTokens int `yaml:"completion_tokens"`
+	ReasoningTokens  int `yaml:"reasoning_tokens"`
+}
+
+// LoadFakeScript 从 YAML（或 JSON）文件加载假模型脚本
+func LoadFakeScript(path string) (*FakeScript, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fake script %q: %w", path, err)
+	}
+	script := &FakeScript{}
+	if err := yaml.Unmarshal(bs, script); err != nil {
+		return nil, fmt.Errorf("parse fake script %q: %w", path, err)
+	}
+	return script, nil
+}
+
+// NewFakeChatModel 创建一个按脚本回复的 ChatModel
+//
+// 每次 Generate 或 Stream 调用算一轮，第 n 轮返回 script 中 agent 对应列表的第 n 项；
+// 通过 WithTools 得到的新实例与原实例共享轮次计数
+func NewFakeChatModel(script *FakeScript, agent string) (model.ToolCallingChatModel, error) {
+	responses, ok := script.Agents[agent]
+	if !ok {
+		responses, ok = script.Agents["*"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("fake script has no responses for agent %q", agent)
+	}
+	return &fakeChatModel{
+		agent:     agent,
+		chunkSize: script.ChunkSize,
+		responses: responses,
+		turn:      &fakeTurn{},
+	}, nil
+}
+
+type fakeTurn struct {
+	mu sync.Mutex
+	n  int
+}
+
+type fakeChatModel struct {
+	agent     string
+	chunkSize int
+	responses []*FakeResponse
+	tools     []*schema.ToolInfo
+	turn      *fakeTurn
+}
+
+// next 取出下一轮的回复，同时返回这是第几轮（从 1 开始）
+func (f *fakeChatModel) next() (*FakeResponse, int, error) {
+	f.turn.mu.Lock()
+	defer f.turn.mu.Unlock()
+	if f.turn.n >= len(f.responses) {
+		return nil, 0, fmt.Errorf("fake model: agent %q has no scripted response for turn %d", f.agent, f.turn.n+1)
+	}
+	resp := f.responses[f.turn.n]
+	f.turn.n++
+	turn := f.turn.n
+	if resp.Error != "" {
+		return nil, 0, fmt.Errorf("fake model: %s", resp.Error)
+	}
+	// 绑定了工具时，校验脚本中的工具调用确实存在，避免脚本与 agent 配置不一致
+	for _, tc := range resp.ToolCalls {
+		if len(f.tools) > 0 && !f.hasTool(tc.Name) {
+			return nil, 0, fmt.Errorf("fake model: agent %q turn %d calls unbound tool %q", f.agent, turn, tc.Name)
+		}
+	}
+	return resp, turn, nil
+}
+
+func (f *fakeChatModel) hasTool(name string) bool {
+	for _, t := range f.tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	resp, turn, err := f.next()
+	if err != nil {
+		return nil, err
+	}
+	msg := &schema.Message{
+		Role:             schema.Assistant,
+		Content:          resp.Content,
+		ReasoningContent: resp.ReasoningContent,
+		ResponseMeta:     resp.responseMeta(),
+	}
+	for i, tc := range resp.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, tc.toolCall(i, f.turnID(turn, i), true))
+	}
+	return msg, nil
+}
+
+func (f *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	resp, turn, err := f.next()
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*schema.Message
+	if resp.ReasoningContent != "" {
+		for _, part := range splitRunes(resp.ReasoningContent, f.chunkSize) {
+			chunks = append(chunks, &schema.Message{Role: schema.Assistant, ReasoningContent: part})
+		}
+	}
+	contentChunks := resp.Chunks
+	if len(contentChunks) == 0 && resp.Content != "" {
+		contentChunks = splitRunes(resp.Content, f.chunkSize)
+	}
+	for _, part := range contentChunks {
+		chunks = append(chunks, &schema.Message{Role: schema.Assistant, Content: part})
+	}
+	// 工具调用模拟真实模型的流式输出：第一个 chunk 带 ID 和名称，后续 chunk 只带参数片段，靠 Index 关联
+	for i, tc := range resp.ToolCalls {
+		chunks = append(chunks, &schema.Message{
+			Role:      schema.Assistant,
+			ToolCalls: []schema.ToolCall{tc.toolCall(i, f.turnID(turn, i), false)},
+		})
+		for _, part := range splitRunes(tc.Arguments, f.chunkSize) {
+			index := i
+			chunks = append(chunks, &schema.Message{
+				Role: schema.Assistant,
+				ToolCalls: []schema.ToolCall{{
+					Index:    &index,
+					Function: schema.FunctionCall{Arguments: part},
+				}},
+			})
+		}
+	}
+	// 最后一个 chunk 携带结束原因和用量
+	chunks = append(chunks, &schema.Message{Role: schema.Assistant, ResponseMeta: resp.responseMeta()})
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+// turnID 为第 turn 轮未指定 ID 的工具调用生成稳定的 ID
+// turn 使用 next 返回的轮次，并发调用时不会读到其他调用推进后的计数
+func (f *fakeChatModel) turnID(turn, i int) string {
+	return fmt.Sprintf("fake_%s_%d_%d", f.agent, turn, i)
+}
+
+func (f *fakeChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	nf := *f
+	nf.tools = tools
+	return &nf, nil
+}
+
+func (f *fakeChatModel) GetType() string {
+	return "Fake"
+}
+
+func (r *FakeResponse) responseMeta() *schema.ResponseMeta {
+	meta := &schema.ResponseMeta{FinishReason: "stop"}
+	if len(r.ToolCalls) > 0 {
+		meta.FinishReason = "tool_calls"
+	}
+	if r.Usage != nil {
+		meta.Usage = &schema.TokenUsage{
+			PromptTokens:     r.Usage.PromptTokens,
+			CompletionTokens: r.Usage.CompletionTokens,
+			TotalTokens:      r.Usage.PromptTokens + r.Usage.CompletionTokens,
+			CompletionTokensDetails: schema.CompletionTokensDetails{
+				ReasoningTokens: r.Usage.ReasoningTokens,
+			},
+		}
+	}
+	return meta
+}
+
+// toolCall 转换为 schema.ToolCall，withArgs 为 false 时只保留 ID 和名称（流式的首个 chunk）
+func (tc *FakeToolCall) toolCall(index int, defaultID string, withArgs bool) schema.ToolCall {
+	id := tc.ID
+	if id == "" {
+		id = defaultID
+	}
+	call := schema.ToolCall{
+		Index:    &index,
+		ID:       id,
+		Type:     "function",
+		Function: schema.FunctionCall{Name: tc.Name},
+	}
+	if withArgs {
+		call.Function.Arguments = tc.Arguments
+	}
+	return call
+}
+
+// splitRunes 按 rune 数切分字符串，size <= 0 时不切分
+func splitRunes(s string, size int) []string {
+	if s == "" {
+		return nil
+	}
+	rs := []rune(s)
+	if size <= 0 || len(rs) <= size {
+		return []string{s}
+	}
+	parts := make([]string, 0, len(rs)/size+1)
+	for i := 0; i < len(rs); i += size {
+		end := min(i+size, len(rs))
+		parts = append(parts, string(rs[i:end]))
+	}
+	return parts
+}
+
+// newFakeChatModel 作为 provider 使用时，Profile.Fixture 指定脚本文件，agent 名称由 ForAgent 传入
+func newFakeChatModel(ctx context.Context, p *Profile) (model.ToolCallingChatModel, error) {
+	if p.Fixture == "" {
+		return nil, fmt.Errorf("fake provider requires fixture")
+	}
+	script, err := LoadFakeScript(p.Fixture)
+	if err != nil {
+		return nil, err
+	}
+	return NewFakeChatModel(script, agentNameFrom(ctx))
+}
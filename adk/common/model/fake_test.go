@@ -0,0 +1,224 @@
+package model
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// collectStream 读完整条流，返回所有 chunk
+func collectStream(t *testing.T, sr *schema.StreamReader[*schema.Message]) []*schema.Message {
+	t.Helper()
+	defer sr.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestFakeChatModelAgentsAndTurns(t *testing.T) {
+	script := &FakeScript{Agents: map[string][]*FakeResponse{
+		"main": {{Content: "first"}, {Content: "second"}},
+		"*":    {{Content: "default"}},
+	}}
+	ctx := context.Background()
+
+	m, err := NewFakeChatModel(script, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WithTools 得到的新实例与原实例共享轮次
+	bound, err := m.WithTools([]*schema.ToolInfo{{Name: "search"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range []struct {
+		generate func() (*schema.Message, error)
+		want     string
+	}{
+		{func() (*schema.Message, error) { return m.Generate(ctx, nil) }, "first"},
+		{func() (*schema.Message, error) { return bound.Generate(ctx, nil) }, "second"},
+	} {
+		msg, err := tt.generate()
+		if err != nil {
+			t.Fatalf("turn %d: %v", i+1, err)
+		}
+		if msg.Content != tt.want {
+			t.Fatalf("turn %d = %q, want %q", i+1, msg.Content, tt.want)
+		}
+	}
+	if _, err := m.Generate(ctx, nil); err == nil || !strings.Contains(err.Error(), "turn 3") {
+		t.Fatalf("err = %v, want no scripted response for turn 3", err)
+	}
+
+	other, err := NewFakeChatModel(script, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := other.Generate(ctx, nil); err != nil || msg.Content != "default" {
+		t.Fatalf("unlisted agent = %v, %v; want the \"*\" response", msg, err)
+	}
+
+	delete(script.Agents, "*")
+	if _, err := NewFakeChatModel(script, "other"); err == nil {
+		t.Fatal("want error for an agent without responses")
+	}
+}
+
+func TestFakeChatModelErrors(t *testing.T) {
+	ctx := context.Background()
+	script := &FakeScript{Agents: map[string][]*FakeResponse{"a": {
+		{Error: "status code: 429"},
+		{ToolCalls: []*FakeToolCall{{Name: "missing"}}},
+		{ToolCalls: []*FakeToolCall{{Name: "search"}}},
+	}}}
+	m, err := NewFakeChatModel(script, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = m.WithTools([]*schema.ToolInfo{{Name: "search"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Generate(ctx, nil)
+	if err == nil || StatusCode(err) != 429 {
+		t.Fatalf("err = %v, want scripted 429", err)
+	}
+	if _, err := m.Stream(ctx, nil); err == nil || !strings.Contains(err.Error(), `unbound tool "missing"`) {
+		t.Fatalf("err = %v, want unbound tool", err)
+	}
+	msg, err := m.Generate(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未指定 ID 时按 agent、轮次和序号生成
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "fake_a_3_0" {
+		t.Fatalf("tool calls = %+v, want ID fake_a_3_0", msg.ToolCalls)
+	}
+}
+
+func TestFakeChatModelStream(t *testing.T) {
+	script := &FakeScript{ChunkSize: 3, Agents: map[string][]*FakeResponse{"a": {{
+		ReasoningContent: "想一想再回答",
+		Content:          "中文和English",
+		ToolCalls: []*FakeToolCall{
+			{ID: "call_1", Name: "search", Arguments: `{"q": "eino"}`},
+			{Name: "fetch", Arguments: `{"url": "x"}`},
+		},
+		Usage: &FakeUsage{PromptTokens: 10, CompletionTokens: 5, ReasoningTokens: 2},
+	}}}}
+	m, err := NewFakeChatModel(script, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := collectStream(t, mustStream(t, m.Stream))
+
+	var content []string
+	for _, c := range chunks {
+		if c.Content != "" {
+			content = append(content, c.Content)
+		}
+		for _, tc := range c.ToolCalls {
+			if tc.Index == nil {
+				t.Fatalf("tool call chunk without Index: %+v", tc)
+			}
+		}
+	}
+	if want := []string{"中文和", "Eng", "lis", "h"}; strings.Join(content, "|") != strings.Join(want, "|") {
+		t.Fatalf("content chunks = %q, want %q", content, want)
+	}
+
+	msg, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ReasoningContent != "想一想再回答" || msg.Content != "中文和English" {
+		t.Fatalf("merged = %q / %q", msg.ReasoningContent, msg.Content)
+	}
+	if len(msg.ToolCalls) != 2 {
+		t.Fatalf("merged tool calls = %+v", msg.ToolCalls)
+	}
+	for i, want := range []schema.ToolCall{
+		{ID: "call_1", Function: schema.FunctionCall{Name: "search", Arguments: `{"q": "eino"}`}},
+		{ID: "fake_a_1_1", Function: schema.FunctionCall{Name: "fetch", Arguments: `{"url": "x"}`}},
+	} {
+		got := msg.ToolCalls[i]
+		if got.ID != want.ID || got.Function != want.Function {
+			t.Errorf("tool call %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	meta := chunks[len(chunks)-1].ResponseMeta
+	if meta == nil || meta.FinishReason != "tool_calls" || meta.Usage == nil {
+		t.Fatalf("last chunk meta = %+v", meta)
+	}
+	if u := meta.Usage; u.PromptTokens != 10 || u.CompletionTokens != 5 || u.TotalTokens != 15 ||
+		u.CompletionTokensDetails.ReasoningTokens != 2 {
+		t.Fatalf("usage = %+v", u)
+	}
+}
+
+func TestFakeChatModelExplicitChunks(t *testing.T) {
+	script := &FakeScript{ChunkSize: 2, Agents: map[string][]*FakeResponse{"a": {{
+		Content: "hello world",
+		Chunks:  []string{"hello ", "world"},
+	}}}}
+	m, err := NewFakeChatModel(script, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := collectStream(t, mustStream(t, m.Stream))
+	var content []string
+	for _, c := range chunks {
+		if c.Content != "" {
+			content = append(content, c.Content)
+		}
+	}
+	if strings.Join(content, "|") != "hello |world" {
+		t.Fatalf("content chunks = %q", content)
+	}
+	if meta := chunks[len(chunks)-1].ResponseMeta; meta.FinishReason != "stop" || meta.Usage != nil {
+		t.Fatalf("last chunk meta = %+v", meta)
+	}
+}
+
+func TestFakeProviderFixture(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.yaml")
+	if err := os.WriteFile(fixture, []byte("agents:\n  critic:\n    - content: ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := withAgentName(context.Background(), "critic")
+	m, err := NewChatModelFromProfile(ctx, &Profile{Name: "fake", Provider: ProviderFake, Fixture: fixture})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := m.Generate(ctx, nil); err != nil || msg.Content != "ok" {
+		t.Fatalf("generate = %v, %v", msg, err)
+	}
+	if _, err := NewChatModelFromProfile(ctx, &Profile{Name: "fake", Provider: ProviderFake}); err == nil {
+		t.Fatal("want error without fixture")
+	}
+}
+
+func mustStream(t *testing.T, stream func(context.Context, []*schema.Message, ...model.Option) (*schema.StreamReader[*schema.Message], error)) *schema.StreamReader[*schema.Message] {
+	t.Helper()
+	sr, err := stream(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sr
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+const (
+	ProviderArk    = "ark"
+	ProviderOpenAI = "openai"
+)
+
+const (
+	ThinkingDisabled = "disabled"
+	ThinkingEnabled  = "enabled"
+	ThinkingAuto     = "auto"
+
+	ThinkingEffortMinimal = "minimal"
+	ThinkingEffortLow     = "low"
+	ThinkingEffortMedium  = "medium"
+	ThinkingEffortHigh    = "high"
+)
+
+// ProviderFactory 根据 Profile 创建对应提供方的 ChatModel
+type ProviderFactory func(ctx context.Context, p *Profile) (model.ToolCallingChatModel, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		ProviderArk:    newArkChatModel,
+		ProviderOpenAI: newOpenAIChatModel,
+		ProviderFake:   newFakeChatModel,
+	}
+)
+
+// ChatModelWrapper 在按 Profile 创建出 ChatModel 之后调用，用于套上重试、限流等中间件
+type ChatModelWrapper func(p *Profile, cm model.ToolCallingChatModel) (model.ToolCallingChatModel, error)
+
+var (
+	wrappersMu sync.RWMutex
+	wrappers   []ChatModelWrapper
+)
+
+// RegisterChatModelWrapper 注册一个 ChatModelWrapper，按注册顺序由内向外套用
+// 一般在中间件包的 init 中调用
+func RegisterChatModelWrapper(w ChatModelWrapper) {
+	wrappersMu.Lock()
+	defer wrappersMu.Unlock()
+	wrappers = append(wrappers, w)
+}
+
+// RegisterProvider 注册（或覆盖）一个模型提供方
+// 一般在 init 中调用，用于接入 ark、openai 之外的模型
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// NewChatModelFromProfile 按 Profile 的 provider 字段创建 ChatModel，并套上已注册的 ChatModelWrapper
+func NewChatModelFromProfile(ctx context.Context, p *Profile) (model.ToolCallingChatModel, error) {
+	providersMu.RLock()
+	factory, ok := providers[p.Provider]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("profile %q: unknown provider %q", p.Name, p.Provider)
+	}
+	cm, err := factory(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+
+	wrappersMu.RLock()
+	defer wrappersMu.RUnlock()
+	for _, w := range wrappers {
+		if cm, err = w(p, cm); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	return cm, nil
+}
+
+// Registry 持有一份模型配置，按 profile 名称或 agent 名称创建 ChatModel
+type Registry struct {
+	cfg *Config
+}
+
+// NewRegistry 基于已加载的配置创建 Registry
+func NewRegistry(cfg *Config) *Registry {
+	return &Registry{cfg: cfg}
+}
+
+// LoadRegistry 从 YAML 配置文件创建 Registry
+func LoadRegistry(path string) (*Registry, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(cfg), nil
+}
+
+// Profile 返回指定名称的 Profile，名称为空时返回默认 Profile
+func (r *Registry) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = r.cfg.Default
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no profile name given and no default profile configured")
+	}
+	p, ok := r.cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return p, nil
+}
+
+// Profiles 返回所有 profile 名称，按字典序排列
+func (r *Registry) Profiles() []string {
+	names := make([]string, 0, len(r.cfg.Profiles))
+	for name := range r.cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChatModel 按 profile 名称创建 ChatModel，名称为空时使用默认 profile
+// profile 配置了 fallbacks 时返回故障切换 ChatModel（备用 profile 自身的 fallbacks 不再展开），
+// 每个后端使用各自 profile 的 timeout
+func (r *Registry) ChatModel(ctx context.Context, name string) (model.ToolCallingChatModel, error) {
+	p, err := r.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := NewChatModelFromProfile(ctx, p)
+	if err != nil || len(p.Fallbacks) == 0 {
+		return cm, err
+	}
+
+	backends := []FailoverBackend{{Name: p.Name, Model: cm, Timeout: p.Timeout}}
+	for _, fb := range p.Fallbacks {
+		fp, err := r.Profile(fb)
+		if err != nil {
+			return nil, err
+		}
+		fcm, err := NewChatModelFromProfile(ctx, fp)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, FailoverBackend{Name: fp.Name, Model: fcm, Timeout: fp.Timeout})
+	}
+	return NewFailoverChatModel(&FailoverConfig{Backends: backends})
+}
+
+// ForAgent 按 agents 映射为指定 agent 创建 ChatModel，未配置映射时使用默认 profile
+func (r *Registry) ForAgent(ctx context.Context, agentName string) (model.ToolCallingChatModel, error) {
+	return r.ChatModel(withAgentName(ctx, agentName), r.cfg.Agents[agentName])
+}
+
+type agentNameKey struct{}
+
+// withAgentName 把 agent 名称传给 ProviderFactory，供需要区分 agent 的提供方（如 fake）使用
+func withAgentName(ctx context.Context, agentName string) context.Context {
+	return context.WithValue(ctx, agentNameKey{}, agentName)
+}
+
+func agentNameFrom(ctx context.Context) string {
+	name, _ := ctx.Value(agentNameKey{}).(string)
+	return name
+}
+
+// NewAgentChatModel 为指定 agent 创建 ChatModel
+//
+// 如果设置了环境变量 MODEL_CONFIG（YAML 配置文件路径），则按配置中的 agents 映射选择 profile；
+// 否则退回到 MODEL_TYPE 等环境变量方式（见 ProfileFromEnv）
+func NewAgentChatModel(ctx context.Context, agentName string) (model.ToolCallingChatModel, error) {
+	path := os.Getenv("MODEL_CONFIG")
+	if path == "" {
+		p := ProfileFromEnv()
+		if err := p.validateThinking(); err != nil {
+			return nil, err
+		}
+		return NewChatModelFromProfile(withAgentName(ctx, agentName), p)
+	}
+	r, err := LoadRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.ForAgent(ctx, agentName)
+}
@@ -0,0 +1,128 @@
+package prints
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/stream"
+)
+
+// 记录类型
+const (
+	RecordEvent = "event" // 一个完整的事件，流式消息合并后输出
+	RecordDelta = "delta" // 流式消息的一个 chunk，只在开启 deltas 时输出
+)
+
+// EventRecord JSONL 格式中的一行
+type EventRecord struct {
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	Agent      string            `json:"agent"`
+	RunPath    []string          `json:"run_path,omitempty"`
+	Role       schema.RoleType   `json:"role,omitempty"`
+	Content    string            `json:"content,omitempty"`
+	Reasoning  string            `json:"reasoning,omitempty"`
+	ToolName   string            `json:"tool_name,omitempty"` // 工具消息对应的工具
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCallRecord  `json:"tool_calls,omitempty"`
+	Streaming  bool              `json:"streaming,omitempty"`
+	TransferTo string            `json:"transfer_to,omitempty"`
+	Interrupts []InterruptRecord `json:"interrupts,omitempty"`
+	Exit       bool              `json:"exit,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ToolCallRecord 模型发起的一次工具调用
+type ToolCallRecord struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// InterruptRecord 一个中断点
+type InterruptRecord struct {
+	ID        string `json:"id"`
+	RootCause bool   `json:"root_cause,omitempty"`
+	Info      string `json:"info,omitempty"`
+}
+
+// NewJSONLRenderer 每个事件输出一个 JSON 对象（JSON Lines），便于 CI 和前端解析
+// deltas 为 true 时，流式消息的每个 chunk 先输出一条 delta 记录，最后仍然输出完整的 event 记录
+func NewJSONLRenderer(w io.Writer, deltas bool) EventRenderer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonlRenderer{enc: enc, deltas: deltas}
+}
+
+type jsonlRenderer struct {
+	enc    *json.Encoder
+	deltas bool
+}
+
+func (j *jsonlRenderer) Render(event *adk.AgentEvent) error {
+	base := EventRecord{Agent: event.AgentName, RunPath: runPath(event)}
+
+	var onChunk stream.ChunkFunc
+	if j.deltas {
+		onChunk = func(chunk *schema.Message) error {
+			if chunk.Content == "" && chunk.ReasoningContent == "" {
+				return nil
+			}
+			r := base
+			r.Type, r.Time = RecordDelta, time.Now()
+			r.Role, r.Content, r.Reasoning = chunk.Role, chunk.Content, chunk.ReasoningContent
+			return j.enc.Encode(&r)
+		}
+	}
+	m, err := stream.EventMessage(event, onChunk)
+	if err != nil {
+		return err
+	}
+	return j.enc.Encode(NewEventRecord(event, m))
+}
+
+// NewEventRecord 把事件和其中的完整消息转换成 event 记录，m 为 nil 表示事件中没有消息
+// 流式消息需要先读完，例如用 stream.EventMessage
+func NewEventRecord(event *adk.AgentEvent, m *schema.Message) *EventRecord {
+	r := &EventRecord{Type: RecordEvent, Time: time.Now(), Agent: event.AgentName, RunPath: runPath(event)}
+	if m != nil {
+		r.Role, r.Content, r.Reasoning = m.Role, m.Content, m.ReasoningContent
+		r.ToolName, r.ToolCallID = m.ToolName, m.ToolCallID
+		if event.Output != nil && event.Output.MessageOutput != nil {
+			r.Streaming = event.Output.MessageOutput.IsStreaming
+		}
+		for _, tc := range m.ToolCalls {
+			r.ToolCalls = append(r.ToolCalls, ToolCallRecord{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+		}
+	}
+	if a := event.Action; a != nil {
+		if a.TransferToAgent != nil {
+			r.TransferTo = a.TransferToAgent.DestAgentName
+		}
+		if a.Interrupted != nil {
+			for _, ic := range a.Interrupted.InterruptContexts {
+				r.Interrupts = append(r.Interrupts, InterruptRecord{ID: ic.ID, RootCause: ic.IsRootCause, Info: InterruptInfo(ic)})
+			}
+		}
+		r.Exit = a.Exit
+	}
+	if event.Err != nil {
+		r.Error = event.Err.Error()
+	}
+	return r
+}
+
+func runPath(event *adk.AgentEvent) []string {
+	if len(event.RunPath) == 0 {
+		return nil
+	}
+	path := make([]string, len(event.RunPath))
+	for i := range event.RunPath {
+		path[i] = event.RunPath[i].String()
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package prints
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/stream"
+)
+
+// NewMarkdownRenderer 输出 Markdown 格式的对话记录，连续的同一 Agent 的事件归在一个标题下
+// 思考过程为引用块，工具调用参数和工具返回为代码块，流式消息读完后整段输出
+func NewMarkdownRenderer(w io.Writer) EventRenderer {
+	return &markdownRenderer{w: w}
+}
+
+type markdownRenderer struct {
+	w         io.Writer
+	lastAgent string
+}
+
+func (md *markdownRenderer) Render(event *adk.AgentEvent) error {
+	m, err := stream.EventMessage(event, nil)
+	if err != nil {
+		return err
+	}
+
+	ew := &errWriter{w: md.w}
+	if event.AgentName != md.lastAgent {
+		md.lastAgent = event.AgentName
+		ew.printf("## %s\n\n", event.AgentName)
+	}
+	if m != nil {
+		if m.ReasoningContent != "" {
+			ew.printf("%s\n\n", quote(m.ReasoningContent))
+		}
+		if m.Content != "" {
+			if m.Role == schema.Tool {
+				name := m.ToolName
+				if name == "" {
+					name = "tool"
+				}
+				ew.printf("**工具返回** `%s`\n\n%s\n", name, fence(m.Content, ""))
+			} else {
+				ew.printf("%s\n\n", m.Content)
+			}
+		}
+		for _, tc := range m.ToolCalls {
+			ew.printf("**调用工具** `%s`\n\n%s\n", tc.Function.Name, fence(prettyJSON(tc.Function.Arguments), "json"))
+		}
+	}
+	if a := event.Action; a != nil {
+		if a.TransferToAgent != nil {
+			ew.printf("*转交给 %s*\n\n", a.TransferToAgent.DestAgentName)
+		}
+		if a.Interrupted != nil {
+			for _, ic := range a.Interrupted.InterruptContexts {
+				if ic.IsRootCause {
+					ew.printf("**中断**\n\n%s\n\n", quote(InterruptInfo(ic)))
+				}
+			}
+		}
+		if a.Exit {
+			ew.printf("*退出*\n\n")
+		}
+	}
+	if event.Err != nil {
+		ew.printf("**错误**\n\n%s\n", fence(event.Err.Error(), ""))
+	}
+	return ew.err
+}
+
+// quote 每一行加上 "> "，空行也要保留引用标记，否则引用块会断开
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight("> "+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fence 代码块的围栏比内容中最长的连续反引号多一个，内容中的 ``` 不会提前结束代码块
+func fence(s, lang string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	f := strings.Repeat("`", max(3, longest+1))
+	return f + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + f + "\n"
+}
+
+// prettyJSON 工具参数格式化成缩进的 JSON，保留原有的字段顺序，不是合法 JSON 时原样返回
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,110 @@
+package prints
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+
+	"eino-learn/adk/common/stream"
+)
+
+// EventRenderer 把 AgentEvent 输出到 io.Writer
+//
+// 流式消息会被读完，但事件中的流会换成一个副本，渲染之后仍然可以用 adk.GetMessage 读取完整消息；
+// 流中的错误和写入错误通过返回值返回，event.Err 作为事件内容输出而不是返回
+type EventRenderer interface {
+	Render(event *adk.AgentEvent) error
+}
+
+// 输出格式
+const (
+	FormatText     = "text"
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+	FormatQuiet    = "quiet"
+)
+
+// NewRenderer 按格式创建 EventRenderer
+func NewRenderer(format string, w io.Writer) (EventRenderer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return NewTextRenderer(w), nil
+	case FormatJSONL:
+		return NewJSONLRenderer(w, false), nil
+	case FormatMarkdown, "md":
+		return NewMarkdownRenderer(w), nil
+	case FormatQuiet:
+		return NewQuietRenderer(), nil
+	default:
+		return nil, fmt.Errorf("unknown event format %q", format)
+	}
+}
+
+// NewRendererFromEnv 按环境变量创建 EventRenderer，未设置时与 Event 相同，以文本格式输出到标准输出
+//
+//	EVENT_FORMAT=text|jsonl|markdown|quiet   输出格式，默认 text
+//	EVENT_DELTAS=true                        jsonl 格式额外输出流式消息的每个 chunk
+//	EVENT_OUTPUT=stdout|stderr|文件路径       输出位置，默认 stdout，文件以追加方式打开
+//
+// 返回的 closeFn 用于关闭输出文件
+func NewRendererFromEnv() (r EventRenderer, closeFn func() error, err error) {
+	closeFn = func() error { return nil }
+	var w io.Writer = os.Stdout
+	switch dest := os.Getenv("EVENT_OUTPUT"); dest {
+	case "", "stdout":
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, closeFn, fmt.Errorf("open event output: %w", err)
+		}
+		w, closeFn = f, f.Close
+	}
+
+	format := os.Getenv("EVENT_FORMAT")
+	if strings.EqualFold(format, FormatJSONL) && os.Getenv("EVENT_DELTAS") == "true" {
+		return NewJSONLRenderer(w, true), closeFn, nil
+	}
+	if r, err = NewRenderer(format, w); err != nil {
+		_ = closeFn()
+		return nil, func() error { return nil }, err
+	}
+	return r, closeFn, nil
+}
+
+// NewQuietRenderer 不输出任何内容，只读完流式消息并返回流中的错误，适合只关心最终结果的场景
+func NewQuietRenderer() EventRenderer {
+	return quietRenderer{}
+}
+
+type quietRenderer struct{}
+
+func (quietRenderer) Render(event *adk.AgentEvent) error {
+	_, err := stream.EventMessage(event, nil)
+	return err
+}
+
+// InterruptInfo 中断信息的文本，优先使用 String()
+func InterruptInfo(ic *adk.InterruptCtx) string {
+	if s, ok := ic.Info.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%v", ic.Info)
+}
+
+// errWriter 记录第一个写入错误，之后的写入都跳过，渲染结束时统一返回
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
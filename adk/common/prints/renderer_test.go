@@ -0,0 +1,179 @@
+package prints
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// streamEvent 一条流式回复：思考过程、分两段到达的回答、参数分两段到达的工具调用
+func streamEvent() *adk.AgentEvent {
+	idx := 0
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		{Role: schema.Assistant, ReasoningContent: "想一想"},
+		schema.AssistantMessage("你好", nil),
+		schema.AssistantMessage("世界", nil),
+		schema.AssistantMessage("", []schema.ToolCall{{Index: &idx, ID: "call_1", Function: schema.FunctionCall{Name: "search", Arguments: `{"q":`}}}),
+		schema.AssistantMessage("", []schema.ToolCall{{Index: &idx, Function: schema.FunctionCall{Arguments: `"eino"}`}}}),
+	})
+	event := adk.EventFromMessage(nil, sr, schema.Assistant, "")
+	event.AgentName = "writer"
+	return event
+}
+
+// interruptEvent 同一个 Agent 的中断事件
+func interruptEvent() *adk.AgentEvent {
+	return &adk.AgentEvent{
+		AgentName: "writer",
+		Action: &adk.AgentAction{Interrupted: &adk.InterruptInfo{InterruptContexts: []*adk.InterruptCtx{
+			{ID: "agent:writer;tool:call_1", Info: "需要确认", IsRootCause: true},
+		}}},
+	}
+}
+
+// render 依次渲染事件，返回输出；每个流式事件渲染后都检查还能读出完整消息
+func render(t *testing.T, r EventRenderer, buf *bytes.Buffer, events ...*adk.AgentEvent) string {
+	t.Helper()
+	for _, event := range events {
+		if err := r.Render(event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Output == nil {
+			continue
+		}
+		m, _, err := adk.GetMessage(event)
+		if err != nil {
+			t.Fatalf("GetMessage after Render: %v", err)
+		}
+		if m.Content != "你好世界" || m.ReasoningContent != "想一想" || len(m.ToolCalls) != 1 ||
+			m.ToolCalls[0].Function.Arguments != `{"q":"eino"}` {
+			t.Fatalf("message after Render = %+v", m)
+		}
+	}
+	return buf.String()
+}
+
+func TestTextRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRenderer(FormatText, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := render(t, r, &buf, streamEvent(), interruptEvent())
+	for _, want := range []string{
+		"name: writer\n",
+		"\nreasoning: 想一想",
+		"\nanswer: 你好世界",
+		"\ntool name: search\narguments: {\"q\":\"eino\"}\n\n",
+		"\n需要确认\n\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestJSONLRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	out := render(t, NewJSONLRenderer(&buf, true), &buf, streamEvent(), interruptEvent())
+
+	var records []EventRecord
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var r EventRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	// 只含工具调用的 chunk 没有 delta 记录
+	if len(records) != 5 {
+		t.Fatalf("got %d records, want 3 deltas and 2 events:\n%s", len(records), out)
+	}
+	var deltas []string
+	for _, r := range records[:3] {
+		if r.Type != RecordDelta || r.Agent != "writer" {
+			t.Fatalf("record = %+v, want a delta from writer", r)
+		}
+		deltas = append(deltas, r.Reasoning+r.Content)
+	}
+	if got := strings.Join(deltas, "|"); got != "想一想|你好|世界" {
+		t.Errorf("deltas = %q", got)
+	}
+
+	ev := records[3]
+	if ev.Type != RecordEvent || !ev.Streaming || ev.Role != schema.Assistant ||
+		ev.Content != "你好世界" || ev.Reasoning != "想一想" {
+		t.Errorf("event record = %+v", ev)
+	}
+	wantCall := ToolCallRecord{ID: "call_1", Name: "search", Arguments: `{"q":"eino"}`}
+	if len(ev.ToolCalls) != 1 || ev.ToolCalls[0] != wantCall {
+		t.Errorf("tool calls = %+v, want [%+v]", ev.ToolCalls, wantCall)
+	}
+
+	in := records[4]
+	wantInterrupt := InterruptRecord{ID: "agent:writer;tool:call_1", RootCause: true, Info: "需要确认"}
+	if in.Type != RecordEvent || in.Content != "" || len(in.Interrupts) != 1 || in.Interrupts[0] != wantInterrupt {
+		t.Errorf("interrupt record = %+v", in)
+	}
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRenderer(FormatMarkdown, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := render(t, r, &buf, streamEvent(), interruptEvent())
+	want := "## writer\n\n" +
+		"> 想一想\n\n" +
+		"你好世界\n\n" +
+		"**调用工具** `search`\n\n```json\n{\n  \"q\": \"eino\"\n}\n```\n\n" +
+		"**中断**\n\n> 需要确认\n\n"
+	// 连续的同一 Agent 的事件只有一个标题
+	if out != want {
+		t.Fatalf("output =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestQuietRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRenderer(FormatQuiet, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := render(t, r, &buf, streamEvent(), interruptEvent()); out != "" {
+		t.Fatalf("quiet renderer wrote %q", out)
+	}
+}
+
+// TestRendererStreamError 流中的错误通过返回值返回
+func TestRendererStreamError(t *testing.T) {
+	boom := errors.New("boom")
+	for _, format := range []string{FormatText, FormatJSONL, FormatMarkdown, FormatQuiet} {
+		t.Run(format, func(t *testing.T) {
+			sr, sw := schema.Pipe[*schema.Message](2)
+			sw.Send(schema.AssistantMessage("半句", nil), nil)
+			sw.Send(nil, boom)
+			sw.Close()
+
+			r, err := NewRenderer(format, &bytes.Buffer{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Render(adk.EventFromMessage(nil, sr, schema.Assistant, "")); !errors.Is(err, boom) {
+				t.Fatalf("Render error = %v, want %v", err, boom)
+			}
+		})
+	}
+}
+
+func TestNewRendererUnknownFormat(t *testing.T) {
+	if _, err := NewRenderer("yaml", &bytes.Buffer{}); err == nil {
+		t.Fatal("NewRenderer(yaml) should fail")
+	}
+}
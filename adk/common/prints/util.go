@@ -3,118 +3,64 @@ package prints
 import (
 	"fmt"
 	"io"
-	"log"
-	"strings"
+	"os"
 
 	"github.com/cloudwego/eino/adk"
 	"github.com/cloudwego/eino/schema"
 
-	"eino-learn/internal/logs"
+	"eino-learn/adk/common/stream"
 )
 
+// Event 以文本格式把事件输出到标准输出，渲染失败时输出错误而不是退出进程
+// 需要其他格式、输出位置或者处理错误时使用 EventRenderer
 func Event(event *adk.AgentEvent) {
-	fmt.Printf("name: %s\npath: %s", event.AgentName, event.RunPath)
+	if err := NewTextRenderer(os.Stdout).Render(event); err != nil {
+		fmt.Printf("error: %v\n\n", err)
+	}
+}
+
+// NewTextRenderer 便于在终端阅读的文本格式，流式消息边接收边输出，单行超过 120 列时自动换行
+// 按显示宽度计算，一个中文字符占两列
+func NewTextRenderer(w io.Writer) EventRenderer {
+	return &textRenderer{w: w, width: 120}
+}
+
+type textRenderer struct {
+	w     io.Writer
+	width int
+}
+
+func (t *textRenderer) Render(event *adk.AgentEvent) error {
+	ew := &errWriter{w: t.w}
+	ew.printf("name: %s\npath: %s", event.AgentName, event.RunPath)
 	if event.Output != nil && event.Output.MessageOutput != nil {
-		// 处理非流式消息
 		if m := event.Output.MessageOutput.Message; m != nil {
 			// 处理一次性完整返回的消息
+			// 思考过程与回答分开显示
+			if len(m.ReasoningContent) > 0 {
+				ew.printf("\nreasoning: %s", m.ReasoningContent)
+			}
 			// 区分 Tool 和 普通消息
 			if len(m.Content) > 0 {
 				if m.Role == schema.Tool {
-					fmt.Printf("\ntool response: %s", m.Content)
+					ew.printf("\ntool response: %s", m.Content)
 				} else {
-					fmt.Printf("\nanswer: %s", m.Content)
+					ew.printf("\nanswer: %s", m.Content)
 				}
 			}
 			// 显示工具调用信息
-			if len(m.ToolCalls) > 0 {
-				for _, tc := range m.ToolCalls {
-					fmt.Printf("\ntool name: %s", tc.Function.Name)
-					fmt.Printf("\narguments: %s", tc.Function.Arguments)
-				}
-			}
-		} else if s := event.Output.MessageOutput.MessageStream; s != nil {
-			// 处理流式消息
-			toolMap := map[int][]*schema.Message{}
-			var contentStart bool
-			charNumOfOneRow := 0
-			maxCharNumOfOneRow := 120
-			for {
-				chunk, err := s.Recv()
-				if err != nil {
-					if err == io.EOF {
-						// 已经再无消息
-						break
-					}
-					fmt.Printf("error: %v", err)
-					return
-				}
-				if chunk.Content != "" {
-					if !contentStart {
-						contentStart = true
-						// 区分 Tool 和 普通消息
-						if chunk.Role == schema.Tool {
-							fmt.Printf("\ntool response: ")
-						} else {
-							fmt.Printf("\nanswer: ")
-						}
-					}
-
-					charNumOfOneRow += len(chunk.Content)
-					if strings.Contains(chunk.Content, "\n") {
-						charNumOfOneRow = 0
-					} else if charNumOfOneRow >= maxCharNumOfOneRow {
-						// 如果内容无换行符而且过长则换行
-						fmt.Printf("\n")
-						charNumOfOneRow = 0
-					}
-					fmt.Printf("%v", chunk.Content)
-				}
-
-				if len(chunk.ToolCalls) > 0 {
-					// 一个chunk可能由多个工具生成
-					for _, tc := range chunk.ToolCalls {
-						index := tc.Index
-						if index == nil {
-							logs.Fatalf("index is nil")
-						}
-						// 工具调用聚合：流式工具调用需要按 index 分组合并
-						toolMap[*index] = append(toolMap[*index], &schema.Message{
-							Role: chunk.Role,
-							ToolCalls: []schema.ToolCall{
-								{
-									ID:    tc.ID,
-									Type:  tc.Type,
-									Index: tc.Index,
-									Function: schema.FunctionCall{
-										Name:      tc.Function.Name,
-										Arguments: tc.Function.Arguments,
-									},
-								},
-							},
-						})
-					}
-				}
-			}
-
-			for _, msgs := range toolMap {
-				// ConcatMessages 会智能合并：
-				// - name: 取非空值
-				// - arguments: 拼接所有部分，因为 arguments 是 json 所以可以直接拼接
-				m, err := schema.ConcatMessages(msgs)
-				if err != nil {
-					log.Fatalf("ConcatMessage failed: %v", err)
-					return
-				}
-				fmt.Printf("\ntool name: %s", m.ToolCalls[0].Function.Name)
-				fmt.Printf("\narguments: %s", m.ToolCalls[0].Function.Arguments)
+			for _, tc := range m.ToolCalls {
+				ew.printf("\ntool name: %s", tc.Function.Name)
+				ew.printf("\narguments: %s", tc.Function.Arguments)
 			}
+		} else if err := t.renderStream(ew, event); err != nil {
+			return err
 		}
 	}
 	if event.Action != nil {
 		// A2A调用
 		if event.Action.TransferToAgent != nil {
-			fmt.Printf("\naction: transfer to %v", event.Action.TransferToAgent.DestAgentName)
+			ew.printf("\naction: transfer to %v", event.Action.TransferToAgent.DestAgentName)
 		}
 		// 如果是中断
 		if event.Action.Interrupted != nil {
@@ -124,21 +70,62 @@ func Event(event *adk.AgentEvent) {
 			// 多个中断点：Graph 的多个节点同时中断
 			// InterruptContexts 数组记录了所有受影响的点！
 			for _, ic := range event.Action.Interrupted.InterruptContexts {
-				str, ok := ic.Info.(fmt.Stringer)
-				if ok {
-					fmt.Printf("\n%s", str.String())
-				} else {
-					fmt.Printf("\n%v", ic.Info)
-				}
+				ew.printf("\n%s", InterruptInfo(ic))
 			}
 		}
 		if event.Action.Exit {
-			fmt.Printf("\naction: exit")
+			ew.printf("\naction: exit")
 		}
 	}
 	if event.Err != nil {
-		fmt.Printf("\nerror: %v", event.Err)
+		ew.printf("\nerror: %v", event.Err)
+	}
+	ew.printf("\n\n")
+	return ew.err
+}
+
+// renderStream 思考过程和回答边接收边输出，工具调用在流结束后合并输出
+func (t *textRenderer) renderStream(ew *errWriter, event *adk.AgentEvent) error {
+	var reasoningStart, contentStart bool
+	wrapper := &LineWrapper{Width: t.width}
+	write := func(text string) {
+		ew.printf("%s", wrapper.Wrap(text))
+	}
+	m, err := stream.EventMessage(event, func(chunk *schema.Message) error {
+		// 思考过程先于回答输出，单独一段显示
+		if chunk.ReasoningContent != "" {
+			if !reasoningStart {
+				reasoningStart = true
+				label := "reasoning: "
+				ew.printf("\n%s", label)
+				// 标签和内容在同一行，计入已占用的列数
+				wrapper.col = Width(label)
+			}
+			write(chunk.ReasoningContent)
+		}
+		if chunk.Content != "" {
+			if !contentStart {
+				contentStart = true
+				// 区分 Tool 和 普通消息
+				label := "answer: "
+				if chunk.Role == schema.Tool {
+					label = "tool response: "
+				}
+				ew.printf("\n%s", label)
+				wrapper.col = Width(label)
+			}
+			write(chunk.Content)
+		}
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		for _, tc := range m.ToolCalls {
+			ew.printf("\ntool name: %s", tc.Function.Name)
+			ew.printf("\narguments: %s", tc.Function.Arguments)
+		}
 	}
-	fmt.Println()
-	fmt.Println()
+	return ew.err
 }
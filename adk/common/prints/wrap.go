@@ -0,0 +1,49 @@
+package prints
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// LineWrapper 按终端显示宽度给流式输出的文本自动换行，中日韩文字和全角符号占两列
+// 文本分多次写入时记住当前行已经占用的列数；文本中的换行会重新开始计数
+type LineWrapper struct {
+	Width int // 每行最多占用的列数，<= 0 时不换行
+	col   int
+}
+
+// Wrap 返回插入了换行的文本，宽字符不会被拆到两行
+func (l *LineWrapper) Wrap(text string) string {
+	if l.Width <= 0 {
+		return text
+	}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for _, r := range text {
+		if r == '\n' {
+			sb.WriteRune(r)
+			l.col = 0
+			continue
+		}
+		w := runewidth.RuneWidth(r)
+		if l.col > 0 && l.col+w > l.Width {
+			sb.WriteByte('\n')
+			l.col = 0
+		}
+		sb.WriteRune(r)
+		l.col += w
+	}
+	return sb.String()
+}
+
+// Wrap 按显示宽度把文本折成不超过 width 列的多行，用于整段重新排版（例如终端窗口大小变化后）
+func Wrap(text string, width int) string {
+	l := &LineWrapper{Width: width}
+	return l.Wrap(text)
+}
+
+// Width 文本在终端中占用的列数
+func Width(text string) int {
+	return runewidth.StringWidth(text)
+}
@@ -0,0 +1,83 @@
+package prints
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{"ascii", "abcdefg", 3, "abc\ndef\ng"},
+		{"cjk", "中文换行测试", 4, "中文\n换行\n测试"},
+		// 宽度为奇数时宽字符不会被拆到两行，放不下就整个换到下一行
+		{"cjk odd width", "中文换行", 3, "中\n文\n换\n行"},
+		{"mixed", "ab中文cd", 4, "ab中\n文cd"},
+		{"full-width punctuation", "好，的。", 4, "好，\n的。"},
+		{"newline resets", "ab\ncdef", 3, "ab\ncde\nf"},
+		{"exact fit", "abc", 3, "abc"},
+		{"no wrap", "abcdef", 0, "abcdef"},
+		// 宽度小于单个宽字符时每行一个字符
+		{"narrower than a rune", "中文", 1, "中\n文"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Wrap(tt.text, tt.width); got != tt.want {
+				t.Fatalf("Wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineWrapperStreaming 分多次写入时与一次写入的结果相同
+func TestLineWrapperStreaming(t *testing.T) {
+	text := "流式输出mixed宽度的文本\n第二行也要换行"
+	want := Wrap(text, 7)
+	l := &LineWrapper{Width: 7}
+	var sb strings.Builder
+	for _, r := range text {
+		sb.WriteString(l.Wrap(string(r)))
+	}
+	if sb.String() != want {
+		t.Fatalf("streamed = %q, want %q", sb.String(), want)
+	}
+	for _, line := range strings.Split(want, "\n") {
+		if Width(line) > 7 {
+			t.Errorf("line %q is %d columns wide", line, Width(line))
+		}
+	}
+}
+
+// TestTextRendererWrapsAfterLabel 流式回答与标签在同一行，标签占用的列数计入第一行
+func TestTextRendererWrapsAfterLabel(t *testing.T) {
+	var buf bytes.Buffer
+	r := &textRenderer{w: &buf, width: 14}
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage("一二三", nil),
+		schema.AssistantMessage("四五六七八", nil),
+	})
+	if err := r.Render(adk.EventFromMessage(nil, sr, schema.Assistant, "")); err != nil {
+		t.Fatal(err)
+	}
+	// "answer: " 占 8 列，第一行只能再放 3 个中文字符
+	want := "\nanswer: 一二三\n四五六七八\n\n"
+	if out := buf.String(); !strings.HasSuffix(out, want) {
+		t.Fatalf("output = %q, want suffix %q", out, want)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "path: ") {
+			continue
+		}
+		if Width(line) > 14 {
+			t.Errorf("line %q is %d columns wide", line, Width(line))
+		}
+	}
+}
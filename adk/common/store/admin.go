@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Admin 检查点存储的管理接口，本包的所有存储都实现了该接口
+//
+//	if admin, ok := s.(store.Admin); ok {
+//		infos, _ := admin.List(ctx, "")
+//	}
+type Admin interface {
+	// Delete 删除检查点，返回是否存在
+	Delete(ctx context.Context, key string) (bool, error)
+	// List 列出 key 以 prefix 开头的检查点，按更新时间倒序，prefix 为空时列出全部
+	List(ctx context.Context, prefix string) ([]CheckpointInfo, error)
+	// Stats 返回检查点数量和总字节数，不包含已过期的检查点
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// CheckpointInfo 检查点的元数据，不包含检查点内容
+// Agent 和 SessionID 只有 SQLite 和 Redis 存储会记录；文件存储没有创建时间，CreatedAt 与 UpdatedAt 相同
+type CheckpointInfo struct {
+	ID        string
+	Agent     string
+	SessionID string
+	Size      int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Stats 存储的统计信息
+type Stats struct {
+	Count int
+	Bytes int64
+}
+
+// TooLargeError 检查点超过大小上限，写入被拒绝
+type TooLargeError struct {
+	Key   string
+	Size  int64
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("checkpoint %q is %d bytes, exceeds limit of %d bytes", e.Key, e.Size, e.Limit)
+}
+
+// Option 存储的可选配置，适用于本包的所有存储
+type Option func(*options)
+
+type options struct {
+	ttl      time.Duration
+	maxBytes int64
+	maxSize  int64
+}
+
+// WithTTL 设置过期时间，检查点超过 ttl 未更新即视为不存在，并在之后的读写中被删除
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// WithMaxBytes 设置所有检查点的总字节数上限，超出时淘汰最久未使用的检查点
+func WithMaxBytes(n int64) Option {
+	return func(o *options) { o.maxBytes = n }
+}
+
+// WithMaxCheckpointSize 设置单个检查点的字节数上限，超出时 Set 返回 *TooLargeError
+func WithMaxCheckpointSize(n int64) Option {
+	return func(o *options) { o.maxSize = n }
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// checkSize 单个检查点超过 MaxCheckpointSize 或 MaxBytes 时拒绝写入，后者即使淘汰所有检查点也放不下
+func (o options) checkSize(key string, size int) error {
+	n := int64(size)
+	if o.maxSize > 0 && n > o.maxSize {
+		return &TooLargeError{Key: key, Size: n, Limit: o.maxSize}
+	}
+	if o.maxBytes > 0 && n > o.maxBytes {
+		return &TooLargeError{Key: key, Size: n, Limit: o.maxBytes}
+	}
+	return nil
+}
+
+func (o options) expired(updatedAt, now time.Time) bool {
+	return o.ttl > 0 && now.Sub(updatedAt) > o.ttl
+}
+
+var (
+	_ Admin = (*inMemoryStore)(nil)
+	_ Admin = (*fileStore)(nil)
+	_ Admin = (*SQLiteStore)(nil)
+	_ Admin = (*RedisStore)(nil)
+)
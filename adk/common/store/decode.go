@@ -0,0 +1,309 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 以下类型与 adk.Runner 保存检查点时使用的未导出类型同名同字段，gob 按字段名解码，因此可以直接读取
+// 字段定义见 eino adk/interrupt.go 的 serialization 和 adk/runctx.go 的 runContext
+
+type ckptSerialization struct {
+	RunCtx              *ckptRunContext
+	Info                *adk.InterruptInfo
+	EnableStreaming     bool
+	InterruptID2Address map[string]adk.Address
+	InterruptID2State   map[string]ckptInterruptState
+}
+
+type ckptRunContext struct {
+	RootInput *adk.AgentInput
+	RunPath   []adk.RunStep
+	Session   *ckptRunSession
+}
+
+type ckptRunSession struct {
+	Values     map[string]any
+	Events     []*ckptEventWrapper
+	LaneEvents *ckptLaneEvents
+}
+
+type ckptLaneEvents struct {
+	Events []*ckptEventWrapper
+	Parent *ckptLaneEvents
+}
+
+// ckptGraph 对应 compose 的 checkpoint，ChatModelAgent 等基于图的 Agent 把它 gob 编码后作为中断状态保存
+type ckptGraph struct {
+	Channels          map[string]any
+	Inputs            map[string]any
+	State             any
+	SkipPreHandler    map[string]bool
+	RerunNodes        []string
+	SubGraphs         map[string]*ckptGraph
+	InterruptID2Addr  map[string]adk.Address
+	InterruptID2State map[string]ckptInterruptState
+}
+
+type ckptInterruptState struct {
+	State                any
+	LayerSpecificPayload any
+}
+
+// ckptEventWrapper 对应 agentEventWrapper，后者实现了 GobEncoder，这里也需要实现 GobDecoder
+type ckptEventWrapper struct {
+	AgentEvent *adk.AgentEvent
+	TS         int64
+	StreamErr  error
+}
+
+func (w *ckptEventWrapper) GobDecode(b []byte) error {
+	type plain ckptEventWrapper
+	return gob.NewDecoder(bytes.NewReader(b)).Decode((*plain)(w))
+}
+
+// Snapshot 检查点解码后的可读内容，可以直接序列化成 JSON
+type Snapshot struct {
+	RunPath         []string            `json:"run_path"`
+	EnableStreaming bool                `json:"enable_streaming"`
+	Interrupts      []SnapshotInterrupt `json:"interrupts"`
+	Input           []*schema.Message   `json:"input,omitempty"`
+	Messages        []SnapshotMessage   `json:"messages"`
+	SessionValues   map[string]any      `json:"session_values,omitempty"`
+}
+
+// SnapshotInterrupt 一个待恢复的中断点
+type SnapshotInterrupt struct {
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	IsRootCause bool   `json:"is_root_cause,omitempty"`
+	Info        any    `json:"info,omitempty"`
+	State       any    `json:"state,omitempty"`
+}
+
+// SnapshotGraph 图的检查点：各节点待处理的输入、图状态（例如 ChatModelAgent 的消息历史）和需要重新执行的节点
+type SnapshotGraph struct {
+	RerunNodes []string                  `json:"rerun_nodes,omitempty"`
+	Inputs     map[string]any            `json:"inputs,omitempty"`
+	State      any                       `json:"state,omitempty"`
+	Interrupts map[string]any            `json:"interrupts,omitempty"`
+	SubGraphs  map[string]*SnapshotGraph `json:"sub_graphs,omitempty"`
+}
+
+// SnapshotMessage 会话中记录的一条 Agent 输出
+type SnapshotMessage struct {
+	Agent   string          `json:"agent"`
+	RunPath string          `json:"run_path"`
+	TS      int64           `json:"ts,omitempty"`
+	Message *schema.Message `json:"message,omitempty"`
+	Action  any             `json:"action,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// DecodeCheckpoint 解码 adk.Runner 保存的检查点
+// 加密存储需要先用 SecureStore.Get 解密；compose.Graph 直接保存的检查点格式不同，不能用此函数解码
+func DecodeCheckpoint(data []byte) (*Snapshot, error) {
+	var s ckptSerialization
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode checkpoint: %w", err)
+	}
+
+	snap := &Snapshot{EnableStreaming: s.EnableStreaming}
+	if rc := s.RunCtx; rc != nil {
+		snap.RunPath = runPathStrings(rc.RunPath)
+		if rc.RootInput != nil {
+			snap.Input = rc.RootInput.Messages
+		}
+		if rc.Session != nil {
+			snap.SessionValues = jsonableMap(rc.Session.Values)
+			events := rc.Session.Events
+			for lane := rc.Session.LaneEvents; lane != nil; lane = lane.Parent {
+				events = append(events, lane.Events...)
+			}
+			// 并行分支的事件保存在各自的 lane 中，按时间戳合并成一条时间线
+			sort.SliceStable(events, func(i, j int) bool { return events[i].TS < events[j].TS })
+			for _, e := range events {
+				snap.Messages = append(snap.Messages, snapshotMessage(e))
+			}
+		}
+	}
+
+	contexts := map[string]*adk.InterruptCtx{}
+	if s.Info != nil {
+		for _, ic := range s.Info.InterruptContexts {
+			contexts[ic.ID] = ic
+		}
+	}
+	for id, addr := range s.InterruptID2Address {
+		it := SnapshotInterrupt{ID: id, Address: addr.String(), IsRootCause: isLeaf(addr, s.InterruptID2Address)}
+		if ic := contexts[id]; ic != nil {
+			it.Info = jsonable(ic.Info)
+		}
+		if st, ok := s.InterruptID2State[id]; ok {
+			it.State = stateJSON(st.State)
+		}
+		snap.Interrupts = append(snap.Interrupts, it)
+	}
+	sort.Slice(snap.Interrupts, func(i, j int) bool { return snap.Interrupts[i].Address < snap.Interrupts[j].Address })
+
+	// 运行路径在中断时可能只剩根节点，以最深的中断点所在的 Agent 为准
+	for _, it := range snap.Interrupts {
+		if !it.IsRootCause {
+			continue
+		}
+		if path := agentPath(s.InterruptID2Address[it.ID]); len(path) > len(snap.RunPath) {
+			snap.RunPath = path
+		}
+	}
+	return snap, nil
+}
+
+// isLeaf 中断地址不是其他中断地址的前缀时即为中断的根源
+func isLeaf(addr adk.Address, all map[string]adk.Address) bool {
+	for _, other := range all {
+		if len(other) > len(addr) && adk.Address(other[:len(addr)]).Equals(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func agentPath(addr adk.Address) []string {
+	var out []string
+	for _, seg := range addr {
+		if seg.Type == adk.AddressSegmentAgent {
+			out = append(out, seg.ID)
+		}
+	}
+	return out
+}
+
+// stateJSON 中断状态为 gob 编码的图检查点时解码展开，否则按 jsonable 处理
+func stateJSON(v any) any {
+	bs, ok := v.([]byte)
+	if !ok || json.Valid(bs) {
+		return jsonable(v)
+	}
+	var g ckptGraph
+	if err := gob.NewDecoder(bytes.NewReader(bs)).Decode(&g); err != nil {
+		return jsonable(v)
+	}
+	return snapshotGraph(&g)
+}
+
+func snapshotGraph(g *ckptGraph) *SnapshotGraph {
+	out := &SnapshotGraph{
+		RerunNodes: g.RerunNodes,
+		Inputs:     jsonableMap(g.Inputs),
+		State:      jsonable(g.State),
+	}
+	for id, addr := range g.InterruptID2Addr {
+		if st, ok := g.InterruptID2State[id]; ok && st.State != nil {
+			if out.Interrupts == nil {
+				out.Interrupts = map[string]any{}
+			}
+			out.Interrupts[addr.String()] = stateJSON(st.State)
+		}
+	}
+	for name, sub := range g.SubGraphs {
+		if out.SubGraphs == nil {
+			out.SubGraphs = map[string]*SnapshotGraph{}
+		}
+		out.SubGraphs[name] = snapshotGraph(sub)
+	}
+	return out
+}
+
+func snapshotMessage(w *ckptEventWrapper) SnapshotMessage {
+	m := SnapshotMessage{TS: w.TS}
+	if w.StreamErr != nil {
+		m.Error = w.StreamErr.Error()
+	}
+	e := w.AgentEvent
+	if e == nil {
+		return m
+	}
+	m.Agent = e.AgentName
+	m.RunPath = strings.Join(runPathStrings(e.RunPath), "/")
+	if e.Err != nil {
+		m.Error = e.Err.Error()
+	}
+	if e.Action != nil {
+		m.Action = jsonable(e.Action)
+	}
+	if e.Output != nil && e.Output.MessageOutput != nil {
+		mv := e.Output.MessageOutput
+		if mv.IsStreaming && mv.MessageStream != nil {
+			m.Message = concatStream(mv.MessageStream)
+		} else {
+			m.Message = mv.Message
+		}
+	}
+	return m
+}
+
+// concatStream 解码后的流只包含一个已拼接好的消息
+func concatStream(sr *schema.StreamReader[*schema.Message]) *schema.Message {
+	defer sr.Close()
+	var msgs []*schema.Message
+	for {
+		msg, err := sr.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	msg, err := schema.ConcatMessages(msgs)
+	if err != nil {
+		return msgs[0]
+	}
+	return msg
+}
+
+func runPathStrings(steps []adk.RunStep) []string {
+	out := make([]string, len(steps))
+	for i := range steps {
+		out[i] = steps[i].String()
+	}
+	return out
+}
+
+// jsonable 保证值可以序列化成 JSON：
+// compose 图的状态以 JSON 字节保存，直接展开；无法序列化的值（例如包含函数或流）转成字符串
+func jsonable(v any) any {
+	if v == nil {
+		return nil
+	}
+	if bs, ok := v.([]byte); ok && json.Valid(bs) {
+		return json.RawMessage(bs)
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
+
+func jsonableMap(m map[string]any) map[string]any {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = jsonable(v)
+	}
+	return out
+}
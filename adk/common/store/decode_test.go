@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+type askUserInput struct {
+	Question string `json:"question"`
+}
+
+// newCheckpoint 用 fake 模型运行 pipeline -> writer，writer 调用 ask_user 工具后中断，返回保存的检查点
+func newCheckpoint(t *testing.T) []byte {
+	t.Helper()
+	ctx := context.Background()
+	cm, err := cmodel.NewFakeChatModel(&cmodel.FakeScript{ChunkSize: 4, Agents: map[string][]*cmodel.FakeResponse{
+		"writer": {{
+			Content:   "先问一下用户",
+			ToolCalls: []*cmodel.FakeToolCall{{ID: "call_ask", Name: "ask_user", Arguments: `{"question": "用哪种语言？"}`}},
+		}},
+	}}, "writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	askUser, err := utils.InferTool("ask_user", "向用户提问", func(ctx context.Context, in *askUserInput) (string, error) {
+		return "", compose.StatefulInterrupt(ctx, in.Question, "waiting")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "writer",
+		Description: "写作",
+		Instruction: "你是写作助手",
+		Model:       cm,
+		ToolsConfig: adk.ToolsConfig{ToolsNodeConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{askUser}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := adk.NewSequentialAgent(ctx, &adk.SequentialAgentConfig{
+		Name:        "pipeline",
+		Description: "流水线",
+		SubAgents:   []adk.Agent{writer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewInMemoryStore()
+	runner := adk.NewRunner(ctx, adk.RunnerConfig{Agent: pipeline, EnableStreaming: true, CheckPointStore: s})
+	iter := runner.Run(ctx, []adk.Message{schema.UserMessage("写一段介绍")}, adk.WithCheckPointID("s1"))
+	interrupted := false
+	for {
+		event, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if event.Err != nil {
+			t.Fatal(event.Err)
+		}
+		if event.Output != nil && event.Output.MessageOutput != nil && event.Output.MessageOutput.IsStreaming {
+			event.Output.MessageOutput.MessageStream.Close()
+		}
+		interrupted = interrupted || event.Action != nil && event.Action.Interrupted != nil
+	}
+	if !interrupted {
+		t.Fatal("run did not interrupt")
+	}
+	data, ok, err := s.Get(ctx, "s1")
+	if err != nil || !ok {
+		t.Fatalf("checkpoint = %v, %v", ok, err)
+	}
+	return data
+}
+
+func TestDecodeCheckpoint(t *testing.T) {
+	snap, err := DecodeCheckpoint(newCheckpoint(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(snap.RunPath, "/"); got != "pipeline/writer" || !snap.EnableStreaming {
+		t.Fatalf("run path = %s, streaming = %v", got, snap.EnableStreaming)
+	}
+	if len(snap.Input) != 1 || snap.Input[0].Content != "写一段介绍" {
+		t.Fatalf("input = %+v", snap.Input)
+	}
+
+	// 中断链从 pipeline 一直到 ask_user 工具，只有最深的工具中断是根源
+	var roots []SnapshotInterrupt
+	var writer *SnapshotGraph
+	for _, it := range snap.Interrupts {
+		if it.IsRootCause {
+			roots = append(roots, it)
+		}
+		if it.Address == "agent:pipeline;agent:writer" {
+			writer, _ = it.State.(*SnapshotGraph)
+		}
+	}
+	if len(snap.Interrupts) < 3 || len(roots) != 1 {
+		t.Fatalf("interrupts = %+v, want a chain with one root cause", snap.Interrupts)
+	}
+	if want := "agent:pipeline;agent:writer;runnable:writer;node:node_1;node:ToolNode;tool:ask_user:call_ask"; roots[0].Address != want {
+		t.Fatalf("root cause = %s, want %s", roots[0].Address, want)
+	}
+
+	// writer 的中断状态是 gob 编码的图检查点，展开后可以看到工具的中断状态
+	if writer == nil || len(writer.RerunNodes) != 1 {
+		t.Fatalf("writer state = %+v, want a decoded graph checkpoint", writer)
+	}
+	if st := writer.Interrupts[roots[0].Address]; st != "waiting" {
+		t.Fatalf("tool interrupt state = %v, want waiting", st)
+	}
+
+	// 流式输出的消息在检查点中已拼接成一条
+	if len(snap.Messages) != 1 {
+		t.Fatalf("messages = %+v", snap.Messages)
+	}
+	m := snap.Messages[0]
+	if m.Agent != "writer" || m.RunPath != "pipeline/writer" || m.Message == nil {
+		t.Fatalf("message = %+v", m)
+	}
+	if m.Message.Content != "先问一下用户" || len(m.Message.ToolCalls) != 1 || m.Message.ToolCalls[0].Function.Name != "ask_user" {
+		t.Fatalf("message = %+v", m.Message)
+	}
+
+	// 解码结果可以直接序列化成 JSON
+	if _, err := json.Marshal(snap); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeCheckpointInvalid(t *testing.T) {
+	if _, err := DecodeCheckpoint([]byte("not a checkpoint")); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewStoreFromEnv 按环境变量选择 CheckPointStore：
+// 设置了 CHECKPOINT_SQLITE 时使用 SQLite 存储，设置了 CHECKPOINT_REDIS 时使用 Redis 存储，
+// 设置了 CHECKPOINT_DIR 时使用文件存储，否则使用内存存储
+//
+//	CHECKPOINT_REDIS=redis://:password@host:6379/0  Redis 地址，多个副本共享检查点
+//	CHECKPOINT_REDIS_PREFIX=eino:                   Redis key 前缀
+//	CHECKPOINT_CLAIM_TTL=10m                        恢复运行时占用检查点的时长
+//	CHECKPOINT_TTL=24h          检查点过期时间
+//	CHECKPOINT_MAX_BYTES=1e8    所有检查点的总字节数上限，超出时淘汰最久未使用的检查点
+//	CHECKPOINT_MAX_SIZE=1e7     单个检查点的字节数上限，超出时写入失败
+//
+// 设置了 CHECKPOINT_KEYS 时再用 NewSecureStore 加密：
+//
+//	CHECKPOINT_KEYS=1:<base64>,2:<base64>  密钥版本和 base64 编码的 AES 密钥，最后一个为当前密钥
+//	CHECKPOINT_COMPRESSION=zstd            加密前的压缩算法：none、gzip、zstd，默认 zstd
+//
+// 设置了 CHECKPOINT_HISTORY=true 时再用 NewHistoryStore 保留每个检查点的所有版本
+func NewStoreFromEnv() (compose.CheckPointStore, error) {
+	opts, err := optionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	var s compose.CheckPointStore
+	if path := os.Getenv("CHECKPOINT_SQLITE"); path != "" {
+		s, err = NewSQLiteStore(path, opts...)
+	} else if url := os.Getenv("CHECKPOINT_REDIS"); url != "" {
+		s, err = redisStoreFromEnv(url, opts)
+	} else if dir := os.Getenv("CHECKPOINT_DIR"); dir != "" {
+		s, err = NewFileStore(dir, opts...)
+	} else {
+		s = NewInMemoryStore(opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s, err = WrapFromEnv(s); err != nil {
+		return nil, err
+	}
+	if history, _ := strconv.ParseBool(os.Getenv("CHECKPOINT_HISTORY")); history {
+		return NewHistoryStore(s), nil
+	}
+	return s, nil
+}
+
+// WrapFromEnv 设置了 CHECKPOINT_KEYS 时用 NewSecureStore 包装 s，否则原样返回
+func WrapFromEnv(s compose.CheckPointStore) (compose.CheckPointStore, error) {
+	cfg, err := secureConfigFromEnv()
+	if err != nil || cfg == nil {
+		return s, err
+	}
+	return NewSecureStore(s, cfg)
+}
+
+func redisStoreFromEnv(url string, opts []Option) (*RedisStore, error) {
+	redisOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse CHECKPOINT_REDIS: %w", err)
+	}
+	cfg := RedisConfig{Client: redis.NewClient(redisOpts), Prefix: os.Getenv("CHECKPOINT_REDIS_PREFIX")}
+	if v := os.Getenv("CHECKPOINT_CLAIM_TTL"); v != "" {
+		if cfg.ClaimTTL, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_CLAIM_TTL: %w", err)
+		}
+	}
+	return NewRedisStore(cfg, opts...)
+}
+
+func secureConfigFromEnv() (*SecureConfig, error) {
+	v := os.Getenv("CHECKPOINT_KEYS")
+	if v == "" {
+		return nil, nil
+	}
+	cfg := &SecureConfig{Keys: make(map[uint32][]byte)}
+	for _, item := range strings.Split(v, ",") {
+		ver, b64, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return nil, fmt.Errorf("parse CHECKPOINT_KEYS: want <version>:<base64 key>, got %q", item)
+		}
+		n, err := strconv.ParseUint(ver, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_KEYS version %q: %w", ver, err)
+		}
+		key, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_KEYS version %d: %w", n, err)
+		}
+		cfg.Keys[uint32(n)] = key
+		cfg.CurrentKey = uint32(n)
+	}
+
+	switch c := strings.ToLower(os.Getenv("CHECKPOINT_COMPRESSION")); c {
+	case "", "zstd":
+		cfg.Compression = CompressionZstd
+	case "gzip":
+		cfg.Compression = CompressionGzip
+	case "none":
+		cfg.Compression = CompressionNone
+	default:
+		return nil, fmt.Errorf("unknown CHECKPOINT_COMPRESSION %q", c)
+	}
+	return cfg, nil
+}
+
+func optionsFromEnv() ([]Option, error) {
+	var opts []Option
+	if v := os.Getenv("CHECKPOINT_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_TTL: %w", err)
+		}
+		opts = append(opts, WithTTL(ttl))
+	}
+	if v := os.Getenv("CHECKPOINT_MAX_BYTES"); v != "" {
+		n, err := parseBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_MAX_BYTES: %w", err)
+		}
+		opts = append(opts, WithMaxBytes(n))
+	}
+	if v := os.Getenv("CHECKPOINT_MAX_SIZE"); v != "" {
+		n, err := parseBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse CHECKPOINT_MAX_SIZE: %w", err)
+		}
+		opts = append(opts, WithMaxCheckpointSize(n))
+	}
+	return opts, nil
+}
+
+// parseBytes 解析字节数，允许 1e8 这样的写法
+func parseBytes(v string) (int64, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(f), nil
+}
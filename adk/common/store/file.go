@@ -0,0 +1,311 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// fileExt 检查点文件的扩展名
+const fileExt = ".ckpt"
+
+// NewFileStore 创建一个基于文件的 CheckPointStore，每个检查点保存为 dir 下的一个文件
+// 进程退出后检查点仍然保留，重启后可以用同一个 checkPointID 恢复中断的运行
+// 返回值同时实现了 Admin；过期按文件修改时间计算，容量统计只包含本进程启动时已有的和之后写入的文件
+func NewFileStore(dir string, opts ...Option) (compose.CheckPointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	f := &fileStore{dir: dir, opts: newOptions(opts), used: make(map[string]time.Time)}
+	if f.opts.maxBytes > 0 {
+		files, _, err := f.scan()
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range files {
+			f.bytes += fi.Size
+		}
+	}
+	return f, nil
+}
+
+// fileStore 文件实现的 CheckPointStore
+// 写入时先写临时文件再 rename，读取方要么看到旧文件要么看到新文件
+// mu 只保护最近使用时间和总字节数，文件读写本身不需要加锁
+type fileStore struct {
+	dir  string
+	opts options
+
+	mu    sync.Mutex
+	used  map[string]time.Time // key -> 本进程内最近一次读写的时间，用于 LRU 淘汰
+	bytes int64                // 设置了 MaxBytes 时维护的总字节数
+}
+
+// Set 原子地写入检查点：写临时文件 -> fsync -> rename 覆盖
+func (f *fileStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := f.opts.checkSize(key, len(value)); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp checkpoint: %w", err)
+	}
+	// rename 成功后临时文件已不存在，Remove 只在失败时起作用
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	// 落盘后再 rename，避免断电后留下内容为空的新文件
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close checkpoint: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var oldSize int64
+	if fi, err := os.Stat(f.path(key)); err == nil {
+		oldSize = fi.Size()
+	}
+	if err := os.Rename(tmp.Name(), f.path(key)); err != nil {
+		return fmt.Errorf("rename checkpoint: %w", err)
+	}
+	f.used[key] = time.Now()
+	f.bytes += int64(len(value)) - oldSize
+	if f.opts.maxBytes > 0 && f.bytes > f.opts.maxBytes {
+		return f.evict(key)
+	}
+	return nil
+}
+
+// Get 读取检查点，不存在或已过期时返回 false
+func (f *fileStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := f.path(key)
+	if f.opts.ttl > 0 {
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("stat checkpoint: %w", err)
+		}
+		if f.opts.expired(fi.ModTime(), time.Now()) {
+			return nil, false, f.remove(key, fi.Size())
+		}
+	}
+	v, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read checkpoint: %w", err)
+	}
+	f.mu.Lock()
+	f.used[key] = time.Now()
+	f.mu.Unlock()
+	return v, true, nil
+}
+
+// Delete 删除检查点，返回是否存在
+func (f *fileStore) Delete(ctx context.Context, key string) (bool, error) {
+	fi, err := os.Stat(f.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat checkpoint: %w", err)
+	}
+	if err := f.remove(key, fi.Size()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// List 列出 key 以 prefix 开头的检查点，按更新时间倒序
+func (f *fileStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	files, err := f.live()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CheckpointInfo, 0, len(files))
+	for _, fi := range files {
+		if strings.HasPrefix(fi.ID, prefix) {
+			out = append(out, fi)
+		}
+	}
+	sortInfos(out)
+	return out, nil
+}
+
+// Stats 返回检查点数量和总字节数
+func (f *fileStore) Stats(ctx context.Context) (Stats, error) {
+	files, err := f.live()
+	if err != nil {
+		return Stats{}, err
+	}
+	st := Stats{Count: len(files)}
+	for _, fi := range files {
+		st.Bytes += fi.Size
+	}
+	return st, nil
+}
+
+// live 返回未过期的检查点，并删除已过期的文件
+func (f *fileStore) live() ([]CheckpointInfo, error) {
+	files, expired, err := f.scan()
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fi := range expired {
+		if err := f.removeLocked(fi.ID, fi.Size); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// scan 读取目录中的所有检查点，分成未过期和已过期两部分
+func (f *fileStore) scan() (live, expired []CheckpointInfo, err error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read checkpoint dir: %w", err)
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+		key, ok := unescapeKey(strings.TrimSuffix(name, fileExt))
+		if !ok {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			// 扫描期间被其他调用删除
+			continue
+		}
+		info := CheckpointInfo{ID: key, Size: fi.Size(), CreatedAt: fi.ModTime(), UpdatedAt: fi.ModTime()}
+		if f.opts.expired(fi.ModTime(), now) {
+			expired = append(expired, info)
+		} else {
+			live = append(live, info)
+		}
+	}
+	return live, expired, nil
+}
+
+// evict 按最近使用时间从旧到新删除检查点，直到总字节数不超过 MaxBytes，调用方需持有锁
+// 本进程没有读写过的文件以修改时间作为最近使用时间；keep 为刚写入的检查点，不会被淘汰
+func (f *fileStore) evict(keep string) error {
+	files, expired, err := f.scan()
+	if err != nil {
+		return err
+	}
+	for _, fi := range expired {
+		if err := f.removeLocked(fi.ID, 0); err != nil {
+			return err
+		}
+	}
+	lastUsed := func(fi CheckpointInfo) time.Time {
+		if t, ok := f.used[fi.ID]; ok {
+			return t
+		}
+		return fi.UpdatedAt
+	}
+	sort.Slice(files, func(a, b int) bool { return lastUsed(files[a]).Before(lastUsed(files[b])) })
+
+	// 以扫描结果为准重新统计，纠正其他进程写入造成的偏差
+	f.bytes = 0
+	for _, fi := range files {
+		f.bytes += fi.Size
+	}
+	for _, fi := range files {
+		if f.bytes <= f.opts.maxBytes {
+			break
+		}
+		if fi.ID == keep {
+			continue
+		}
+		if err := f.removeLocked(fi.ID, fi.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove 删除检查点文件并更新统计
+func (f *fileStore) remove(key string, size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.removeLocked(key, size)
+}
+
+// removeLocked 同 remove，调用方需持有锁
+func (f *fileStore) removeLocked(key string, size int64) error {
+	err := os.Remove(f.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("remove checkpoint: %w", err)
+	}
+	delete(f.used, key)
+	f.bytes -= size
+	return nil
+}
+
+func (f *fileStore) path(key string) string {
+	return filepath.Join(f.dir, escapeKey(key)+fileExt)
+}
+
+// escapeKey 把 key 转成安全的文件名：字母、数字、'-'、'_' 原样保留，其他字节写成 %XX
+// 不同的 key 对应不同的文件名，也不会出现 "."、".." 或包含路径分隔符的文件名
+func escapeKey(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// unescapeKey escapeKey 的逆操作，name 不是 escapeKey 生成的文件名时返回 false
+func unescapeKey(name string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", false
+		}
+		b, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteByte(byte(b))
+		i += 2
+	}
+	return sb.String(), true
+}
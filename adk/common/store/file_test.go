@@ -0,0 +1,183 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newFileStore 在临时目录中创建文件存储，返回存储和目录
+func newFileStore(t *testing.T, opts ...Option) (*fileStore, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	s, err := NewFileStore(dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*fileStore), dir
+}
+
+func TestFileStoreSetGet(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newFileStore(t)
+
+	if _, ok, err := s.Get(ctx, "s1"); err != nil || ok {
+		t.Fatalf("get missing = %v, %v", ok, err)
+	}
+	for _, v := range []string{"v1", "v2"} {
+		if err := s.Set(ctx, "s1", []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, ok, err := s.Get(ctx, "s1")
+	if err != nil || !ok || string(v) != "v2" {
+		t.Fatalf("get = %q, %v, %v; want v2", v, ok, err)
+	}
+
+	// 目录中只有检查点文件，没有留下临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "s1"+fileExt {
+		t.Fatalf("dir entries = %v, want only s1%s", entries, fileExt)
+	}
+
+	// 重新打开同一个目录仍能读到检查点
+	reopened, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := reopened.Get(ctx, "s1"); err != nil || !ok || string(v) != "v2" {
+		t.Fatalf("get after reopen = %q, %v, %v", v, ok, err)
+	}
+
+	if ok, err := s.Delete(ctx, "s1"); err != nil || !ok {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if ok, err := s.Delete(ctx, "s1"); err != nil || ok {
+		t.Fatalf("delete again = %v, %v", ok, err)
+	}
+}
+
+// TestFileStoreAtomicWrite 并发读取时只会看到完整的旧内容或新内容
+func TestFileStoreAtomicWrite(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newFileStore(t)
+	a, b := bytes.Repeat([]byte("a"), 1<<20), bytes.Repeat([]byte("b"), 1<<20)
+	if err := s.Set(ctx, "s1", a); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 20 {
+			v := a
+			if i%2 == 0 {
+				v = b
+			}
+			if err := s.Set(ctx, "s1", v); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for range 50 {
+		v, ok, err := s.Get(ctx, "s1")
+		if err != nil || !ok {
+			t.Fatalf("get = %v, %v", ok, err)
+		}
+		if !bytes.Equal(v, a) && !bytes.Equal(v, b) {
+			t.Fatalf("read a partially written checkpoint of %d bytes", len(v))
+		}
+	}
+	wg.Wait()
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestFileStoreKeys(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newFileStore(t)
+	keys := []string{"../escape", "a/b", "a%2F", "会话-1", ".", "a_b-c"}
+	for _, key := range keys {
+		if err := s.Set(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("set %q: %v", key, err)
+		}
+	}
+	// 所有文件都在目录内，且各不相同
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(keys) {
+		t.Fatalf("dir has %d entries, want %d", len(entries), len(keys))
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape"+fileExt)); !os.IsNotExist(err) {
+		t.Fatalf("key escaped the checkpoint dir: %v", err)
+	}
+	for _, key := range keys {
+		if v, ok, err := s.Get(ctx, key); err != nil || !ok || string(v) != key {
+			t.Errorf("get %q = %q, %v, %v", key, v, ok, err)
+		}
+	}
+
+	for prefix, want := range map[string][]string{
+		"a":  {"a%2F", "a/b", "a_b-c"},
+		"a/": {"a/b"},
+		"会话": {"会话-1"},
+		"x":  nil,
+	} {
+		infos, err := s.List(ctx, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, info := range infos {
+			got[info.ID] = true
+		}
+		if len(got) != len(want) {
+			t.Errorf("List(%q) = %+v, want %v", prefix, infos, want)
+			continue
+		}
+		for _, id := range want {
+			if !got[id] {
+				t.Errorf("List(%q) = %+v, want %v", prefix, infos, want)
+				break
+			}
+		}
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"abc-_XYZ09": "abc-_XYZ09",
+		"a/b":        "a%2Fb",
+		"..":         "%2E%2E",
+		"%":          "%25",
+		"会":          "%E4%BC%9A",
+	} {
+		if got := escapeKey(key); got != want {
+			t.Errorf("escapeKey(%q) = %q, want %q", key, got, want)
+		}
+		if got, ok := unescapeKey(want); !ok || got != key {
+			t.Errorf("unescapeKey(%q) = %q, %v; want %q", want, got, ok, key)
+		}
+	}
+	for _, name := range []string{"%", "%2", "%ZZ"} {
+		if _, ok := unescapeKey(name); ok {
+			t.Errorf("unescapeKey(%q) should fail", name)
+		}
+	}
+}
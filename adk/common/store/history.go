@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// historySep 版本号与 key 之间的分隔符，key 本身不应包含该字符
+const historySep = "@"
+
+// Version 检查点历史中的一个版本，N 从 1 开始递增
+type Version struct {
+	N         int
+	Size      int64
+	CreatedAt time.Time // 被包装的存储没有实现 Admin 时为 0 值
+}
+
+// HistoryStore 保留检查点所有版本的 CheckPointStore 装饰器
+//
+// 每次 Set 除覆盖最新值外，还把值另存为一个新版本，在被包装的存储中的布局为：
+//
+//	key               最新版本，Runner.Resume 读取的就是它
+//	key@000001 ...    各个版本
+//	key@head          最新的版本号
+//
+// 被包装的存储设置了 TTL 或容量上限时，旧版本可能被清理
+type HistoryStore struct {
+	inner compose.CheckPointStore
+	mu    sync.Mutex // 保证同一个 HistoryStore 内版本号分配不冲突
+}
+
+// NewHistoryStore 用历史模式包装任意 CheckPointStore
+func NewHistoryStore(inner compose.CheckPointStore) *HistoryStore {
+	return &HistoryStore{inner: inner}
+}
+
+// Set 写入最新值并追加一个版本
+func (h *HistoryStore) Set(ctx context.Context, key string, value []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	head, err := h.head(ctx, key)
+	if err != nil {
+		return err
+	}
+	return h.append(ctx, key, head+1, value)
+}
+
+// Get 读取最新版本
+func (h *HistoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return h.inner.Get(ctx, key)
+}
+
+// GetVersion 读取指定版本，各个版本写入后不再改变，以只读方式读取，不占用 RedisStore 中的检查点
+func (h *HistoryStore) GetVersion(ctx context.Context, key string, n int) ([]byte, bool, error) {
+	return h.inner.Get(WithReadOnly(ctx), versionKey(key, n))
+}
+
+// Versions 按版本号从小到大列出 key 仍然存在的版本
+func (h *HistoryStore) Versions(ctx context.Context, key string) ([]Version, error) {
+	if admin, ok := h.inner.(Admin); ok {
+		infos, err := admin.List(ctx, key+historySep)
+		if err != nil {
+			return nil, err
+		}
+		var out []Version
+		for _, info := range infos {
+			n, err := strconv.Atoi(strings.TrimPrefix(info.ID, key+historySep))
+			if err != nil {
+				// key@head 或者其他 key 的版本（例如 key 为 "a" 时的 "a@b@000001"）
+				continue
+			}
+			out = append(out, Version{N: n, Size: info.Size, CreatedAt: info.UpdatedAt})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].N < out[j].N })
+		return out, nil
+	}
+
+	head, err := h.head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var out []Version
+	for n := 1; n <= head; n++ {
+		v, ok, err := h.GetVersion(ctx, key, n)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, Version{N: n, Size: int64(len(v))})
+		}
+	}
+	return out, nil
+}
+
+// Fork 以 key 的第 n 个版本为起点创建新的检查点 newKey，之后用 newKey 调用 Runner.Resume 即从该版本继续运行
+// 版本 1..n 一并复制，新会话同样可以再回到更早的版本；原会话不受影响
+//
+// newKey@head 最后写入，是新会话存在的标志：中途失败时新会话视为不存在，
+// 被包装的存储实现了 Admin 时还会删除已经写入的 key
+func (h *HistoryStore) Fork(ctx context.Context, key string, n int, newKey string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if head, err := h.head(ctx, newKey); err != nil {
+		return err
+	} else if head > 0 {
+		return fmt.Errorf("checkpoint %q already exists", newKey)
+	}
+	latest, ok, err := h.GetVersion(ctx, key, n)
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("checkpoint %q has no version %d", key, n)
+	}
+
+	var written []string
+	set := func(k string, v []byte) error {
+		written = append(written, k)
+		return h.inner.Set(ctx, k, v)
+	}
+	copyAll := func() error {
+		for i := 1; i < n; i++ {
+			v, ok, err := h.GetVersion(ctx, key, i)
+			if err != nil {
+				return err
+			}
+			// 被清理掉的早期版本跳过，只要第 n 个版本存在即可恢复
+			if !ok {
+				continue
+			}
+			if err := set(versionKey(newKey, i), v); err != nil {
+				return err
+			}
+		}
+		if err := set(versionKey(newKey, n), latest); err != nil {
+			return err
+		}
+		if err := set(newKey, latest); err != nil {
+			return err
+		}
+		return set(newKey+historySep+"head", []byte(strconv.Itoa(n)))
+	}
+	if err := copyAll(); err != nil {
+		if admin, ok := h.inner.(Admin); ok {
+			for _, k := range written {
+				_, _ = admin.Delete(ctx, k)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete 删除 key 的最新值和所有版本
+func (h *HistoryStore) Delete(ctx context.Context, key string) (bool, error) {
+	admin, ok := h.inner.(Admin)
+	if !ok {
+		return false, ErrAdminUnsupported
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	versions, err := h.Versions(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	existed, err := admin.Delete(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		if _, err := admin.Delete(ctx, versionKey(key, v.N)); err != nil {
+			return existed, err
+		}
+	}
+	if _, err := admin.Delete(ctx, key+historySep+"head"); err != nil {
+		return existed, err
+	}
+	return existed, nil
+}
+
+// List 列出 key 以 prefix 开头的检查点，只包含各个 key 的最新值，key@head 和各个版本不会列出，版本用 Versions 查看
+func (h *HistoryStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	admin, ok := h.inner.(Admin)
+	if !ok {
+		return nil, ErrAdminUnsupported
+	}
+	infos, err := admin.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := infos[:0]
+	for _, info := range infos {
+		if !strings.Contains(info.ID, historySep) {
+			out = append(out, info)
+		}
+	}
+	return out, nil
+}
+
+// Release 释放被包装的存储中 key 的占用，见 RedisStore.Release
+func (h *HistoryStore) Release(ctx context.Context, key string) error {
+	return Release(ctx, h.inner, key)
+}
+
+// Stats 转发给被包装的存储
+func (h *HistoryStore) Stats(ctx context.Context) (Stats, error) {
+	admin, ok := h.inner.(Admin)
+	if !ok {
+		return Stats{}, ErrAdminUnsupported
+	}
+	return admin.Stats(ctx)
+}
+
+// append 把 value 设为 key 的最新值，并写入为第 n 个版本，调用方需持有锁
+//
+// 最新值最先写入：RedisStore 只在写最新值时检查占用，失去占用的副本在这一步就返回 ErrConflict，
+// 不会留下多余的版本或推进 head；之后再写版本和 head，中途失败时 head 不会指向不存在的版本，
+// 下一次 Set 会重新写入第 n 个版本
+func (h *HistoryStore) append(ctx context.Context, key string, n int, value []byte) error {
+	if err := h.inner.Set(ctx, key, value); err != nil {
+		return err
+	}
+	if err := h.inner.Set(ctx, versionKey(key, n), value); err != nil {
+		return err
+	}
+	return h.inner.Set(ctx, key+historySep+"head", []byte(strconv.Itoa(n)))
+}
+
+// head 返回 key 最新的版本号，没有历史时返回 0
+// 以只读方式读取，不占用 RedisStore 中的 key@head，多个副本可以同时读取
+func (h *HistoryStore) head(ctx context.Context, key string) (int, error) {
+	v, ok, err := h.inner.Get(WithReadOnly(ctx), key+historySep+"head")
+	if err != nil || !ok {
+		return 0, err
+	}
+	n, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, errors.New("corrupt checkpoint history head for " + strconv.Quote(key))
+	}
+	return n, nil
+}
+
+// versionKey 版本号补零到 6 位，按字符串排序即按版本排序
+func versionKey(key string, n int) string {
+	return fmt.Sprintf("%s%s%06d", key, historySep, n)
+}
+
+var _ Admin = (*HistoryStore)(nil)
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// failingStore 写入 failKey 时返回错误的内存存储
+type failingStore struct {
+	*inMemoryStore
+	failKey string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f *failingStore) Set(ctx context.Context, key string, value []byte) error {
+	if key == f.failKey {
+		return errInjected
+	}
+	return f.inMemoryStore.Set(ctx, key, value)
+}
+
+// mustSetAll 依次写入 values，每次写入产生一个版本
+func mustSetAll(t *testing.T, h *HistoryStore, key string, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		if err := h.Set(context.Background(), key, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// versionNumbers 返回 key 现存的版本号
+func versionNumbers(t *testing.T, h *HistoryStore, key string) []int {
+	t.Helper()
+	versions, err := h.Versions(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]int, len(versions))
+	for i, v := range versions {
+		out[i] = v.N
+	}
+	return out
+}
+
+func TestHistoryStoreVersions(t *testing.T) {
+	ctx := context.Background()
+	for name, inner := range map[string]compose.CheckPointStore{
+		"admin": NewInMemoryStore(),
+		// 被包装的存储没有实现 Admin 时按 head 逐个读取版本
+		"plain": struct{ compose.CheckPointStore }{NewInMemoryStore()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := NewHistoryStore(inner)
+			mustSetAll(t, h, "s1", "v1", "v22", "v333")
+			// 其他 key 的版本不会混进来，即使 key 以 s1@ 开头
+			mustSetAll(t, h, "s1@x", "other")
+
+			if v, ok, err := h.Get(ctx, "s1"); err != nil || !ok || string(v) != "v333" {
+				t.Fatalf("get latest = %q, %v, %v", v, ok, err)
+			}
+			versions, err := h.Versions(ctx, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != 3 {
+				t.Fatalf("versions = %+v, want 3", versions)
+			}
+			for i, v := range versions {
+				if v.N != i+1 || v.Size != int64(i+2) {
+					t.Errorf("version %d = %+v", i, v)
+				}
+				bs, ok, err := h.GetVersion(ctx, "s1", v.N)
+				if err != nil || !ok || len(bs) != int(v.Size) {
+					t.Errorf("get version %d = %q, %v, %v", v.N, bs, ok, err)
+				}
+			}
+			if _, ok, err := h.GetVersion(ctx, "s1", 4); err != nil || ok {
+				t.Fatalf("get missing version = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestHistoryStoreAdmin(t *testing.T) {
+	ctx := context.Background()
+	h := NewHistoryStore(NewInMemoryStore())
+	mustSetAll(t, h, "s1", "v1", "v2")
+	mustSetAll(t, h, "s2", "v1")
+
+	// List 只列出最新值
+	if got := keys(t, h, ""); got != "s2,s1" {
+		t.Fatalf("List = %s", got)
+	}
+	if ok, err := h.Delete(ctx, "s1"); err != nil || !ok {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	// 最新值、各个版本和 head 一并删除，之后重新写入从第 1 个版本开始
+	if n := versionNumbers(t, h, "s1"); len(n) != 0 {
+		t.Fatalf("versions after delete = %v", n)
+	}
+	mustSetAll(t, h, "s1", "again")
+	if n := versionNumbers(t, h, "s1"); len(n) != 1 || n[0] != 1 {
+		t.Fatalf("versions after rewrite = %v, want [1]", n)
+	}
+
+	plain := NewHistoryStore(struct{ compose.CheckPointStore }{NewInMemoryStore()})
+	if _, err := plain.Delete(ctx, "s1"); !errors.Is(err, ErrAdminUnsupported) {
+		t.Fatalf("delete without Admin: err = %v", err)
+	}
+}
+
+func TestHistoryStoreFork(t *testing.T) {
+	ctx := context.Background()
+	h := NewHistoryStore(NewInMemoryStore())
+	mustSetAll(t, h, "s1", "v1", "v2", "v3")
+
+	if err := h.Fork(ctx, "s1", 2, "fork"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := h.Get(ctx, "fork"); err != nil || !ok || string(v) != "v2" {
+		t.Fatalf("fork latest = %q, %v, %v; want v2", v, ok, err)
+	}
+	if n := versionNumbers(t, h, "fork"); len(n) != 2 || n[0] != 1 || n[1] != 2 {
+		t.Fatalf("fork versions = %v, want [1 2]", n)
+	}
+	// 新会话继续写入时从第 3 个版本开始，原会话不受影响
+	mustSetAll(t, h, "fork", "f3")
+	if v, _, _ := h.GetVersion(ctx, "fork", 3); string(v) != "f3" {
+		t.Fatalf("fork version 3 = %q", v)
+	}
+	if v, _, _ := h.Get(ctx, "s1"); string(v) != "v3" {
+		t.Fatalf("original latest = %q, want v3", v)
+	}
+
+	for _, tt := range []struct {
+		n      int
+		newKey string
+		want   string
+	}{
+		{2, "fork", "already exists"},
+		{9, "fork2", "has no version 9"},
+	} {
+		if err := h.Fork(ctx, "s1", tt.n, tt.newKey); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Fork(%d, %q) = %v, want %q", tt.n, tt.newKey, err, tt.want)
+		}
+	}
+}
+
+// TestHistoryStoreForkFailure 分叉中途失败时不留下新会话的任何 key
+func TestHistoryStoreForkFailure(t *testing.T) {
+	ctx := context.Background()
+	for _, failKey := range []string{"fork@000001", "fork@000002", "fork", "fork@head"} {
+		t.Run(failKey, func(t *testing.T) {
+			inner := &failingStore{inMemoryStore: NewInMemoryStore().(*inMemoryStore), failKey: failKey}
+			h := NewHistoryStore(inner)
+			mustSetAll(t, h, "s1", "v1", "v2")
+
+			if err := h.Fork(ctx, "s1", 2, "fork"); !errors.Is(err, errInjected) {
+				t.Fatalf("fork: err = %v, want the injected failure", err)
+			}
+			if got := keys(t, inner, "fork"); got != "" {
+				t.Fatalf("keys left after failed fork: %s", got)
+			}
+			// 失败后可以用同一个 key 重新分叉
+			inner.failKey = ""
+			if err := h.Fork(ctx, "s1", 2, "fork"); err != nil {
+				t.Fatalf("retry fork: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrConflict 检查点正在被其他副本恢复，或者在本次读取之后已被其他副本改写
+var ErrConflict = errors.New("checkpoint is held by another runner")
+
+// defaultClaimTTL 恢复运行时占用检查点的默认时长，持有者崩溃后最多等待这么久其他副本即可接手
+const defaultClaimTTL = 10 * time.Minute
+
+// redisGetScript 读取检查点并占用它：占用标记不存在或者就是自己时成功，否则返回 -1
+// KEYS: 检查点, 占用标记  ARGV: 占用标识（为空时只读）, 占用时长（毫秒）
+var redisGetScript = redis.NewScript(`
+local v = redis.call('HMGET', KEYS[1], 'v', 'rev')
+if not v[1] then
+	return false
+end
+if ARGV[1] ~= '' then
+	if not redis.call('SET', KEYS[2], ARGV[1], 'NX', 'PX', ARGV[2]) then
+		if redis.call('GET', KEYS[2]) ~= ARGV[1] then
+			return -1
+		end
+		redis.call('PEXPIRE', KEYS[2], ARGV[2])
+	end
+end
+return v
+`)
+
+// redisSetScript 写入检查点并释放占用，返回新的版本号；被其他副本抢先时返回 -1
+// 仍然持有占用标记，或者占用已过期但版本号没有变化（期间没有其他副本写入）时才允许写入
+// KEYS: 检查点, 占用标记  ARGV: 占用标识（为空时无条件写入）, 读取时的版本号, 内容, 当前时间（毫秒）, TTL（毫秒）, Agent, 会话 ID
+var redisSetScript = redis.NewScript(`
+local owner = false
+if ARGV[1] ~= '' then
+	owner = redis.call('GET', KEYS[2])
+	if owner ~= ARGV[1] and (owner or redis.call('HGET', KEYS[1], 'rev') ~= ARGV[2]) then
+		return -1
+	end
+end
+local rev = redis.call('HINCRBY', KEYS[1], 'rev', 1)
+redis.call('HSET', KEYS[1], 'v', ARGV[3], 'updated', ARGV[4])
+redis.call('HSETNX', KEYS[1], 'created', ARGV[4])
+if ARGV[6] ~= '' or ARGV[7] ~= '' then
+	redis.call('HSET', KEYS[1], 'agent', ARGV[6], 'session', ARGV[7])
+end
+if tonumber(ARGV[5]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+else
+	redis.call('PERSIST', KEYS[1])
+end
+if owner == ARGV[1] then
+	redis.call('DEL', KEYS[2])
+end
+return rev
+`)
+
+// redisReleaseScript 占用标记仍属于自己时删除
+var redisReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisConfig Redis 存储的配置
+type RedisConfig struct {
+	// Client 单机、哨兵或集群客户端均可
+	Client redis.UniversalClient
+	// Prefix 所有 key 的前缀，多个应用共用一个 Redis 时用于隔离，默认 "eino:"
+	Prefix string
+	// ClaimTTL 恢复运行时占用检查点的时长，应大于一次恢复运行的最长耗时，默认 10 分钟
+	ClaimTTL time.Duration
+}
+
+// redisClaim 本副本通过 Get 占用的检查点
+type redisClaim struct {
+	token string
+	rev   string
+}
+
+// RedisStore Redis 实现的 CheckPointStore，多个副本共享同一份检查点，会话不再绑定在某个实例上
+//
+// 每个检查点是一个 hash，记录内容、版本号、创建/更新时间、Agent 名称和会话 ID：
+//
+//	<prefix>ckpt:{<key>}    检查点
+//	<prefix>claim:{<key>}   占用标记，值为占用者的随机标识，带过期时间
+//
+// Runner.Resume 先 Get 再在新的中断处 Set，Get 时占用检查点，同一时刻只有一个副本能恢复同一次运行，
+// 其他副本的 Get 返回 ErrConflict；Set 时检查占用仍属于自己（或占用已过期但检查点未被改写）再写入，
+// 否则同样返回 ErrConflict，不会覆盖其他副本的结果。
+// 恢复后正常结束的运行不会再调用 Set，占用保留到 ClaimTTL 过期，期间不能重复恢复；需要提前释放时调用 Release
+//
+// key 使用 {} 作为集群的 hash tag，检查点和占用标记落在同一个槽上；TTL 由 Redis 负责过期，不支持 WithMaxBytes，
+// 总容量请通过服务端的 maxmemory 策略控制
+type RedisStore struct {
+	client   redis.UniversalClient
+	prefix   string
+	claimTTL time.Duration
+	opts     options
+
+	mu     sync.Mutex
+	claims map[string]redisClaim
+}
+
+// NewRedisStore 创建 Redis 存储
+func NewRedisStore(cfg RedisConfig, opts ...Option) (*RedisStore, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("redis checkpoint store requires a client")
+	}
+	o := newOptions(opts)
+	if o.maxBytes > 0 {
+		return nil, errors.New("redis checkpoint store does not support max bytes, use the server's maxmemory policy instead")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "eino:"
+	}
+	if cfg.ClaimTTL <= 0 {
+		cfg.ClaimTTL = defaultClaimTTL
+	}
+	return &RedisStore{
+		client:   cfg.Client,
+		prefix:   cfg.Prefix,
+		claimTTL: cfg.ClaimTTL,
+		opts:     o,
+		claims:   make(map[string]redisClaim),
+	}, nil
+}
+
+type readOnlyKey struct{}
+
+// WithReadOnly 标记 ctx 中的 Get 只读取检查点、不占用它，用于查看检查点等不会恢复运行的场景
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+func readOnly(ctx context.Context) bool {
+	v, _ := ctx.Value(readOnlyKey{}).(bool)
+	return v
+}
+
+// Get 读取并占用检查点，已被其他副本占用时返回包装了 ErrConflict 的错误
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	token := ""
+	if !readOnly(ctx) {
+		s.mu.Lock()
+		token = s.claims[key].token
+		s.mu.Unlock()
+		if token == "" {
+			token = uuid.NewString()
+		}
+	}
+
+	res, err := redisGetScript.Run(ctx, s.client, []string{s.dataKey(key), s.claimKey(key)}, token, s.claimTTL.Milliseconds()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read checkpoint %q: %w", key, err)
+	}
+	if n, ok := res.(int64); ok && n < 0 {
+		return nil, false, fmt.Errorf("checkpoint %q: %w", key, ErrConflict)
+	}
+	fields, ok := res.([]any)
+	if !ok || len(fields) != 2 {
+		return nil, false, fmt.Errorf("read checkpoint %q: unexpected reply %v", key, res)
+	}
+	value, _ := fields[0].(string)
+	if token != "" {
+		rev, _ := fields[1].(string)
+		s.mu.Lock()
+		s.claims[key] = redisClaim{token: token, rev: rev}
+		s.mu.Unlock()
+	}
+	return []byte(value), true, nil
+}
+
+// Set 写入检查点；之前通过 Get 占用过时，只有占用仍然有效才会写入并释放占用，否则返回包装了 ErrConflict 的错误
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := s.opts.checkSize(key, len(value)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	claim := s.claims[key]
+	delete(s.claims, key)
+	s.mu.Unlock()
+
+	md := metadataFrom(ctx)
+	n, err := redisSetScript.Run(ctx, s.client, []string{s.dataKey(key), s.claimKey(key)},
+		claim.token, claim.rev, value, time.Now().UnixMilli(), s.opts.ttl.Milliseconds(), md.Agent, md.SessionID).Int64()
+	if err != nil {
+		return fmt.Errorf("write checkpoint %q: %w", key, err)
+	}
+	if n < 0 {
+		return fmt.Errorf("checkpoint %q: %w", key, ErrConflict)
+	}
+	return nil
+}
+
+// Release 释放本副本通过 Get 占用的检查点，恢复运行结束（没有再次中断）或失败后调用，其他副本即可再次恢复
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	claim, ok := s.claims[key]
+	delete(s.claims, key)
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := redisReleaseScript.Run(ctx, s.client, []string{s.claimKey(key)}, claim.token).Err(); err != nil {
+		return fmt.Errorf("release checkpoint %q: %w", key, err)
+	}
+	return nil
+}
+
+// Release 释放 s 中 key 的占用，s 可以是包装了 RedisStore 的 HistoryStore 或 SecureStore，
+// 不会占用检查点的存储什么也不做；Runner.ResumeWithParams 失败后调用，避免会话在 ClaimTTL 内无法再次恢复
+func Release(ctx context.Context, s compose.CheckPointStore, key string) error {
+	if r, ok := s.(interface {
+		Release(ctx context.Context, key string) error
+	}); ok {
+		return r.Release(ctx, key)
+	}
+	return nil
+}
+
+// Delete 删除检查点及其占用标记
+func (s *RedisStore) Delete(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	delete(s.claims, key)
+	s.mu.Unlock()
+	pipe := s.client.TxPipeline()
+	del := pipe.Del(ctx, s.dataKey(key))
+	pipe.Del(ctx, s.claimKey(key))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("delete checkpoint %q: %w", key, err)
+	}
+	return del.Val() > 0, nil
+}
+
+// List 用 SCAN 列出 key 以 prefix 开头的检查点，集群模式下遍历所有主节点
+func (s *RedisStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	keys, err := s.scan(ctx, s.prefix+"ckpt:{"+escapeGlob(prefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]CheckpointInfo, 0, len(keys))
+	// 集群模式下各个 key 可能在不同节点上，逐个读取，由客户端按槽路由
+	for _, k := range keys {
+		info, ok, err := s.info(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		// SCAN 和读取之间可能已过期或被删除
+		if ok {
+			infos = append(infos, info)
+		}
+	}
+	sortInfos(infos)
+	return infos, nil
+}
+
+// Stats 统计所有检查点，已过期的检查点已被 Redis 删除
+func (s *RedisStore) Stats(ctx context.Context) (Stats, error) {
+	infos, err := s.List(ctx, "")
+	if err != nil {
+		return Stats{}, err
+	}
+	var st Stats
+	for _, info := range infos {
+		st.Count++
+		st.Bytes += info.Size
+	}
+	return st, nil
+}
+
+func (s *RedisStore) info(ctx context.Context, redisKey string) (CheckpointInfo, bool, error) {
+	pipe := s.client.Pipeline()
+	// go-redis 没有封装 HSTRLEN
+	size := pipe.Do(ctx, "HSTRLEN", redisKey, "v")
+	fields := pipe.HMGet(ctx, redisKey, "created", "updated", "agent", "session")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return CheckpointInfo{}, false, fmt.Errorf("read checkpoint info: %w", err)
+	}
+	vals := fields.Val()
+	n, _ := size.Int64()
+	if n == 0 && vals[1] == nil {
+		return CheckpointInfo{}, false, nil
+	}
+	str := func(v any) string { s, _ := v.(string); return s }
+	ms := func(v any) time.Time { n, _ := strconv.ParseInt(str(v), 10, 64); return time.UnixMilli(n) }
+	return CheckpointInfo{
+		ID:        strings.TrimSuffix(strings.TrimPrefix(redisKey, s.prefix+"ckpt:{"), "}"),
+		Agent:     str(vals[2]),
+		SessionID: str(vals[3]),
+		Size:      n,
+		CreatedAt: ms(vals[0]),
+		UpdatedAt: ms(vals[1]),
+	}, true, nil
+}
+
+func (s *RedisStore) scan(ctx context.Context, match string) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+	scanNode := func(ctx context.Context, c redis.Cmdable) error {
+		it := c.Scan(ctx, 0, match, 1000).Iterator()
+		for it.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, it.Val())
+			mu.Unlock()
+		}
+		return it.Err()
+	}
+
+	var err error
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error { return scanNode(ctx, c) })
+	} else {
+		err = scanNode(ctx, s.client)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan checkpoints: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *RedisStore) dataKey(key string) string {
+	return s.prefix + "ckpt:{" + key + "}"
+}
+
+func (s *RedisStore) claimKey(key string) string {
+	return s.prefix + "claim:{" + key + "}"
+}
+
+// Close 关闭 Redis 客户端
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// escapeGlob 转义 SCAN MATCH 的通配符，prefix 按字面匹配
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
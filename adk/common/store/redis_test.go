@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testClaimTTL = time.Minute
+
+// newRedisReplicas 返回共用同一个 miniredis 的 n 个 RedisStore，模拟多个副本
+func newRedisReplicas(t *testing.T, n int) (*miniredis.Miniredis, []*RedisStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	stores := make([]*RedisStore, n)
+	for i := range stores {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		s, err := NewRedisStore(RedisConfig{Client: client, ClaimTTL: testClaimTTL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[i] = s
+	}
+	return mr, stores
+}
+
+// mustGet 读取 key，要求检查点存在且内容为 want
+func mustGet(t *testing.T, ctx context.Context, s *RedisStore, key, want string) {
+	t.Helper()
+	v, ok, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get %q: %v", key, err)
+	}
+	if !ok || string(v) != want {
+		t.Fatalf("get %q = %q, %v; want %q", key, v, ok, want)
+	}
+}
+
+func TestRedisStoreGetConflict(t *testing.T) {
+	ctx := context.Background()
+	_, rs := newRedisReplicas(t, 2)
+	a, b := rs[0], rs[1]
+	if err := a.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	mustGet(t, ctx, a, "s1", "v1")
+	// 同一个副本可以重复读取
+	mustGet(t, ctx, a, "s1", "v1")
+	if _, _, err := b.Get(ctx, "s1"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("second replica get: err = %v, want ErrConflict", err)
+	}
+	// 只读不受占用影响
+	mustGet(t, WithReadOnly(ctx), b, "s1", "v1")
+
+	if err := a.Set(ctx, "s1", []byte("v2")); err != nil {
+		t.Fatalf("owner set: %v", err)
+	}
+	// Set 后占用已释放
+	mustGet(t, ctx, b, "s1", "v2")
+}
+
+func TestRedisStoreSetAfterClaimStolen(t *testing.T) {
+	ctx := context.Background()
+	mr, rs := newRedisReplicas(t, 2)
+	a, b := rs[0], rs[1]
+	if err := a.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	mustGet(t, ctx, a, "s1", "v1")
+	mr.FastForward(testClaimTTL + time.Second)
+	mustGet(t, ctx, b, "s1", "v1")
+
+	if err := a.Set(ctx, "s1", []byte("from a")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("set after claim stolen: err = %v, want ErrConflict", err)
+	}
+	if err := b.Set(ctx, "s1", []byte("from b")); err != nil {
+		t.Fatalf("new owner set: %v", err)
+	}
+	mustGet(t, WithReadOnly(ctx), a, "s1", "from b")
+}
+
+func TestRedisStoreSetAfterClaimExpired(t *testing.T) {
+	ctx := context.Background()
+	mr, rs := newRedisReplicas(t, 2)
+	a, b := rs[0], rs[1]
+	if err := a.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 占用过期但期间没有其他副本写入，允许写入
+	mustGet(t, ctx, a, "s1", "v1")
+	mr.FastForward(testClaimTTL + time.Second)
+	if err := a.Set(ctx, "s1", []byte("v2")); err != nil {
+		t.Fatalf("set after claim expired: %v", err)
+	}
+
+	// 占用过期后被其他副本读取并改写，版本号变化，拒绝写入
+	mustGet(t, ctx, a, "s1", "v2")
+	mr.FastForward(testClaimTTL + time.Second)
+	mustGet(t, ctx, b, "s1", "v2")
+	if err := b.Set(ctx, "s1", []byte("v3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set(ctx, "s1", []byte("stale")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("set after revision changed: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestRedisStoreRelease(t *testing.T) {
+	ctx := context.Background()
+	_, rs := newRedisReplicas(t, 2)
+	a, b := rs[0], rs[1]
+	if err := a.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	mustGet(t, ctx, a, "s1", "v1")
+	// 通过包装的存储释放
+	if err := Release(ctx, NewHistoryStore(a), "s1"); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(t, ctx, b, "s1", "v1")
+
+	// 已被其他副本占用时，Release 不会删除对方的占用
+	if err := a.Release(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.Get(ctx, "s1"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("get after other replica claimed: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestRedisStoreList(t *testing.T) {
+	ctx := WithMetadata(context.Background(), "agent", "session")
+	_, rs := newRedisReplicas(t, 1)
+	s := rs[0]
+	for _, key := range []string{"a1", "a2", "a*x", "b1"} {
+		if err := s.Set(ctx, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for prefix, want := range map[string][]string{
+		"":   {"a*x", "a1", "a2", "b1"},
+		"a":  {"a*x", "a1", "a2"},
+		"a*": {"a*x"},
+		"c":  nil,
+	} {
+		infos, err := s.List(ctx, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, info := range infos {
+			got = append(got, info.ID)
+			if info.Agent != "agent" || info.SessionID != "session" {
+				t.Errorf("%s: agent, session = %q, %q", info.ID, info.Agent, info.SessionID)
+			}
+		}
+		sort.Strings(got)
+		if len(got) != len(want) {
+			t.Errorf("List(%q) = %v, want %v", prefix, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("List(%q) = %v, want %v", prefix, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestHistoryStoreOnRedis(t *testing.T) {
+	ctx := context.Background()
+	mr, rs := newRedisReplicas(t, 2)
+	a, b := NewHistoryStore(rs[0]), NewHistoryStore(rs[1])
+
+	// 读取 head 不占用，两个副本可以交替写入
+	if err := a.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(ctx, "s1", []byte("v2")); err != nil {
+		t.Fatalf("second replica set: %v", err)
+	}
+
+	// b 的占用过期后被 a 抢走，b 的写入被拒绝，且不留下新版本、不推进 head
+	if _, _, err := b.Get(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(testClaimTTL + time.Second)
+	if _, _, err := a.Get(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(ctx, "s1", []byte("stale")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("set after claim stolen: err = %v, want ErrConflict", err)
+	}
+	if err := a.Set(ctx, "s1", []byte("v3")); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := a.Versions(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("versions = %+v, want 3", versions)
+	}
+	for i, want := range []string{"v1", "v2", "v3"} {
+		v, ok, err := a.GetVersion(ctx, "s1", i+1)
+		if err != nil || !ok || string(v) != want {
+			t.Errorf("version %d = %q, %v, %v; want %q", i+1, v, ok, err, want)
+		}
+	}
+}
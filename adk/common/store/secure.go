@@ -0,0 +1,295 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression 检查点加密前使用的压缩算法
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+// secureMagic 加密检查点的前缀，用于区分未加密的旧数据
+var secureMagic = []byte("eck1")
+
+// secureHeaderLen magic(4) + 压缩算法(1) + 密钥版本(4)
+const secureHeaderLen = 9
+
+var (
+	// ErrTampered 检查点解密失败：内容被篡改、被换到了其他 key 下，或者使用了错误的密钥
+	ErrTampered = errors.New("checkpoint authentication failed")
+	// ErrAdminUnsupported 被包装的存储没有实现 Admin
+	ErrAdminUnsupported = errors.New("underlying checkpoint store does not implement Admin")
+)
+
+// UnknownKeyError 检查点使用的密钥版本不在 SecureConfig.Keys 中，通常是轮换时过早删除了旧密钥
+type UnknownKeyError struct {
+	Key     string
+	Version uint32
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("checkpoint %q is encrypted with unknown key version %d", e.Key, e.Version)
+}
+
+// SecureConfig 加密存储的配置
+type SecureConfig struct {
+	// Keys 密钥版本 -> AES 密钥，长度为 16、24 或 32 字节
+	// 轮换密钥时加入新版本并修改 CurrentKey，旧版本保留到所有检查点都用新密钥重新写入（见 Reencrypt）
+	Keys map[uint32][]byte
+	// CurrentKey 新写入的检查点使用的密钥版本
+	CurrentKey uint32
+	// Compression 加密前的压缩算法，默认不压缩
+	Compression Compression
+}
+
+// NewSecureStore 用 AES-GCM 加密包装任意 CheckPointStore，写入前先压缩再加密
+//
+// 检查点 key 作为附加数据参与认证，把密文复制到其他 key 下同样会被识别为篡改；
+// 读取时密钥版本记录在密文头部，因此不同版本密钥写入的检查点可以共存
+func NewSecureStore(inner compose.CheckPointStore, cfg *SecureConfig) (*SecureStore, error) {
+	if cfg == nil || len(cfg.Keys) == 0 {
+		return nil, errors.New("secure store requires at least one key")
+	}
+	s := &SecureStore{inner: inner, current: cfg.CurrentKey, compression: cfg.Compression, aeads: make(map[uint32]cipher.AEAD)}
+	for version, key := range cfg.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint key version %d: %w", version, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint key version %d: %w", version, err)
+		}
+		s.aeads[version] = aead
+	}
+	if _, ok := s.aeads[cfg.CurrentKey]; !ok {
+		return nil, fmt.Errorf("current checkpoint key version %d not in keys", cfg.CurrentKey)
+	}
+
+	switch cfg.Compression {
+	case CompressionNone, CompressionGzip:
+	case CompressionZstd:
+		var err error
+		if s.zenc, err = zstd.NewWriter(nil); err != nil {
+			return nil, fmt.Errorf("create zstd encoder: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown checkpoint compression %d", cfg.Compression)
+	}
+	return s, nil
+}
+
+// SecureStore 压缩并加密检查点的 CheckPointStore 装饰器
+// 被包装的存储实现了 Admin 时，Delete、List、Stats 直接转发，Size 和 Bytes 为加密后的大小
+type SecureStore struct {
+	inner       compose.CheckPointStore
+	aeads       map[uint32]cipher.AEAD
+	current     uint32
+	compression Compression
+	zenc        *zstd.Encoder // EncodeAll 可以并发调用
+}
+
+// zdec 所有 SecureStore 共享的 zstd 解码器，第一次解压时创建，DecodeAll 可以并发调用
+var zdec = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil)
+})
+
+// Set 压缩、加密后写入被包装的存储
+func (s *SecureStore) Set(ctx context.Context, key string, value []byte) error {
+	bs, err := s.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.inner.Set(ctx, key, bs)
+}
+
+// Get 读取并解密检查点，认证失败时返回包装了 ErrTampered 的错误
+func (s *SecureStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	bs, ok, err := s.inner.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	v, err := s.open(key, bs)
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Release 释放被包装的存储中 key 的占用，见 RedisStore.Release
+func (s *SecureStore) Release(ctx context.Context, key string) error {
+	return Release(ctx, s.inner, key)
+}
+
+// Reencrypt 用当前密钥重新写入 key 以 prefix 开头、且不是用当前密钥加密的检查点，返回重新写入的数量
+// 轮换密钥后执行一次，之后即可从 Keys 中删除旧版本
+func (s *SecureStore) Reencrypt(ctx context.Context, prefix string) (int, error) {
+	admin, ok := s.inner.(Admin)
+	if !ok {
+		return 0, ErrAdminUnsupported
+	}
+	infos, err := admin.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, info := range infos {
+		bs, ok, err := s.inner.Get(ctx, info.ID)
+		if err != nil {
+			return n, err
+		}
+		if !ok || len(bs) < secureHeaderLen || binary.BigEndian.Uint32(bs[5:9]) == s.current {
+			continue
+		}
+		v, err := s.open(info.ID, bs)
+		if err != nil {
+			return n, err
+		}
+		if err := s.Set(ctx, info.ID, v); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Delete 转发给被包装的存储
+func (s *SecureStore) Delete(ctx context.Context, key string) (bool, error) {
+	admin, ok := s.inner.(Admin)
+	if !ok {
+		return false, ErrAdminUnsupported
+	}
+	return admin.Delete(ctx, key)
+}
+
+// List 转发给被包装的存储
+func (s *SecureStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	admin, ok := s.inner.(Admin)
+	if !ok {
+		return nil, ErrAdminUnsupported
+	}
+	return admin.List(ctx, prefix)
+}
+
+// Stats 转发给被包装的存储
+func (s *SecureStore) Stats(ctx context.Context) (Stats, error) {
+	admin, ok := s.inner.(Admin)
+	if !ok {
+		return Stats{}, ErrAdminUnsupported
+	}
+	return admin.Stats(ctx)
+}
+
+// seal 密文格式：magic | 压缩算法 | 密钥版本 | nonce | AES-GCM(压缩后的内容)，头部和 key 作为附加数据
+func (s *SecureStore) seal(key string, value []byte) ([]byte, error) {
+	plain, err := s.compress(value)
+	if err != nil {
+		return nil, err
+	}
+	aead := s.aeads[s.current]
+
+	out := make([]byte, secureHeaderLen, secureHeaderLen+aead.NonceSize()+len(plain)+aead.Overhead())
+	copy(out, secureMagic)
+	out[4] = byte(s.compression)
+	binary.BigEndian.PutUint32(out[5:9], s.current)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plain, additionalData(out[:secureHeaderLen], key)), nil
+}
+
+func (s *SecureStore) open(key string, bs []byte) ([]byte, error) {
+	if len(bs) < secureHeaderLen || !bytes.Equal(bs[:4], secureMagic) {
+		return nil, fmt.Errorf("checkpoint %q is not encrypted: %w", key, ErrTampered)
+	}
+	version := binary.BigEndian.Uint32(bs[5:9])
+	aead, ok := s.aeads[version]
+	if !ok {
+		return nil, &UnknownKeyError{Key: key, Version: version}
+	}
+	rest := bs[secureHeaderLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("checkpoint %q: %w", key, ErrTampered)
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], additionalData(bs[:secureHeaderLen], key))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %q: %w", key, ErrTampered)
+	}
+	// 压缩算法在认证范围内，到这里一定是写入时的值
+	return decompress(Compression(bs[4]), plain)
+}
+
+func additionalData(header []byte, key string) []byte {
+	return append(append([]byte{}, header...), key...)
+}
+
+func (s *SecureStore) compress(value []byte) ([]byte, error) {
+	switch s.compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(value); err != nil {
+			return nil, fmt.Errorf("gzip checkpoint: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("gzip checkpoint: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return s.zenc.EncodeAll(value, nil), nil
+	default:
+		return value, nil
+	}
+}
+
+func decompress(c Compression, bs []byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return bs, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(bs))
+		if err != nil {
+			return nil, fmt.Errorf("gunzip checkpoint: %w", err)
+		}
+		defer r.Close()
+		v, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("gunzip checkpoint: %w", err)
+		}
+		return v, nil
+	case CompressionZstd:
+		dec, err := zdec()
+		if err != nil {
+			return nil, fmt.Errorf("create zstd decoder: %w", err)
+		}
+		v, err := dec.DecodeAll(bs, nil)
+		if err != nil {
+			return nil, fmt.Errorf("zstd decode checkpoint: %w", err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown checkpoint compression %d", c)
+	}
+}
+
+var _ Admin = (*SecureStore)(nil)
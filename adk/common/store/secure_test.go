@@ -0,0 +1,162 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte{1}, 32)
+	testKey2 = bytes.Repeat([]byte{2}, 16)
+)
+
+// newSecureStore 用 keys 中的密钥包装 inner，current 为当前密钥版本
+func newSecureStore(t *testing.T, inner compose.CheckPointStore, current uint32, keys map[uint32][]byte, c Compression) *SecureStore {
+	t.Helper()
+	s, err := NewSecureStore(inner, &SecureConfig{Keys: keys, CurrentKey: current, Compression: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSecureStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("检查点内容 checkpoint "), 100)
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		inner := NewInMemoryStore()
+		s := newSecureStore(t, inner, 1, map[uint32][]byte{1: testKey1}, c)
+		if err := s.Set(ctx, "s1", value); err != nil {
+			t.Fatal(err)
+		}
+		v, ok, err := s.Get(ctx, "s1")
+		if err != nil || !ok || !bytes.Equal(v, value) {
+			t.Fatalf("compression %d: get = %d bytes, %v, %v", c, len(v), ok, err)
+		}
+
+		raw, _, _ := inner.Get(ctx, "s1")
+		if bytes.Contains(raw, []byte("checkpoint")) {
+			t.Fatalf("compression %d: stored value is not encrypted", c)
+		}
+		if c != CompressionNone && len(raw) >= len(value) {
+			t.Fatalf("compression %d: stored %d bytes, want less than %d", c, len(raw), len(value))
+		}
+	}
+}
+
+func TestSecureStoreTampered(t *testing.T) {
+	ctx := context.Background()
+	inner := NewInMemoryStore()
+	s := newSecureStore(t, inner, 1, map[uint32][]byte{1: testKey1}, CompressionGzip)
+	if err := s.Set(ctx, "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _, _ := inner.Get(ctx, "s1")
+
+	tests := []struct {
+		name  string
+		key   string
+		value func() []byte
+	}{
+		{"flipped ciphertext", "s1", func() []byte {
+			bs := bytes.Clone(sealed)
+			bs[len(bs)-1] ^= 1
+			return bs
+		}},
+		// 压缩算法在头部，同样参与认证
+		{"flipped header", "s1", func() []byte {
+			bs := bytes.Clone(sealed)
+			bs[4] = byte(CompressionNone)
+			return bs
+		}},
+		{"truncated", "s1", func() []byte { return sealed[:secureHeaderLen+4] }},
+		{"copied to another key", "s2", func() []byte { return sealed }},
+		{"not encrypted", "s3", func() []byte { return []byte("plain checkpoint") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := inner.Set(ctx, tt.key, tt.value()); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, err := s.Get(ctx, tt.key); !errors.Is(err, ErrTampered) || ok {
+				t.Fatalf("get = %v, %v; want ErrTampered", ok, err)
+			}
+		})
+	}
+
+	// 使用错误的密钥同样视为篡改
+	other := newSecureStore(t, inner, 1, map[uint32][]byte{1: testKey2}, CompressionGzip)
+	if err := inner.Set(ctx, "s1", sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.Get(ctx, "s1"); !errors.Is(err, ErrTampered) {
+		t.Fatalf("get with wrong key: err = %v, want ErrTampered", err)
+	}
+}
+
+func TestSecureStoreKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := NewInMemoryStore()
+	old := newSecureStore(t, inner, 1, map[uint32][]byte{1: testKey1}, CompressionZstd)
+	for _, key := range []string{"a1", "a2", "b1"} {
+		if err := old.Set(ctx, key, []byte("v-"+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 加入新密钥后旧检查点仍可读取，新写入的检查点使用新密钥
+	rotated := newSecureStore(t, inner, 2, map[uint32][]byte{1: testKey1, 2: testKey2}, CompressionZstd)
+	if err := rotated.Set(ctx, "c1", []byte("v-c1")); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a1", "c1"} {
+		if v, ok, err := rotated.Get(ctx, key); err != nil || !ok || string(v) != "v-"+key {
+			t.Fatalf("get %s = %q, %v, %v", key, v, ok, err)
+		}
+	}
+	var unknown *UnknownKeyError
+	if _, _, err := old.Get(ctx, "c1"); !errors.As(err, &unknown) || unknown.Key != "c1" || unknown.Version != 2 {
+		t.Fatalf("get with old keys: err = %v, want UnknownKeyError for version 2", err)
+	}
+
+	n, err := rotated.Reencrypt(ctx, "a")
+	if err != nil || n != 2 {
+		t.Fatalf("reencrypt a = %d, %v; want 2", n, err)
+	}
+	if n, err := rotated.Reencrypt(ctx, ""); err != nil || n != 1 {
+		t.Fatalf("reencrypt all = %d, %v; want only b1", n, err)
+	}
+
+	// 全部重新加密后可以删除旧密钥
+	latest := newSecureStore(t, inner, 2, map[uint32][]byte{2: testKey2}, CompressionZstd)
+	for _, key := range []string{"a1", "a2", "b1", "c1"} {
+		if v, ok, err := latest.Get(ctx, key); err != nil || !ok || string(v) != "v-"+key {
+			t.Fatalf("get %s after rotation = %q, %v, %v", key, v, ok, err)
+		}
+	}
+}
+
+func TestSecureStoreConfig(t *testing.T) {
+	inner := NewInMemoryStore()
+	for name, cfg := range map[string]*SecureConfig{
+		"nil":             nil,
+		"no keys":         {},
+		"bad key length":  {Keys: map[uint32][]byte{1: []byte("short")}, CurrentKey: 1},
+		"missing current": {Keys: map[uint32][]byte{1: testKey1}, CurrentKey: 2},
+		"bad compression": {Keys: map[uint32][]byte{1: testKey1}, CurrentKey: 1, Compression: 9},
+	} {
+		if _, err := NewSecureStore(inner, cfg); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	// 被包装的存储没有实现 Admin
+	s := newSecureStore(t, struct{ compose.CheckPointStore }{inner}, 1, map[uint32][]byte{1: testKey1}, CompressionNone)
+	if _, err := s.Reencrypt(context.Background(), ""); !errors.Is(err, ErrAdminUnsupported) {
+		t.Fatalf("reencrypt: err = %v, want ErrAdminUnsupported", err)
+	}
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯 Go 实现的 SQLite 驱动，不依赖 cgo
+)
+
+// sqliteSchema 检查点表，时间戳为 Unix 毫秒
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS checkpoints (
+	id         TEXT PRIMARY KEY,
+	value      BLOB NOT NULL,
+	agent      TEXT NOT NULL DEFAULT '',
+	session_id TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	used_at    INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_checkpoints_updated_at ON checkpoints(updated_at);
+CREATE INDEX IF NOT EXISTS idx_checkpoints_used_at ON checkpoints(used_at);
+`
+
+// Metadata 随检查点一起记录的信息
+type Metadata struct {
+	Agent     string
+	SessionID string
+}
+
+type metadataKey struct{}
+
+// WithMetadata 把 Agent 名称和会话 ID 放入 ctx
+// Runner 保存检查点时会把运行时的 ctx 传给 Set，因此在 Run/Resume 前设置即可被记录下来
+func WithMetadata(ctx context.Context, agent, sessionID string) context.Context {
+	return context.WithValue(ctx, metadataKey{}, Metadata{Agent: agent, SessionID: sessionID})
+}
+
+func metadataFrom(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// SQLiteStore SQLite 实现的 CheckPointStore，除检查点内容外还记录创建/更新时间、Agent 名称和会话 ID
+// 并提供列出、删除、清理过期检查点等管理操作，实现了 Admin
+type SQLiteStore struct {
+	db   *sql.DB
+	opts options
+}
+
+// NewSQLiteStore 打开（不存在时创建）path 指向的数据库文件
+// 使用 WAL 模式，读取检查点时不会被写入阻塞
+func NewSQLiteStore(path string, opts ...Option) (*SQLiteStore, error) {
+	// 路径按 URI 转义，其中的 '?'、'#'、'%' 不会被当成查询参数或转义符
+	dsn := &url.URL{
+		Scheme:   "file",
+		Path:     path,
+		OmitHost: true, // 相对路径写成 file:dir/x.db，而不是把第一段当成主机名的 file://dir/x.db
+		RawQuery: "_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)",
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint db: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init checkpoint db: %w", err)
+	}
+	return &SQLiteStore{db: db, opts: newOptions(opts)}, nil
+}
+
+// Set 写入检查点，已存在时覆盖内容并保留创建时间
+// ctx 中没有元数据时（例如 Resume 时未调用 WithMetadata）保留原有的 Agent 名称和会话 ID
+func (s *SQLiteStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := s.opts.checkSize(key, len(value)); err != nil {
+		return err
+	}
+	md := metadataFrom(ctx)
+	now := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	defer tx.Rollback()
+	// 过期的检查点先删除，再按需淘汰，过期的检查点不占用容量
+	if s.opts.ttl > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM checkpoints WHERE updated_at < ?`, s.cutoff(now)); err != nil {
+			return fmt.Errorf("expire checkpoints: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO checkpoints (id, value, agent, session_id, created_at, updated_at, used_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	value      = excluded.value,
+	agent      = CASE WHEN excluded.agent = '' THEN checkpoints.agent ELSE excluded.agent END,
+	session_id = CASE WHEN excluded.session_id = '' THEN checkpoints.session_id ELSE excluded.session_id END,
+	updated_at = excluded.updated_at,
+	used_at    = excluded.used_at`,
+		key, value, md.Agent, md.SessionID, now.UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if s.opts.maxBytes > 0 {
+		if err := s.evict(ctx, tx, key); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// evict 按最近使用时间从旧到新删除检查点，直到总字节数不超过 MaxBytes；keep 为刚写入的检查点，不会被淘汰
+func (s *SQLiteStore) evict(ctx context.Context, tx *sql.Tx, keep string) error {
+	var total int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(length(value)), 0) FROM checkpoints`).Scan(&total); err != nil {
+		return fmt.Errorf("evict checkpoints: %w", err)
+	}
+	if total <= s.opts.maxBytes {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, length(value) FROM checkpoints WHERE id != ? ORDER BY used_at, id`, keep)
+	if err != nil {
+		return fmt.Errorf("evict checkpoints: %w", err)
+	}
+	var victims []string
+	for rows.Next() && total > s.opts.maxBytes {
+		var id string
+		var size int64
+		if err := rows.Scan(&id, &size); err != nil {
+			rows.Close()
+			return fmt.Errorf("evict checkpoints: %w", err)
+		}
+		victims = append(victims, id)
+		total -= size
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("evict checkpoints: %w", err)
+	}
+	for _, id := range victims {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM checkpoints WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("evict checkpoints: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get 读取检查点，不存在或已过期时返回 false
+func (s *SQLiteStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	now := time.Now()
+	var v []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM checkpoints WHERE id = ? AND updated_at >= ?`,
+		key, s.cutoff(now)).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read checkpoint: %w", err)
+	}
+	// 只有需要按最近使用淘汰时才记录读取时间，避免每次读取都产生一次写入
+	if s.opts.maxBytes > 0 {
+		if _, err := s.db.ExecContext(ctx, `UPDATE checkpoints SET used_at = ? WHERE id = ?`, now.UnixMilli(), key); err != nil {
+			return nil, false, fmt.Errorf("touch checkpoint: %w", err)
+		}
+	}
+	return v, true, nil
+}
+
+// List 列出 id 以 prefix 开头的检查点，按更新时间倒序
+func (s *SQLiteStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, agent, session_id, length(value), created_at, updated_at
+FROM checkpoints WHERE substr(id, 1, length(?)) = ? AND updated_at >= ?
+ORDER BY updated_at DESC, id`, prefix, prefix, s.cutoff(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var out []CheckpointInfo
+	for rows.Next() {
+		var info CheckpointInfo
+		var created, updated int64
+		if err := rows.Scan(&info.ID, &info.Agent, &info.SessionID, &info.Size, &created, &updated); err != nil {
+			return nil, fmt.Errorf("scan checkpoint: %w", err)
+		}
+		info.CreatedAt = time.UnixMilli(created)
+		info.UpdatedAt = time.UnixMilli(updated)
+		out = append(out, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list checkpoints: %w", err)
+	}
+	return out, nil
+}
+
+// Delete 删除检查点，返回是否存在
+func (s *SQLiteStore) Delete(ctx context.Context, key string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM checkpoints WHERE id = ?`, key)
+	if err != nil {
+		return false, fmt.Errorf("delete checkpoint: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete checkpoint: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Stats 返回检查点数量和总字节数
+func (s *SQLiteStore) Stats(ctx context.Context) (Stats, error) {
+	var st Stats
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(length(value)), 0) FROM checkpoints WHERE updated_at >= ?`,
+		s.cutoff(time.Now())).Scan(&st.Count, &st.Bytes)
+	if err != nil {
+		return Stats{}, fmt.Errorf("checkpoint stats: %w", err)
+	}
+	return st, nil
+}
+
+// cutoff 返回未过期检查点的最早更新时间（Unix 毫秒），未设置 TTL 时为 0
+func (s *SQLiteStore) cutoff(now time.Time) int64 {
+	if s.opts.ttl <= 0 {
+		return 0
+	}
+	return now.Add(-s.opts.ttl).UnixMilli()
+}
+
+// Prune 删除超过 olderThan 未更新的检查点，返回删除的数量
+func (s *SQLiteStore) Prune(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan).UnixMilli()
+	res, err := s.db.ExecContext(ctx, `DELETE FROM checkpoints WHERE updated_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("prune checkpoints: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("prune checkpoints: %w", err)
+	}
+	return n, nil
+}
+
+// Close 关闭数据库连接
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newSQLiteStore 在临时目录中创建 SQLite 存储
+func newSQLiteStore(t *testing.T, opts ...Option) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "checkpoints.db"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// backdate 把检查点的更新时间和最近使用时间改为 d 之前
+func (s *SQLiteStore) backdate(t *testing.T, key string, d time.Duration) {
+	t.Helper()
+	ts := time.Now().Add(-d).UnixMilli()
+	if _, err := s.db.Exec(`UPDATE checkpoints SET updated_at = ?, used_at = ? WHERE id = ?`, ts, ts, key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteStorePath 路径中的 '?'、'#'、'%' 和空格按文件名原样处理，相对路径也能打开
+func TestSQLiteStorePath(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, path := range []string{
+		filepath.Join(dir, "a?b#c%20 d.db"),
+		filepath.Join("sub dir", "ck.db"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("open %q: %v", path, err)
+		}
+		if err := s.Set(ctx, "s1", []byte("v1")); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("db file for %q: %v", path, err)
+		}
+
+		// 重新打开后仍能读到检查点
+		s, err = NewSQLiteStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, ok, err := s.Get(ctx, "s1")
+		s.Close()
+		if err != nil || !ok || string(v) != "v1" {
+			t.Fatalf("get after reopen %q = %q, %v, %v", path, v, ok, err)
+		}
+	}
+}
+
+func TestSQLiteStoreMetadata(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+	if err := s.Set(WithMetadata(ctx, "loop", "sess-1"), "s1", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	created := func() time.Time {
+		infos, err := s.List(ctx, "s1")
+		if err != nil || len(infos) != 1 {
+			t.Fatalf("list = %+v, %v", infos, err)
+		}
+		return infos[0].CreatedAt
+	}()
+
+	// ctx 中没有元数据时保留原来的 Agent 和会话 ID，覆盖内容但保留创建时间
+	time.Sleep(2 * time.Millisecond)
+	if err := s.Set(ctx, "s1", []byte("v22")); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := s.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("list = %+v", infos)
+	}
+	info := infos[0]
+	if info.Agent != "loop" || info.SessionID != "sess-1" || info.Size != 3 {
+		t.Fatalf("info = %+v", info)
+	}
+	if !info.CreatedAt.Equal(created) || !info.UpdatedAt.After(created) {
+		t.Fatalf("created, updated = %v, %v; want created %v kept", info.CreatedAt, info.UpdatedAt, created)
+	}
+}
+
+func TestSQLiteStoreListPrefix(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+	for _, key := range []string{"a1", "a2", "a%x", "a_y", "b1"} {
+		if err := s.Set(ctx, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// prefix 按字面匹配，'%' 和 '_' 不是通配符
+	for prefix, want := range map[string]int{"": 5, "a": 4, "a%": 1, "a_": 1, "c": 0} {
+		infos, err := s.List(ctx, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != want {
+			t.Errorf("List(%q) = %+v, want %d", prefix, infos, want)
+		}
+	}
+}
+
+func TestSQLiteStorePrune(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+	for _, key := range []string{"old1", "old2", "new"} {
+		if err := s.Set(ctx, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.backdate(t, "old1", 48*time.Hour)
+	s.backdate(t, "old2", 25*time.Hour)
+
+	n, err := s.Prune(ctx, 24*time.Hour)
+	if err != nil || n != 2 {
+		t.Fatalf("prune = %d, %v; want 2", n, err)
+	}
+	st, err := s.Stats(ctx)
+	if err != nil || st.Count != 1 || st.Bytes != 3 {
+		t.Fatalf("stats = %+v, %v", st, err)
+	}
+	if n, err := s.Prune(ctx, 24*time.Hour); err != nil || n != 0 {
+		t.Fatalf("second prune = %d, %v; want 0", n, err)
+	}
+}
@@ -1,40 +1,152 @@
 package store
 
 import (
+	"container/list"
 	"context"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/cloudwego/eino/compose"
 )
 
 // NewInMemoryStore 创建一个线程安全的内存 CheckPointStore
 // 用于存储 Agent 执行中断时的检查点状态
-func NewInMemoryStore() compose.CheckPointStore {
+// 返回值同时实现了 Admin，可以通过 opts 设置过期时间和容量上限
+func NewInMemoryStore(opts ...Option) compose.CheckPointStore {
 	return &inMemoryStore{
-		mem: make(map[string][]byte),
+		opts:  newOptions(opts),
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
 	}
 }
 
+// memEntry 内存存储中的一个检查点
+type memEntry struct {
+	key       string
+	value     []byte
+	createdAt time.Time
+	updatedAt time.Time
+}
+
 // inMemoryStore 内存实现的 CheckPointStore
+// 链表按最近使用排序，表头为最近读写的检查点，超出 MaxBytes 时从表尾淘汰
 type inMemoryStore struct {
-	mu  sync.RWMutex        // 读写锁，保护 map 的并发访问
-	mem map[string][]byte    // 存储 key-value 数据
+	opts options
+
+	mu    sync.Mutex               // Get 也会调整链表顺序，因此读写都需要互斥
+	ll    *list.List               // 元素为 *memEntry
+	items map[string]*list.Element // key -> 链表元素
+	bytes int64                    // 所有检查点内容的总字节数
 }
 
 // Set 设置 key-value 到存储中
 // 这是线程安全的操作，可以并发调用
 func (i *inMemoryStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := i.opts.checkSize(key, len(value)); err != nil {
+		return err
+	}
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
-	i.mem[key] = value
+	now := time.Now()
+	i.removeExpired(now)
+	if el, ok := i.items[key]; ok {
+		e := el.Value.(*memEntry)
+		i.bytes += int64(len(value) - len(e.value))
+		e.value, e.updatedAt = value, now
+		i.ll.MoveToFront(el)
+	} else {
+		i.items[key] = i.ll.PushFront(&memEntry{key: key, value: value, createdAt: now, updatedAt: now})
+		i.bytes += int64(len(value))
+	}
+	// 表头是刚写入的检查点，单个检查点不会超过 MaxBytes，因此不会被淘汰
+	for i.opts.maxBytes > 0 && i.bytes > i.opts.maxBytes {
+		i.removeElement(i.ll.Back())
+	}
 	return nil
 }
 
 // Get 从存储中获取 key 对应的值
-// 这是线程安全的操作，支持并发读
+// 这是线程安全的操作，支持并发调用；已过期的检查点视为不存在
 func (i *inMemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
-	i.mu.RLock()
-	defer i.mu.RUnlock()
-	v, ok := i.mem[key]
-	return v, ok, nil
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	el, ok := i.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memEntry)
+	if i.opts.expired(e.updatedAt, time.Now()) {
+		i.removeElement(el)
+		return nil, false, nil
+	}
+	i.ll.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Delete 删除检查点，返回是否存在
+func (i *inMemoryStore) Delete(ctx context.Context, key string) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	el, ok := i.items[key]
+	if ok {
+		i.removeElement(el)
+	}
+	return ok, nil
+}
+
+// List 列出 key 以 prefix 开头的检查点，按更新时间倒序
+func (i *inMemoryStore) List(ctx context.Context, prefix string) ([]CheckpointInfo, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeExpired(time.Now())
+	var out []CheckpointInfo
+	for el := i.ll.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*memEntry)
+		if strings.HasPrefix(e.key, prefix) {
+			out = append(out, CheckpointInfo{ID: e.key, Size: int64(len(e.value)), CreatedAt: e.createdAt, UpdatedAt: e.updatedAt})
+		}
+	}
+	sortInfos(out)
+	return out, nil
+}
+
+// Stats 返回检查点数量和总字节数
+func (i *inMemoryStore) Stats(ctx context.Context) (Stats, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeExpired(time.Now())
+	return Stats{Count: len(i.items), Bytes: i.bytes}, nil
+}
+
+// removeExpired 删除所有已过期的检查点，调用方需持有锁
+func (i *inMemoryStore) removeExpired(now time.Time) {
+	if i.opts.ttl <= 0 {
+		return
+	}
+	for el := i.ll.Front(); el != nil; {
+		next := el.Next()
+		if i.opts.expired(el.Value.(*memEntry).updatedAt, now) {
+			i.removeElement(el)
+		}
+		el = next
+	}
+}
+
+func (i *inMemoryStore) removeElement(el *list.Element) {
+	e := i.ll.Remove(el).(*memEntry)
+	delete(i.items, e.key)
+	i.bytes -= int64(len(e.value))
+}
+
+// sortInfos 按更新时间倒序排列，时间相同时按 ID 排序，保证输出稳定
+func sortInfos(infos []CheckpointInfo) {
+	sort.Slice(infos, func(a, b int) bool {
+		if !infos[a].UpdatedAt.Equal(infos[b].UpdatedAt) {
+			return infos[a].UpdatedAt.After(infos[b].UpdatedAt)
+		}
+		return infos[a].ID < infos[b].ID
+	})
 }
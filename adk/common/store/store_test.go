@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// adminStore 同时实现了 CheckPointStore 和 Admin 的存储
+type adminStore interface {
+	compose.CheckPointStore
+	Admin
+}
+
+// storeCase 一种存储的构造方式，backdate 把检查点的更新时间改为 d 之前
+type storeCase struct {
+	name     string
+	open     func(t *testing.T, opts ...Option) adminStore
+	backdate func(t *testing.T, s adminStore, key string, d time.Duration)
+}
+
+var storeCases = []storeCase{
+	{
+		name: "memory",
+		open: func(t *testing.T, opts ...Option) adminStore {
+			return NewInMemoryStore(opts...).(*inMemoryStore)
+		},
+		backdate: func(t *testing.T, s adminStore, key string, d time.Duration) {
+			m := s.(*inMemoryStore)
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.items[key].Value.(*memEntry).updatedAt = time.Now().Add(-d)
+		},
+	},
+	{
+		name: "file",
+		open: func(t *testing.T, opts ...Option) adminStore {
+			s, _ := newFileStore(t, opts...)
+			return s
+		},
+		backdate: func(t *testing.T, s adminStore, key string, d time.Duration) {
+			ts := time.Now().Add(-d)
+			if err := os.Chtimes(s.(*fileStore).path(key), ts, ts); err != nil {
+				t.Fatal(err)
+			}
+		},
+	},
+	{
+		name: "sqlite",
+		open: func(t *testing.T, opts ...Option) adminStore {
+			return newSQLiteStore(t, opts...)
+		},
+		backdate: func(t *testing.T, s adminStore, key string, d time.Duration) {
+			s.(*SQLiteStore).backdate(t, key, d)
+		},
+	},
+}
+
+// keys 返回 List 结果中的 ID
+func keys(t *testing.T, s Admin, prefix string) string {
+	t.Helper()
+	infos, err := s.List(context.Background(), prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(infos))
+	for i, info := range infos {
+		ids[i] = info.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+// tick 等待时钟前进：SQLite 的时间戳精度为毫秒，文件修改时间的精度取决于内核时钟，
+// 连续的读写需要隔开才能区分先后
+func tick() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestStoreAdmin(t *testing.T) {
+	ctx := context.Background()
+	for _, sc := range storeCases {
+		t.Run(sc.name, func(t *testing.T) {
+			s := sc.open(t)
+			for _, key := range []string{"a1", "a2", "b1"} {
+				if err := s.Set(ctx, key, []byte("v-"+key)); err != nil {
+					t.Fatal(err)
+				}
+				tick()
+			}
+			// 按更新时间倒序
+			if got := keys(t, s, ""); got != "b1,a2,a1" {
+				t.Fatalf("List(\"\") = %s", got)
+			}
+			if got := keys(t, s, "a"); got != "a2,a1" {
+				t.Fatalf("List(\"a\") = %s", got)
+			}
+			if st, err := s.Stats(ctx); err != nil || st.Count != 3 || st.Bytes != 12 {
+				t.Fatalf("stats = %+v, %v", st, err)
+			}
+			if ok, err := s.Delete(ctx, "a1"); err != nil || !ok {
+				t.Fatalf("delete = %v, %v", ok, err)
+			}
+			if ok, err := s.Delete(ctx, "a1"); err != nil || ok {
+				t.Fatalf("delete missing = %v, %v", ok, err)
+			}
+			if st, err := s.Stats(ctx); err != nil || st.Count != 2 || st.Bytes != 8 {
+				t.Fatalf("stats after delete = %+v, %v", st, err)
+			}
+		})
+	}
+}
+
+func TestStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	for _, sc := range storeCases {
+		t.Run(sc.name, func(t *testing.T) {
+			s := sc.open(t, WithTTL(time.Hour))
+			for _, key := range []string{"old", "new"} {
+				if err := s.Set(ctx, key, []byte(key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sc.backdate(t, s, "old", 2*time.Hour)
+
+			if _, ok, err := s.Get(ctx, "old"); err != nil || ok {
+				t.Fatalf("get expired = %v, %v; want not found", ok, err)
+			}
+			if v, ok, err := s.Get(ctx, "new"); err != nil || !ok || string(v) != "new" {
+				t.Fatalf("get live = %q, %v, %v", v, ok, err)
+			}
+			if got := keys(t, s, ""); got != "new" {
+				t.Fatalf("List = %s, want only the live checkpoint", got)
+			}
+			if st, err := s.Stats(ctx); err != nil || st.Count != 1 || st.Bytes != 3 {
+				t.Fatalf("stats = %+v, %v", st, err)
+			}
+
+			// 重新写入后不再过期
+			if err := s.Set(ctx, "old", []byte("again")); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok, err := s.Get(ctx, "old"); err != nil || !ok || string(v) != "again" {
+				t.Fatalf("get rewritten = %q, %v, %v", v, ok, err)
+			}
+		})
+	}
+}
+
+func TestStoreMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	value := []byte("0123456789")
+	for _, sc := range storeCases {
+		t.Run(sc.name, func(t *testing.T) {
+			s := sc.open(t, WithMaxBytes(35))
+			for _, key := range []string{"a", "b", "c"} {
+				if err := s.Set(ctx, key, value); err != nil {
+					t.Fatal(err)
+				}
+				tick()
+			}
+			// 读取 a 后 b 成为最久未使用的检查点
+			if _, ok, err := s.Get(ctx, "a"); err != nil || !ok {
+				t.Fatalf("get a = %v, %v", ok, err)
+			}
+			tick()
+			if err := s.Set(ctx, "d", value); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := s.Get(ctx, "b"); ok {
+				t.Fatal("b should have been evicted")
+			}
+			for _, key := range []string{"a", "c", "d"} {
+				if _, ok, err := s.Get(ctx, key); err != nil || !ok {
+					t.Fatalf("get %s = %v, %v; want kept", key, ok, err)
+				}
+			}
+			if st, err := s.Stats(ctx); err != nil || st.Count != 3 || st.Bytes != 30 {
+				t.Fatalf("stats = %+v, %v", st, err)
+			}
+
+			// 刚写入的大检查点不会被淘汰，其他检查点给它让位
+			big := make([]byte, 30)
+			if err := s.Set(ctx, "big", big); err != nil {
+				t.Fatal(err)
+			}
+			if got := keys(t, s, ""); got != "big" {
+				t.Fatalf("List = %s, want only the new checkpoint", got)
+			}
+		})
+	}
+}
+
+func TestStoreTooLarge(t *testing.T) {
+	ctx := context.Background()
+	for _, sc := range storeCases {
+		t.Run(sc.name, func(t *testing.T) {
+			tests := []struct {
+				opt   Option
+				limit int64
+			}{
+				{WithMaxCheckpointSize(8), 8},
+				// 比所有检查点的总上限还大，淘汰所有检查点也放不下
+				{WithMaxBytes(6), 6},
+			}
+			for _, tt := range tests {
+				s := sc.open(t, tt.opt)
+				if err := s.Set(ctx, "small", []byte("ok")); err != nil {
+					t.Fatal(err)
+				}
+				err := s.Set(ctx, "s1", []byte("0123456789"))
+				var tooLarge *TooLargeError
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("err = %v, want *TooLargeError", err)
+				}
+				if tooLarge.Key != "s1" || tooLarge.Size != 10 || tooLarge.Limit != tt.limit {
+					t.Fatalf("err = %+v", tooLarge)
+				}
+				// 被拒绝的写入不影响已有的检查点
+				if got := keys(t, s, ""); got != "small" {
+					t.Fatalf("List = %s", got)
+				}
+			}
+		})
+	}
+}
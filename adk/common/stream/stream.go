@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ChunkFunc 每收到一个 chunk 调用一次，返回错误时停止读取，用于边接收边渲染
+type ChunkFunc func(chunk *schema.Message) error
+
+// Aggregator 逐个接收流式消息的 chunk，汇总成一条完整消息
+//
+// 文本内容和思考过程按到达顺序拼接；工具调用按 Index 分组，同一组的参数（分段的 JSON）直接拼接，
+// 结果按 Index 升序排列，没有 Index 的工具调用各自独立、排在最前面；
+// 用量取各 chunk 中的最大值，一般即最后一个 chunk 中的用量
+type Aggregator struct {
+	chunks []*schema.Message
+}
+
+// Add 加入一个 chunk，nil 会被忽略
+func (a *Aggregator) Add(chunk *schema.Message) {
+	if chunk != nil {
+		a.chunks = append(a.chunks, chunk)
+	}
+}
+
+// Len 已经加入的 chunk 数
+func (a *Aggregator) Len() int {
+	return len(a.chunks)
+}
+
+// Message 返回汇总后的消息，总是新的对象，修改它不会影响原始 chunk；还没有 chunk 时返回 nil
+func (a *Aggregator) Message() (*schema.Message, error) {
+	if len(a.chunks) == 0 {
+		return nil, nil
+	}
+	m, err := schema.ConcatMessages(a.chunks)
+	if err != nil {
+		return nil, fmt.Errorf("concat message chunks: %w", err)
+	}
+	return m, nil
+}
+
+// Collect 读完消息流并返回汇总后的消息，onChunk 不为 nil 时每个 chunk 都会先交给它
+// 读完或出错后关闭流；流为空时返回 nil
+func Collect(s *schema.StreamReader[*schema.Message], onChunk ChunkFunc) (*schema.Message, error) {
+	defer s.Close()
+	var a Aggregator
+	for {
+		chunk, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("receive message stream: %w", err)
+		}
+		if chunk == nil {
+			continue
+		}
+		if onChunk != nil {
+			if err := onChunk(chunk); err != nil {
+				return nil, err
+			}
+		}
+		a.Add(chunk)
+	}
+	return a.Message()
+}
+
+// EventMessage 返回事件中的完整消息，非流式消息直接返回，不调用 onChunk
+//
+// 流式消息读取的是副本，事件中的流换成另一个副本，之后仍然可以再次调用 EventMessage 或 adk.GetMessage；
+// 事件中没有消息时返回 nil
+func EventMessage(event *adk.AgentEvent, onChunk ChunkFunc) (*schema.Message, error) {
+	if event == nil || event.Output == nil || event.Output.MessageOutput == nil {
+		return nil, nil
+	}
+	mo := event.Output.MessageOutput
+	if mo.MessageStream == nil {
+		return mo.Message, nil
+	}
+	ss := mo.MessageStream.Copy(2)
+	mo.MessageStream = ss[1]
+	return Collect(ss[0], onChunk)
+}
+
+// Usage 返回消息中的 token 用量，没有时返回 nil
+func Usage(m *schema.Message) *schema.TokenUsage {
+	if m == nil || m.ResponseMeta == nil {
+		return nil
+	}
+	return m.ResponseMeta.Usage
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// toolChunk 只含一个工具调用分片的 chunk，index 为 nil 表示没有 Index
+func toolChunk(index *int, id, name, args string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{Index: index, ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}})
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestCollect(t *testing.T) {
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		{Role: schema.Assistant, ReasoningContent: "先查"},
+		schema.AssistantMessage("好的", nil),
+		nil,
+		// Index 1 先到，参数分两段；没有 Index 的调用单独成组
+		toolChunk(intPtr(1), "call_b", "weather", `{"city":`),
+		toolChunk(intPtr(0), "call_a", "search", `{"q":"eino"}`),
+		toolChunk(intPtr(1), "", "", `"上海"}`),
+		toolChunk(nil, "call_c", "clock", `{}`),
+		{Role: schema.Assistant, ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}},
+	})
+	var chunks int
+	m, err := Collect(sr, func(chunk *schema.Message) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nil chunk 不会交给 onChunk
+	if chunks != 7 {
+		t.Errorf("onChunk called %d times, want 7", chunks)
+	}
+	if m.Content != "好的" || m.ReasoningContent != "先查" {
+		t.Errorf("content = %q, reasoning = %q", m.Content, m.ReasoningContent)
+	}
+
+	want := []struct{ id, name, args string }{
+		{"call_c", "clock", `{}`},
+		{"call_a", "search", `{"q":"eino"}`},
+		{"call_b", "weather", `{"city":"上海"}`},
+	}
+	if len(m.ToolCalls) != len(want) {
+		t.Fatalf("got %d tool calls, want %d: %+v", len(m.ToolCalls), len(want), m.ToolCalls)
+	}
+	for i, w := range want {
+		tc := m.ToolCalls[i]
+		if tc.ID != w.id || tc.Function.Name != w.name || tc.Function.Arguments != w.args {
+			t.Errorf("tool call %d = %s %s %s, want %s %s %s", i, tc.ID, tc.Function.Name, tc.Function.Arguments, w.id, w.name, w.args)
+		}
+	}
+	if u := Usage(m); u == nil || u.TotalTokens != 15 {
+		t.Errorf("usage = %+v, want 15 total tokens", u)
+	}
+}
+
+// TestCollectStopsOnChunkError onChunk 返回错误时停止读取并原样返回
+func TestCollectStopsOnChunkError(t *testing.T) {
+	stop := errors.New("stop")
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage("a", nil),
+		schema.AssistantMessage("b", nil),
+	})
+	var seen int
+	_, err := Collect(sr, func(*schema.Message) error {
+		seen++
+		return stop
+	})
+	if !errors.Is(err, stop) || seen != 1 {
+		t.Fatalf("err = %v after %d chunks, want %v after 1", err, seen, stop)
+	}
+}
+
+func TestCollectStreamError(t *testing.T) {
+	boom := errors.New("boom")
+	sr, sw := schema.Pipe[*schema.Message](2)
+	sw.Send(schema.AssistantMessage("a", nil), nil)
+	sw.Send(nil, boom)
+	sw.Close()
+	if _, err := Collect(sr, nil); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestCollectEmpty(t *testing.T) {
+	m, err := Collect(schema.StreamReaderFromArray([]*schema.Message{}), nil)
+	if err != nil || m != nil {
+		t.Fatalf("Collect(empty) = %v, %v, want nil, nil", m, err)
+	}
+}
+
+// TestEventMessage 读取流式事件后事件中的流仍然完整，可以再读一次
+func TestEventMessage(t *testing.T) {
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage("你", nil),
+		schema.AssistantMessage("好", nil),
+	})
+	event := adk.EventFromMessage(nil, sr, schema.Assistant, "")
+
+	var got []string
+	m, err := EventMessage(event, func(chunk *schema.Message) error {
+		got = append(got, chunk.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "你好" || len(got) != 2 || got[0] != "你" || got[1] != "好" {
+		t.Fatalf("message = %q, chunks = %q", m.Content, got)
+	}
+
+	again, err := EventMessage(event, nil)
+	if err != nil || again.Content != "你好" {
+		t.Fatalf("second EventMessage = %v, %v", again, err)
+	}
+	if gm, _, err := adk.GetMessage(event); err != nil || gm.Content != "你好" {
+		t.Fatalf("GetMessage = %v, %v", gm, err)
+	}
+}
+
+// TestEventMessageNonStreaming 非流式消息直接返回，不调用 onChunk；没有消息时返回 nil
+func TestEventMessageNonStreaming(t *testing.T) {
+	msg := schema.AssistantMessage("完整回复", nil)
+	m, err := EventMessage(adk.EventFromMessage(msg, nil, schema.Assistant, ""), func(*schema.Message) error {
+		t.Fatal("onChunk called for a non-streaming message")
+		return nil
+	})
+	if err != nil || m != msg {
+		t.Fatalf("EventMessage = %v, %v, want the original message", m, err)
+	}
+
+	m, err = EventMessage(&adk.AgentEvent{Action: &adk.AgentAction{Exit: true}}, nil)
+	if err != nil || m != nil {
+		t.Fatalf("EventMessage(no output) = %v, %v, want nil, nil", m, err)
+	}
+}
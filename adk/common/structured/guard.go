@@ -0,0 +1,121 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// NewToolGuard 包装智能体使用的 ChatModel，为由模型自行决定何时调用的结果工具加上校验和重新提问
+//
+// 模型调用名为 cfg.Name 的工具时按 T 的 JSON Schema 和 Validator 校验参数，不通过时把回复和错误原因
+// 追加到对话中重新调用模型，最多 MaxRetries 次；仍不通过时原样返回最后一次回复，由工具自行兜底。
+// 没有调用该工具的回复直接返回。流式调用会先收完整个回复再校验，校验通过后再按原来的分片输出
+func NewToolGuard[T any](cm model.ToolCallingChatModel, cfg *Config) (model.ToolCallingChatModel, error) {
+	e := newExtractor[T](cfg)
+	if e.cfg.Mode != ModeToolCall {
+		return nil, errors.New("tool guard only supports ModeToolCall")
+	}
+	e.cm = cm
+	return &toolGuard[T]{e: e}, nil
+}
+
+type toolGuard[T any] struct {
+	e *Extractor[T] // e.cm 为被包装的模型
+}
+
+// invalid 返回回复中结果工具的调用 ID 和校验错误，没有调用结果工具或校验通过时返回 nil
+func (g *toolGuard[T]) invalid(reply *schema.Message) (string, *ValidationError) {
+	for _, tc := range reply.ToolCalls {
+		if tc.Function.Name != g.e.cfg.Name {
+			continue
+		}
+		if _, vErr := g.e.decode(tc.Function.Arguments); vErr != nil {
+			return tc.ID, vErr
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+func (g *toolGuard[T]) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msgs := append([]*schema.Message(nil), input...)
+	for attempt := 0; ; attempt++ {
+		reply, err := g.e.cm.Generate(ctx, msgs, opts...)
+		if err != nil {
+			return nil, err
+		}
+		callID, vErr := g.invalid(reply)
+		if vErr == nil || attempt >= g.e.retries() {
+			return reply, nil
+		}
+		msgs = append(msgs, g.e.feedback(reply, callID, vErr)...)
+	}
+}
+
+func (g *toolGuard[T]) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msgs := append([]*schema.Message(nil), input...)
+	for attempt := 0; ; attempt++ {
+		sr, err := g.e.cm.Stream(ctx, msgs, opts...)
+		if err != nil {
+			return nil, err
+		}
+		chunks, err := collect(sr)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := schema.ConcatMessages(chunks)
+		if err != nil {
+			return nil, err
+		}
+		callID, vErr := g.invalid(reply)
+		if vErr == nil || attempt >= g.e.retries() {
+			return schema.StreamReaderFromArray(chunks), nil
+		}
+		msgs = append(msgs, g.e.feedback(reply, callID, vErr)...)
+	}
+}
+
+func (g *toolGuard[T]) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := g.e.cm.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	e := *g.e
+	e.cm = inner
+	return &toolGuard[T]{e: &e}, nil
+}
+
+// GetType 沿用被包装模型的类型，框架在外面包回调时统计仍记在原模型名下
+func (g *toolGuard[T]) GetType() string {
+	if typ, ok := components.GetType(g.e.cm); ok {
+		return typ
+	}
+	return "ToolGuard"
+}
+
+// IsCallbacksEnabled 与被包装的模型一致：下游自带回调时每次重新提问都会触发回调，
+// 否则由框架在外面包一层，整个校验过程只触发一次
+func (g *toolGuard[T]) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(g.e.cm)
+}
+
+// collect 读完整条流
+func collect(sr *schema.StreamReader[*schema.Message]) ([]*schema.Message, error) {
+	defer sr.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
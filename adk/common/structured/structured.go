@@ -0,0 +1,264 @@
+package structured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// Mode 让模型输出结构化结果的方式
+type Mode string
+
+const (
+	// ModeToolCall 把 schema 作为工具参数，强制模型调用该工具，从工具参数中取出结果
+	ModeToolCall Mode = "tool_call"
+	// ModePrompt 把 schema 写进提示词，要求模型直接回复 JSON，适合不支持强制工具调用的模型
+	ModePrompt Mode = "prompt"
+)
+
+// Validator 由结果类型实现，用于 schema 之外的业务校验（例如字段之间的约束）
+type Validator interface {
+	Validate() error
+}
+
+// NoRetries 作为 Config.MaxRetries 时校验不通过不再重新提问
+const NoRetries = -1
+
+// defaultMaxRetries Config.MaxRetries 为 0 时的重试次数
+const defaultMaxRetries = 2
+
+// Config 结构化输出的配置，0 值字段使用默认值
+type Config struct {
+	Mode        Mode   // 默认 ModeToolCall
+	Name        string // 工具名称，默认 submit_result
+	Description string // 工具描述，同时用作提示词中对结果的说明
+	MaxRetries  int    // 校验不通过时带着错误重新提问的次数，0 使用默认值 2，不重试时设为 NoRetries
+}
+
+// ValidationError 模型的回复不符合 schema
+type ValidationError struct {
+	Raw    string   // 模型回复的原始内容
+	Issues []string // 不通过的原因
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid structured output: " + strings.Join(e.Issues, "; ")
+}
+
+// Extractor 让模型按 T 的 JSON Schema 输出结果并解析成 T
+//
+// T 一般为结构体，字段的约束通过 jsonschema 标签声明，例如：
+//
+//	type Critique struct {
+//		Verdict string   `json:"verdict" jsonschema:"enum=pass,enum=revise,description=评审结论"`
+//		Score   int      `json:"score" jsonschema:"minimum=0,maximum=10"`
+//		Issues  []string `json:"issues" jsonschema:"description=需要改进的问题"`
+//	}
+type Extractor[T any] struct {
+	cm     model.ToolCallingChatModel // ModeToolCall 下已绑定结果工具
+	cfg    Config
+	schema *jsonschema.Schema
+	tool   *schema.ToolInfo
+}
+
+// NewExtractor 按 T 生成 JSON Schema 并创建 Extractor
+func NewExtractor[T any](cm model.ToolCallingChatModel, cfg *Config) (*Extractor[T], error) {
+	e := newExtractor[T](cfg)
+	switch e.cfg.Mode {
+	case ModeToolCall:
+		bound, err := cm.WithTools([]*schema.ToolInfo{e.tool})
+		if err != nil {
+			return nil, fmt.Errorf("bind result tool: %w", err)
+		}
+		e.cm = bound
+	case ModePrompt:
+		e.cm = cm
+	default:
+		return nil, fmt.Errorf("unknown structured output mode %q", e.cfg.Mode)
+	}
+	return e, nil
+}
+
+// newExtractor 填充默认配置并生成 schema，不绑定模型
+func newExtractor[T any](cfg *Config) *Extractor[T] {
+	c := Config{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Mode == "" {
+		c.Mode = ModeToolCall
+	}
+	if c.Name == "" {
+		c.Name = "submit_result"
+	}
+	if c.Description == "" {
+		c.Description = "提交最终结果"
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+
+	r := &jsonschema.Reflector{Anonymous: true, DoNotReference: true}
+	js := r.Reflect(new(T))
+	js.Version = ""
+	return &Extractor[T]{
+		cfg:    c,
+		schema: js,
+		tool:   &schema.ToolInfo{Name: c.Name, Desc: c.Description, ParamsOneOf: schema.NewParamsOneOfByJSONSchema(js)},
+	}
+}
+
+// retries 校验不通过时最多重新提问的次数
+func (e *Extractor[T]) retries() int {
+	return max(e.cfg.MaxRetries, 0)
+}
+
+// Schema 返回 T 对应的 JSON Schema
+func (e *Extractor[T]) Schema() *jsonschema.Schema {
+	return e.schema
+}
+
+// Generate 调用模型并返回解析、校验后的结果
+//
+// 校验不通过时把模型的回复和错误原因追加到对话中重新提问，最多 MaxRetries 次；
+// 仍不通过时返回的错误包装了最后一次的 *ValidationError
+func (e *Extractor[T]) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*T, error) {
+	var msgs []*schema.Message
+	switch e.cfg.Mode {
+	case ModeToolCall:
+		msgs = append(msgs, input...)
+		opts = append(opts, model.WithToolChoice(schema.ToolChoiceForced, e.cfg.Name))
+	case ModePrompt:
+		msgs = withInstruction(input, e.promptInstruction())
+	}
+
+	var lastErr *ValidationError
+	for attempt := 0; attempt <= e.retries(); attempt++ {
+		reply, err := e.cm.Generate(ctx, msgs, opts...)
+		if err != nil {
+			return nil, err
+		}
+		raw, callID := e.extract(reply)
+		v, vErr := e.decode(raw)
+		if vErr == nil {
+			return v, nil
+		}
+		lastErr = vErr
+		msgs = append(msgs, e.feedback(reply, callID, vErr)...)
+	}
+	return nil, fmt.Errorf("structured output still invalid after %d attempts: %w", e.retries()+1, lastErr)
+}
+
+// extract 取出回复中的 JSON：ModeToolCall 取结果工具的参数，ModePrompt 取消息正文
+func (e *Extractor[T]) extract(reply *schema.Message) (raw, callID string) {
+	if e.cfg.Mode == ModeToolCall {
+		for _, tc := range reply.ToolCalls {
+			if tc.Function.Name == e.cfg.Name {
+				return tc.Function.Arguments, tc.ID
+			}
+		}
+		return "", ""
+	}
+	return stripCodeFence(reply.Content), ""
+}
+
+// decode 依次做 JSON 语法、schema、Validator 三层校验
+func (e *Extractor[T]) decode(raw string) (*T, *ValidationError) {
+	if strings.TrimSpace(raw) == "" {
+		if e.cfg.Mode == ModeToolCall {
+			return nil, &ValidationError{Issues: []string{fmt.Sprintf("must call tool %q with the result", e.cfg.Name)}}
+		}
+		return nil, &ValidationError{Issues: []string{"reply must be a JSON object"}}
+	}
+	var generic any
+	if err := json.Unmarshal([]byte(raw), &generic); err != nil {
+		return nil, &ValidationError{Raw: raw, Issues: []string{"invalid JSON: " + err.Error()}}
+	}
+	if issues := validate(e.schema, generic, ""); len(issues) > 0 {
+		return nil, &ValidationError{Raw: raw, Issues: issues}
+	}
+
+	v := new(T)
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return nil, &ValidationError{Raw: raw, Issues: []string{err.Error()}}
+	}
+	if val, ok := any(v).(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return nil, &ValidationError{Raw: raw, Issues: []string{err.Error()}}
+		}
+	}
+	return v, nil
+}
+
+// feedback 构造重新提问的消息：先放回模型上一次的回复，再给出错误原因
+// 回复中有工具调用时必须用对应的工具消息回应，否则部分模型会拒绝请求
+func (e *Extractor[T]) feedback(reply *schema.Message, callID string, vErr *ValidationError) []*schema.Message {
+	text := "结果未通过校验，请修正以下问题后重新提交：\n- " + strings.Join(vErr.Issues, "\n- ")
+	out := []*schema.Message{reply}
+	if len(reply.ToolCalls) == 0 {
+		return append(out, schema.UserMessage(text))
+	}
+	for _, tc := range reply.ToolCalls {
+		content := "忽略：请只调用 " + e.cfg.Name
+		if tc.ID == callID {
+			content = text
+		}
+		out = append(out, schema.ToolMessage(content, tc.ID, schema.WithToolName(tc.Function.Name)))
+	}
+	if callID == "" {
+		out = append(out, schema.UserMessage(text))
+	}
+	return out
+}
+
+// withInstruction 把结果说明合并进对话开头的系统消息，没有系统消息时在开头插入一条
+// 不修改 input 中的消息
+func withInstruction(input []*schema.Message, instruction string) []*schema.Message {
+	msgs := make([]*schema.Message, 0, len(input)+1)
+	if len(input) > 0 && input[0].Role == schema.System {
+		merged := *input[0]
+		merged.Content = strings.TrimRight(merged.Content, "\n") + "\n\n" + instruction
+		return append(append(msgs, &merged), input[1:]...)
+	}
+	return append(append(msgs, schema.SystemMessage(instruction)), input...)
+}
+
+func (e *Extractor[T]) promptInstruction() string {
+	bs, _ := json.MarshalIndent(e.schema, "", "  ")
+	return fmt.Sprintf("%s。只回复一个符合以下 JSON Schema 的 JSON 对象，不要输出任何其他内容：\n%s", e.cfg.Description, bs)
+}
+
+// stripCodeFence 去掉模型常加的 ```json 代码块标记，并截取最外层的 JSON 对象
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	start, end := strings.IndexByte(s, '{'), strings.LastIndexByte(s, '}')
+	if start < 0 || end < start {
+		return s
+	}
+	return s[start : end+1]
+}
+
+// Generate 用一次性的 Extractor 生成结构化结果，适合只调用一次的场景
+func Generate[T any](ctx context.Context, cm model.ToolCallingChatModel, input []*schema.Message, cfg *Config,
+	opts ...model.Option) (*T, error) {
+	e, err := NewExtractor[T](cm, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return e.Generate(ctx, input, opts...)
+}
+
+// IsValidationError 判断 err 是否由模型回复不符合 schema 导致
+func IsValidationError(err error) bool {
+	var vErr *ValidationError
+	return errors.As(err, &vErr)
+}
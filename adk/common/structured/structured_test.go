@@ -0,0 +1,264 @@
+package structured
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	cmodel "eino-learn/adk/common/model"
+)
+
+// recordingModel 记录每次调用模型时的输入
+type recordingModel struct {
+	model.ToolCallingChatModel
+	inputs *[][]*schema.Message
+}
+
+func (m *recordingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	*m.inputs = append(*m.inputs, input)
+	return m.ToolCallingChatModel.Generate(ctx, input, opts...)
+}
+
+func (m *recordingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	*m.inputs = append(*m.inputs, input)
+	return m.ToolCallingChatModel.Stream(ctx, input, opts...)
+}
+
+func (m *recordingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.ToolCallingChatModel.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingModel{ToolCallingChatModel: inner, inputs: m.inputs}, nil
+}
+
+// newFake 返回按 responses 依次回复的 fake 模型和记录的输入
+func newFake(t *testing.T, responses ...*cmodel.FakeResponse) (model.ToolCallingChatModel, *[][]*schema.Message) {
+	t.Helper()
+	cm, err := cmodel.NewFakeChatModel(&cmodel.FakeScript{Agents: map[string][]*cmodel.FakeResponse{"a": responses}}, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs [][]*schema.Message
+	return &recordingModel{ToolCallingChatModel: cm, inputs: &inputs}, &inputs
+}
+
+// submit 调用结果工具的回复
+func submit(name, args string) *cmodel.FakeResponse {
+	return &cmodel.FakeResponse{ToolCalls: []*cmodel.FakeToolCall{{Name: name, Arguments: args}}}
+}
+
+func TestExtractorRetry(t *testing.T) {
+	ctx := context.Background()
+	cm, inputs := newFake(t,
+		submit("submit_result", `{"verdict": "pass", "score": 11}`),
+		submit("submit_result", `{"verdict": "revise", "score": 3}`),
+		submit("submit_result", `{"verdict": "revise", "score": 3, "issues": ["缺少示例"]}`),
+	)
+	e, err := NewExtractor[review](cm, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []*schema.Message{schema.UserMessage("评审")}
+	r, err := e.Generate(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Verdict != "revise" || r.Score != 3 || len(r.Issues) != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+
+	// 每次重新提问都带上模型上一次的回复和针对该工具调用的错误原因
+	if len(*inputs) != 3 {
+		t.Fatalf("model called %d times, want 3", len(*inputs))
+	}
+	for i, want := range []string{"$.score: must be <= 10", "revise requires issues"} {
+		msgs := (*inputs)[i+1]
+		if len(msgs) != len(input)+2*(i+1) {
+			t.Fatalf("attempt %d: %d messages, want %d", i+2, len(msgs), len(input)+2*(i+1))
+		}
+		reply, fb := msgs[len(msgs)-2], msgs[len(msgs)-1]
+		if fb.Role != schema.Tool || fb.ToolCallID != reply.ToolCalls[0].ID || !strings.Contains(fb.Content, want) {
+			t.Errorf("attempt %d feedback = %+v, want tool message containing %q", i+2, fb, want)
+		}
+	}
+	if len(input) != 1 {
+		t.Fatalf("input modified: %+v", input)
+	}
+}
+
+func TestExtractorRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantCalls  int
+	}{
+		{"default", 0, 3},
+		{"no retries", NoRetries, 1},
+		{"one retry", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var responses []*cmodel.FakeResponse
+			for range tt.wantCalls {
+				// 没有调用结果工具也算校验不通过
+				responses = append(responses, &cmodel.FakeResponse{Content: "好的"})
+			}
+			cm, inputs := newFake(t, responses...)
+			_, err := Generate[review](ctx, cm, nil, &Config{MaxRetries: tt.maxRetries})
+			if !IsValidationError(err) {
+				t.Fatalf("err = %v, want validation error", err)
+			}
+			if !strings.Contains(err.Error(), `must call tool "submit_result"`) {
+				t.Fatalf("err = %v", err)
+			}
+			if len(*inputs) != tt.wantCalls {
+				t.Fatalf("model called %d times, want %d", len(*inputs), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExtractorPromptMode(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		input []*schema.Message
+		// 第一条系统消息应以 wantPrefix 开头，消息数为 wantLen
+		wantPrefix string
+		wantLen    int
+	}{
+		{"merge into system prompt", []*schema.Message{schema.SystemMessage("你是评审员"), schema.UserMessage("评审")}, "你是评审员\n\n评审结果", 2},
+		{"prepend system prompt", []*schema.Message{schema.UserMessage("评审")}, "评审结果", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, inputs := newFake(t, &cmodel.FakeResponse{Content: "结果如下：\n```json\n{\"verdict\": \"pass\", \"score\": 8}\n```"})
+			r, err := Generate[review](ctx, cm, tt.input, &Config{Mode: ModePrompt, Description: "评审结果"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Verdict != "pass" || r.Score != 8 {
+				t.Fatalf("result = %+v", r)
+			}
+			msgs := (*inputs)[0]
+			if len(msgs) != tt.wantLen || msgs[0].Role != schema.System || !strings.HasPrefix(msgs[0].Content, tt.wantPrefix) {
+				t.Fatalf("messages = %+v", msgs)
+			}
+			if !strings.Contains(msgs[0].Content, `"score"`) {
+				t.Fatalf("system prompt does not contain the schema: %s", msgs[0].Content)
+			}
+			if msgs[len(msgs)-1].Role != schema.User {
+				t.Fatalf("last message = %+v, want the user message", msgs[len(msgs)-1])
+			}
+			// 不修改调用方的消息
+			if tt.input[0].Role == schema.System && tt.input[0].Content != "你是评审员" {
+				t.Fatalf("input system message modified: %q", tt.input[0].Content)
+			}
+		})
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"```\n{\"a\": {\"b\": 2}}\n```\n", `{"a": {"b": 2}}`},
+		{"结果：{\"a\": 1}。", `{"a": 1}`},
+		{"  没有 JSON  ", "没有 JSON"},
+		{"} 顺序颠倒 {", "} 顺序颠倒 {"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := stripCodeFence(tt.in); got != tt.want {
+			t.Errorf("stripCodeFence(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToolGuard(t *testing.T) {
+	ctx := context.Background()
+	cm, inputs := newFake(t,
+		// 没有调用结果工具的回复直接返回
+		&cmodel.FakeResponse{Content: "先看看"},
+		submit("submit_critique", `{"verdict": "pass", "score": 42}`),
+		submit("submit_critique", `{"verdict": "pass", "score": 9}`),
+		// 流式调用
+		submit("submit_critique", `{"verdict": "revise", "score": 2}`),
+		&cmodel.FakeResponse{Content: "第二次", ToolCalls: []*cmodel.FakeToolCall{{Name: "submit_critique", Arguments: `{"verdict": "revise", "score": 2, "issues": ["x"]}`}}},
+	)
+	g, err := NewToolGuard[review](cm, &Config{Name: "submit_critique"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err = g.WithTools([]*schema.ToolInfo{{Name: "submit_critique"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := g.Generate(ctx, nil)
+	if err != nil || msg.Content != "先看看" {
+		t.Fatalf("generate = %+v, %v", msg, err)
+	}
+	msg, err = g.Generate(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := msg.ToolCalls[0].Function.Arguments; !strings.Contains(args, `"score": 9`) {
+		t.Fatalf("guarded reply = %s, want the corrected critique", args)
+	}
+	if n := len((*inputs)[2]); n != 2 {
+		t.Fatalf("retry has %d messages, want the reply and the feedback", n)
+	}
+
+	sr, err := g.Stream(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	msg, err = schema.ConcatMessages(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "第二次" || !strings.Contains(msg.ToolCalls[0].Function.Arguments, `"issues"`) {
+		t.Fatalf("streamed reply = %+v", msg)
+	}
+
+	if _, err := NewToolGuard[review](cm, &Config{Mode: ModePrompt}); err == nil {
+		t.Fatal("want error for ModePrompt")
+	}
+}
+
+func TestToolGuardGivesUp(t *testing.T) {
+	cm, inputs := newFake(t, submit("submit_critique", `{"verdict": "pass", "score": 42}`))
+	g, err := NewToolGuard[review](cm, &Config{Name: "submit_critique", MaxRetries: NoRetries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err = g.WithTools([]*schema.ToolInfo{{Name: "submit_critique"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重试用尽后原样返回，由工具自行处理
+	msg, err := g.Generate(context.Background(), nil)
+	if err != nil || !strings.Contains(msg.ToolCalls[0].Function.Arguments, "42") || len(*inputs) != 1 {
+		t.Fatalf("generate = %+v, %v after %d calls", msg, err, len(*inputs))
+	}
+}
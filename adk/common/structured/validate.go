@@ -0,0 +1,163 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eino-contrib/jsonschema"
+)
+
+// validate 按 JSON Schema 校验解析后的 JSON 值，返回所有不通过的原因
+//
+// 只实现了结构体反射出的 schema 会用到的关键字：type、properties、required、additionalProperties、
+// items、enum、const、minimum/maximum、minLength/maxLength、pattern、minItems/maxItems
+func validate(s *jsonschema.Schema, v any, path string) []string {
+	if s == nil {
+		return nil
+	}
+	var issues []string
+	addf := func(format string, args ...any) {
+		issues = append(issues, fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...)))
+	}
+
+	if types := schemaTypes(s); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return typeMatches(t, v) }) {
+		addf("expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+		return issues
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, v) }) {
+		addf("must be one of %s", mustJSON(s.Enum))
+	}
+	if s.Const != nil && !jsonEqual(s.Const, v) {
+		addf("must be %s", mustJSON(s.Const))
+	}
+
+	switch x := v.(type) {
+	case float64:
+		if min, ok := number(s.Minimum); ok && x < min {
+			addf("must be >= %v", min)
+		}
+		if max, ok := number(s.Maximum); ok && x > max {
+			addf("must be <= %v", max)
+		}
+		if min, ok := number(s.ExclusiveMinimum); ok && x <= min {
+			addf("must be > %v", min)
+		}
+		if max, ok := number(s.ExclusiveMaximum); ok && x >= max {
+			addf("must be < %v", max)
+		}
+	case string:
+		n := uint64(utf8.RuneCountInString(x))
+		if s.MinLength != nil && n < *s.MinLength {
+			addf("length must be >= %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			addf("length must be <= %d", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(x) {
+				addf("must match pattern %q", s.Pattern)
+			}
+		}
+	case []any:
+		n := uint64(len(x))
+		if s.MinItems != nil && n < *s.MinItems {
+			addf("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && n > *s.MaxItems {
+			addf("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range x {
+			issues = append(issues, validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				addf("missing required field %q", name)
+			}
+		}
+		for name, fv := range x {
+			var ps *jsonschema.Schema
+			if s.Properties != nil {
+				ps, _ = s.Properties.Get(name)
+			}
+			if ps == nil {
+				if s.AdditionalProperties == jsonschema.FalseSchema {
+					addf("unknown field %q", name)
+				}
+				continue
+			}
+			issues = append(issues, validate(ps, fv, path+"."+name)...)
+		}
+	}
+	return issues
+}
+
+func schemaTypes(s *jsonschema.Schema) []string {
+	if len(s.TypeEnhanced) > 0 {
+		return s.TypeEnhanced
+	}
+	if s.Type != "" {
+		return []string{s.Type}
+	}
+	return nil
+}
+
+func typeMatches(t string, v any) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		return jsonType(v) == "number"
+	default:
+		return jsonType(v) == t
+	}
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func number(n json.Number) (float64, bool) {
+	if n == "" {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// jsonEqual 比较两个值序列化后是否相同，用于 enum 和 const，避免 int 与 float64 的类型差异
+func jsonEqual(a, b any) bool {
+	return mustJSON(a) == mustJSON(b)
+}
+
+func mustJSON(v any) string {
+	bs, _ := json.Marshal(v)
+	return string(bs)
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return "$" + path
+}
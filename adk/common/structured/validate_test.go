@@ -0,0 +1,89 @@
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/eino-contrib/jsonschema"
+)
+
+// review 测试用的结果类型
+type review struct {
+	Verdict string   `json:"verdict" jsonschema:"enum=pass,enum=revise"`
+	Score   int      `json:"score" jsonschema:"minimum=0,maximum=10"`
+	Issues  []string `json:"issues,omitempty" jsonschema:"maxItems=2"`
+	Summary string   `json:"summary,omitempty" jsonschema:"minLength=2,maxLength=20,pattern=^[^!]*$"`
+}
+
+func (r *review) Validate() error {
+	if r.Verdict == "revise" && len(r.Issues) == 0 {
+		return errors.New("revise requires issues")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	s := newExtractor[review](nil).schema
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"valid", `{"verdict": "pass", "score": 9}`, nil},
+		{"valid with optional fields", `{"verdict": "revise", "score": 3, "issues": ["a", "b"], "summary": "需要改进"}`, nil},
+		{"not an object", `[1]`, []string{"$: expected object, got array"}},
+		{"missing required", `{"verdict": "pass"}`, []string{`$: missing required field "score"`}},
+		{"unknown field", `{"verdict": "pass", "score": 1, "extra": true}`, []string{`$: unknown field "extra"`}},
+		{"wrong type", `{"verdict": "pass", "score": "9"}`, []string{"$.score: expected integer, got string"}},
+		{"fractional integer", `{"verdict": "pass", "score": 9.5}`, []string{"$.score: expected integer, got number"}},
+		{"above maximum", `{"verdict": "pass", "score": 11}`, []string{"$.score: must be <= 10"}},
+		{"below minimum", `{"verdict": "pass", "score": -1}`, []string{"$.score: must be >= 0"}},
+		{"enum", `{"verdict": "maybe", "score": 5}`, []string{`$.verdict: must be one of ["pass","revise"]`}},
+		{"item type", `{"verdict": "pass", "score": 5, "issues": [1]}`, []string{"$.issues[0]: expected string, got number"}},
+		{"max items", `{"verdict": "pass", "score": 5, "issues": ["a", "b", "c"]}`, []string{"$.issues: must have at most 2 items"}},
+		// 长度按字符计，不按字节计
+		{"min length", `{"verdict": "pass", "score": 5, "summary": "好"}`, []string{"$.summary: length must be >= 2"}},
+		{"max length", `{"verdict": "pass", "score": 5, "summary": "一二三四五六七八九十一二三四五六七八九十一"}`,
+			[]string{"$.summary: length must be <= 20"}},
+		{"pattern", `{"verdict": "pass", "score": 5, "summary": "好!"}`, []string{`$.summary: must match pattern "^[^!]*$"`}},
+		{"multiple issues", `{"verdict": "maybe", "score": 20}`, []string{
+			`$.verdict: must be one of ["pass","revise"]`,
+			"$.score: must be <= 10",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v any
+			if err := json.Unmarshal([]byte(tt.raw), &v); err != nil {
+				t.Fatal(err)
+			}
+			got := validate(s, v, "")
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Fatalf("validate(%s) = %q, want %q", tt.raw, got, want)
+			}
+		})
+	}
+}
+
+func TestValidateConstAndNullable(t *testing.T) {
+	s := &jsonschema.Schema{TypeEnhanced: []string{"string", "null"}, Const: "x"}
+	for raw, want := range map[string]int{
+		`"x"`:  0,
+		`null`: 1, // 类型允许 null，但不等于 const
+		`"y"`:  1,
+		`1`:    1,
+	} {
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			t.Fatal(err)
+		}
+		if got := validate(s, v, ""); len(got) != want {
+			t.Errorf("validate(%s) = %q, want %d issues", raw, got, want)
+		}
+	}
+}
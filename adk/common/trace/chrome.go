@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// chromeEvent Chrome trace event 格式中的一个事件，见
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type chromeEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Ph    string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   int64          `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace 把 Span 转换为 Chrome trace event JSON，可以在 chrome://tracing 或 https://ui.perfetto.dev 中打开
+// 每条链路是一个进程；同一线程中的事件必须严格嵌套，并行的 Span（例如并行调用的工具）会分到不同的线程
+func WriteChromeTrace(w io.Writer, spans []*SpanRecord) error {
+	events := make([]chromeEvent, 0, len(spans)*2)
+	for pid, group := range groupByTrace(spans) {
+		pid++
+		events = append(events, chromeEvent{
+			Name: "process_name", Ph: "M", PID: pid,
+			Args: map[string]any{"name": group[0].Name + " " + group[0].TraceID},
+		})
+		for _, lane := range assignLanes(group) {
+			s := lane.span
+			args := map[string]any{"span_id": s.SpanID}
+			for k, v := range s.Attributes {
+				args[k] = v
+			}
+			if s.Input != "" {
+				args["input"] = s.Input
+			}
+			if s.Output != "" {
+				args["output"] = s.Output
+			}
+			if s.Error != "" {
+				args["error"] = s.Error
+			}
+			events = append(events, chromeEvent{
+				Name: s.Name, Cat: string(s.Kind), Ph: "X",
+				TS: s.Start.UnixMicro(), Dur: max(s.End.Sub(s.Start).Microseconds(), 1),
+				PID: pid, TID: lane.tid, Args: args,
+			})
+			for _, e := range s.Events {
+				events = append(events, chromeEvent{
+					Name: e.Name, Cat: string(s.Kind), Ph: "i", Scope: "t",
+					TS: e.Time.UnixMicro(), PID: pid, TID: lane.tid, Args: e.Attributes,
+				})
+			}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(map[string]any{"traceEvents": events, "displayTimeUnit": "ms"})
+}
+
+// groupByTrace 按链路分组，组内按开始时间排序，根 Span 排在最前面，各组按开始时间排序
+func groupByTrace(spans []*SpanRecord) [][]*SpanRecord {
+	index := map[string]int{}
+	var groups [][]*SpanRecord
+	for _, s := range spans {
+		i, ok := index[s.TraceID]
+		if !ok {
+			i = len(groups)
+			index[s.TraceID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	for _, g := range groups {
+		// 开始时间相同时先放持续时间长的，父 Span 总在子 Span 之前
+		sort.SliceStable(g, func(i, j int) bool {
+			if !g[i].Start.Equal(g[j].Start) {
+				return g[i].Start.Before(g[j].Start)
+			}
+			return g[i].End.After(g[j].End)
+		})
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i][0].Start.Before(groups[j][0].Start) })
+	return groups
+}
+
+type laneSpan struct {
+	span *SpanRecord
+	tid  int
+}
+
+// assignLanes 为按开始时间排序的 Span 分配线程，优先放在父 Span 所在的线程
+// 线程中保存尚未结束的 Span 的结束时间，新的 Span 只能放在完全包含它的 Span 之上或空闲的线程中
+func assignLanes(spans []*SpanRecord) []laneSpan {
+	var lanes [][]*SpanRecord
+	laneOf := map[string]int{}
+	fits := func(l int, s *SpanRecord) bool {
+		stack := lanes[l]
+		for len(stack) > 0 && !stack[len(stack)-1].End.After(s.Start) {
+			stack = stack[:len(stack)-1]
+		}
+		lanes[l] = stack
+		return len(stack) == 0 || !stack[len(stack)-1].End.Before(s.End)
+	}
+
+	out := make([]laneSpan, 0, len(spans))
+	for _, s := range spans {
+		l := -1
+		if pl, ok := laneOf[s.ParentID]; ok && fits(pl, s) {
+			l = pl
+		}
+		for i := 0; l < 0 && i < len(lanes); i++ {
+			if fits(i, s) {
+				l = i
+			}
+		}
+		if l < 0 {
+			l = len(lanes)
+			lanes = append(lanes, nil)
+		}
+		lanes[l] = append(lanes[l], s)
+		laneOf[s.SpanID] = l
+		out = append(out, laneSpan{span: s, tid: l + 1})
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/eino/callbacks"
+	"gopkg.in/yaml.v3"
+)
+
+// 后端类型
+const (
+	BackendCozeLoop = "cozeloop"
+	BackendOTel     = "otel"
+	BackendFile     = "file"
+	BackendNoop     = "noop"
+)
+
+// Config 链路追踪的配置，可以同时启用多个后端
+//
+//	backends:
+//	  - type: cozeloop
+//	    workspace_id: ${COZELOOP_WORKSPACE_ID}
+//	    api_token: ${COZELOOP_API_TOKEN}
+//	  - type: otel
+//	    exporter: otlp
+//	    endpoint: http://localhost:4318
+//	    capture_content: true
+//	  - type: file
+//	    path: traces.jsonl
+type Config struct {
+	Backends []BackendConfig `yaml:"backends"`
+}
+
+// BackendConfig 一个后端的配置，按 Type 使用对应的字段
+type BackendConfig struct {
+	Type string `yaml:"type"`
+
+	// cozeloop
+	WorkspaceID string `yaml:"workspace_id"`
+	APIToken    string `yaml:"api_token"`
+
+	// otel
+	Exporter       string `yaml:"exporter"` // otlp（默认）或 stdout
+	Endpoint       string `yaml:"endpoint"` // OTLP/HTTP 地址，为空时按 OTEL_EXPORTER_OTLP_* 环境变量
+	ServiceName    string `yaml:"service_name"`
+	CaptureContent bool   `yaml:"capture_content"`
+
+	// file
+	Path string `yaml:"path"` // stdout、stderr 或文件路径，默认 traces.jsonl
+}
+
+// LoadConfig 读取 YAML 配置文件，文件中的 ${VAR} 会替换为环境变量，密钥不必写在文件里
+func LoadConfig(path string) (*Config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read trace config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(bs))), &cfg); err != nil {
+		return nil, fmt.Errorf("parse trace config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// ConfigFromEnv 按环境变量生成配置，依次检查：
+//
+//	TRACE_CONFIG=trace.yaml             读取配置文件，见 Config
+//	TRACE_BACKENDS=cozeloop,otel,file   启用的后端，各后端的参数取自下面的环境变量
+//
+// 都未设置时，按各后端自己的环境变量判断是否启用：
+//
+//	COZELOOP_WORKSPACE_ID、COZELOOP_API_TOKEN                CozeLoop
+//	OTEL_TRACES_EXPORTER=otlp|stdout                         OTel，未设置时设置了 OTLP 地址即为 otlp
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318        OTLP/HTTP 地址，其他 OTEL_EXPORTER_OTLP_* 变量同样生效
+//	OTEL_INSTRUMENTATION_GENAI_CAPTURE_MESSAGE_CONTENT=true  OTel 记录消息内容、工具参数和结果
+//	TRACE_FILE=traces.jsonl                                  本地文件
+func ConfigFromEnv() (*Config, error) {
+	if path := os.Getenv("TRACE_CONFIG"); path != "" {
+		return LoadConfig(path)
+	}
+
+	cfg := &Config{}
+	if v := os.Getenv("TRACE_BACKENDS"); v != "" {
+		for _, typ := range strings.Split(v, ",") {
+			typ = strings.ToLower(strings.TrimSpace(typ))
+			switch typ {
+			case BackendCozeLoop:
+				backends := cozeLoopBackendsFromEnv()
+				if len(backends) == 0 {
+					return nil, errors.New("TRACE_BACKENDS includes cozeloop but COZELOOP_WORKSPACE_ID or COZELOOP_API_TOKEN is not set")
+				}
+				cfg.Backends = append(cfg.Backends, backends...)
+			case BackendOTel:
+				backends := otelBackendsFromEnv()
+				if len(backends) == 0 {
+					backends = []BackendConfig{{Type: BackendOTel}}
+				}
+				cfg.Backends = append(cfg.Backends, backends...)
+			case BackendFile:
+				cfg.Backends = append(cfg.Backends, BackendConfig{Type: BackendFile, Path: os.Getenv("TRACE_FILE")})
+			case BackendNoop, "":
+			default:
+				return nil, fmt.Errorf("unknown trace backend %q in TRACE_BACKENDS", typ)
+			}
+		}
+		return cfg, nil
+	}
+
+	cfg.Backends = append(cfg.Backends, cozeLoopBackendsFromEnv()...)
+	cfg.Backends = append(cfg.Backends, otelBackendsFromEnv()...)
+	if path := os.Getenv("TRACE_FILE"); path != "" {
+		cfg.Backends = append(cfg.Backends, BackendConfig{Type: BackendFile, Path: path})
+	}
+	return cfg, nil
+}
+
+// NewTracerFromConfig 创建配置中的所有后端，没有后端时返回 NoopTracer
+func NewTracerFromConfig(ctx context.Context, cfg *Config) (Tracer, error) {
+	var tracers []Tracer
+	for _, b := range cfg.Backends {
+		t, err := newBackend(ctx, b)
+		if err != nil {
+			// 已经创建的后端需要关闭，否则后台的上报协程会一直存在
+			_ = NewMultiTracer(tracers...).Close(ctx)
+			return nil, err
+		}
+		if t != nil {
+			tracers = append(tracers, t)
+		}
+	}
+	return NewMultiTracer(tracers...), nil
+}
+
+func newBackend(ctx context.Context, b BackendConfig) (Tracer, error) {
+	switch strings.ToLower(b.Type) {
+	case BackendCozeLoop:
+		return NewCozeLoopTracer(b.WorkspaceID, b.APIToken)
+	case BackendOTel:
+		return newOTelTracerFromConfig(ctx, b)
+	case BackendFile:
+		path := b.Path
+		if path == "" {
+			path = "traces.jsonl"
+		}
+		return NewFileTracer(path)
+	case BackendNoop:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown trace backend %q", b.Type)
+	}
+}
+
+// Setup 创建配置中的所有后端并注册全局回调，返回的 Tracer 用于手动创建 Span
+// 实现了 CallbackProvider 的后端注册它自己的回调，其他后端共用一个 NewHandler
+func Setup(ctx context.Context, cfg *Config) (Tracer, CloseFn, error) {
+	t, err := NewTracerFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var generic []Tracer
+	backends := []Tracer{t}
+	if m, ok := t.(multiTracer); ok {
+		backends = m
+	}
+	for _, b := range backends {
+		switch b := b.(type) {
+		case NoopTracer:
+		case CallbackProvider:
+			callbacks.AppendGlobalHandlers(b.Handler())
+		default:
+			generic = append(generic, b)
+		}
+	}
+	var h *handler
+	if len(generic) > 0 {
+		h = newHandler(NewMultiTracer(generic...))
+		callbacks.AppendGlobalHandlers(h.handler())
+	}
+
+	return t, func(ctx context.Context) {
+		if h != nil {
+			h.wait()
+		}
+		if err := t.Close(ctx); err != nil {
+			log.Printf("close tracer failed, err: %v", err)
+		}
+	}, nil
+}
+
+// AppendTracingIfConfigured 按 ConfigFromEnv 启用链路追踪后端并注册全局回调，返回关闭函数和启动 Span 的函数
+// 没有配置任何后端时返回空操作函数
+func AppendTracingIfConfigured(ctx context.Context) (closeFn CloseFn, startSpanFn StartSpanFn) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("load trace config failed, err: %v", err)
+	}
+	return appendIfConfigured(ctx, cfg)
+}
+
+func appendIfConfigured(ctx context.Context, cfg *Config) (CloseFn, StartSpanFn) {
+	t, closeFn, err := Setup(ctx, cfg)
+	if err != nil {
+		log.Fatalf("setup tracing failed, err: %v", err)
+	}
+	return closeFn, NewStartSpanFn(t)
+}
@@ -2,8 +2,9 @@ package trace
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"os"
+	"time"
 
 	ccb "github.com/cloudwego/eino-ext/callbacks/cozeloop"
 	"github.com/cloudwego/eino/callbacks"
@@ -15,7 +16,7 @@ import (
 // *** Span 通过context来构建父子关系，通过context来传递链路追踪信息
 // **************************************************************
 
-// CloseFn 关闭 CozeLoop 客户端的函数
+// CloseFn 关闭链路追踪后端的函数
 type CloseFn func(ctx context.Context)
 
 // EndSpanFn 结束一个 Span 的函数，用于设置输出并完成 Span
@@ -38,89 +39,97 @@ type StartSpanFn func(ctx context.Context, name string, input any) (nCtx context
 //
 // 文档地址: https://loop.coze.cn/open/docs/cozeloop/go-sdk
 //
-// 返回值：
-//   - closeFn: 关闭 CozeLoop 客户端的函数
-//   - startSpanFn: 启动链路追踪 Span 的函数
-func AppendCozeLoopCallbackIfConfigured(_ context.Context) (closeFn CloseFn, startSpanFn StartSpanFn) {
-	// 从环境变量读取 CozeLoop 配置
-	wsID := os.Getenv("COZELOOP_WORKSPACE_ID")
-	apiKey := os.Getenv("COZELOOP_API_TOKEN")
-
-	// 如果没有配置环境变量，返回空函数
-	if wsID == "" || apiKey == "" {
-		return func(ctx context.Context) {
-			// 空 close 函数
-		}, buildStartSpanFn(nil) // 传入 nil 表示未配置 CozeLoop
-	}
+// 需要同时启用其他后端时使用 AppendTracingIfConfigured
+func AppendCozeLoopCallbackIfConfigured(ctx context.Context) (closeFn CloseFn, startSpanFn StartSpanFn) {
+	return appendIfConfigured(ctx, &Config{Backends: cozeLoopBackendsFromEnv()})
+}
 
-	// 创建 CozeLoop 客户端
+// NewCozeLoopTracer 创建 CozeLoop 后端
+// 它实现了 CallbackProvider，组件的 Span 由 CozeLoop 官方的 eino 回调记录，模型 Span 的输出中包含思考过程
+func NewCozeLoopTracer(workspaceID, apiToken string) (Tracer, error) {
 	client, err := cozeloop.NewClient(
-		cozeloop.WithWorkspaceID(wsID), // 工作空间 ID
-		cozeloop.WithAPIToken(apiKey),  // API Token
+		cozeloop.WithWorkspaceID(workspaceID), // 工作空间 ID
+		cozeloop.WithAPIToken(apiToken),       // API Token
 	)
 	if err != nil {
-		log.Fatalf("cozeloop.NewClient failed, err: %v", err)
+		return nil, fmt.Errorf("cozeloop.NewClient failed: %w", err)
 	}
+	return &cozeLoopTracer{client: client}, nil
+}
+
+type cozeLoopTracer struct {
+	client cozeloop.Client
+}
 
-	// 创建 CozeLoop 回调处理器
-	handler := ccb.NewLoopHandler(client)
+// StartSpan 启动一个 CozeLoop Span，SpanKind 即 CozeLoop 的 span type
+func (t *cozeLoopTracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	o := newSpanOptions(opts)
+	ctx, span := t.client.StartSpan(ctx, name, string(o.kind))
+	s := &cozeLoopSpan{ctx: ctx, span: span}
+	s.SetAttributes(o.attrs...)
+	return ctx, s
+}
 
-	// 注册为全局回调，这样所有 Eino 的组件（Model、Tool、Graph 等）的执行都会被追踪
-	callbacks.AppendGlobalHandlers(handler)
+func (t *cozeLoopTracer) Close(ctx context.Context) error {
+	t.client.Close(ctx)
+	return nil
+}
 
-	// 返回关闭函数和启动 Span 的函数
-	return client.Close, buildStartSpanFn(client)
+// Handler 注册为全局回调后，所有 Eino 组件（Model、Tool、Graph 等）的执行都会被追踪
+func (t *cozeLoopTracer) Handler() callbacks.Handler {
+	return ccb.NewLoopHandler(t.client)
 }
 
-// buildStartSpanFn 构建 StartSpanFn 函数
-//
-// 参数：
-//   - client: CozeLoop 客户端，如果为 nil 表示未配置 CozeLoop，返回空操作函数
-//
-// 返回一个函数，该函数用于启动一个链路追踪 Span：
-//   - name: Span 的名称，通常标识操作的类型（如 "chat_model", "tool_call"）
-//   - input: 操作的输入数据
-//   - 返回: 新的 context（包含 span 信息）和结束 Span 的函数
-func buildStartSpanFn(client cozeloop.Client) StartSpanFn {
-	return func(ctx context.Context, name string, input any) (nCtx context.Context, endFn EndSpanFn) {
-		// 如果客户端为 nil，返回空操作函数
-		if client == nil {
-			return ctx, func(ctx context.Context, output any) {
-				// 空 end 函数
-			}
-		}
-
-		// 启动一个新的 Span
-		// name: Span 名称，用于标识操作类型
-		// "custom": 表示这是自定义类型的 Span
-		nCtx, span := client.StartSpan(ctx, name, "custom")
-
-		// 设置 Span 的输入数据，便于追踪查看
-		span.SetInput(ctx, input)
-
-		// 返回包含 span 的 context 和结束 Span 的函数
-		return nCtx, buildEndSpanFn(span)
+type cozeLoopSpan struct {
+	ctx  context.Context
+	span cozeloop.Span
+}
+
+func (s *cozeLoopSpan) SetInput(input any) {
+	s.span.SetInput(s.ctx, input)
+}
+
+func (s *cozeLoopSpan) SetOutput(output any) {
+	s.span.SetOutput(s.ctx, output)
+}
+
+// SetAttributes 属性记录为 CozeLoop 的自定义 tag
+func (s *cozeLoopSpan) SetAttributes(attrs ...Attr) {
+	if len(attrs) == 0 {
+		return
+	}
+	tags := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		tags[a.Key] = a.Value
 	}
+	s.span.SetTags(s.ctx, tags)
 }
 
-// buildEndSpanFn 构建 EndSpanFn 函数
-//
-// 参数：
-//   - span: CozeLoop 的 Span 对象，如果为 nil 表示未配置 CozeLoop，返回空操作函数
-//
-// 返回一个函数，该函数用于结束一个链路追踪 Span：
-//   - output: 操作的输出数据
-func buildEndSpanFn(span cozeloop.Span) EndSpanFn {
-	return func(ctx context.Context, output any) {
-		// 如果 span 为 nil，直接返回
-		if span == nil {
-			return
-		}
-
-		// 设置 Span 的输出数据
-		span.SetOutput(ctx, output)
-
-		// 完成 Span，将追踪数据发送到 CozeLoop 平台
-		span.Finish(ctx)
+// AddEvent CozeLoop 没有事件，记录为 event.{name} tag，值为事件时间（Unix 毫秒）和属性
+func (s *cozeLoopSpan) AddEvent(name string, attrs ...Attr) {
+	v := map[string]any{"time": time.Now().UnixMilli()}
+	for _, a := range attrs {
+		v[a.Key] = a.Value
+	}
+	s.span.SetTags(s.ctx, map[string]any{"event." + name: toJSON(v)})
+}
+
+// SetError 非 0 的状态码即视为异常
+func (s *cozeLoopSpan) SetError(err error) {
+	s.span.SetError(s.ctx, err)
+	s.span.SetStatusCode(s.ctx, 1)
+}
+
+func (s *cozeLoopSpan) End() {
+	// 完成 Span，将追踪数据发送到 CozeLoop 平台
+	s.span.Finish(s.ctx)
+}
+
+func cozeLoopBackendsFromEnv() []BackendConfig {
+	wsID := os.Getenv("COZELOOP_WORKSPACE_ID")
+	apiKey := os.Getenv("COZELOOP_API_TOKEN")
+	if wsID == "" || apiKey == "" {
+		return nil
 	}
+	return []BackendConfig{{Type: BackendCozeLoop, WorkspaceID: wsID, APIToken: apiKey}}
 }
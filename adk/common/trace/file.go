@@ -0,0 +1,205 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+)
+
+// SpanRecord 本地文件中的一个 Span，每行一个 JSON 对象，在 Span 结束时写入
+type SpanRecord struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Kind       SpanKind       `json:"kind"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	DurationMS float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Events     []EventRecord  `json:"events,omitempty"`
+	Input      string         `json:"input,omitempty"`
+	Output     string         `json:"output,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// EventRecord Span 中的一个事件
+type EventRecord struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// NewFileTracer 把 Span 以 JSON Lines 格式写入本地文件，不需要任何外部服务
+// path 为 stdout、stderr 或文件路径，文件以追加方式打开；输入输出总是记录，按字符截断到 8192
+func NewFileTracer(path string) (Tracer, error) {
+	switch path {
+	case "stdout":
+		return NewWriterTracer(os.Stdout, nil), nil
+	case "stderr":
+		return NewWriterTracer(os.Stderr, nil), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return NewWriterTracer(f, f), nil
+}
+
+// NewWriterTracer 把 Span 以 JSON Lines 格式写入 w，closer 不为 nil 时在 Close 时关闭
+func NewWriterTracer(w io.Writer, closer io.Closer) Tracer {
+	return &fileTracer{w: w, closer: closer}
+}
+
+// NewFileHandler 把组件的 Span 写入本地 JSONL 文件的回调，不需要 CozeLoop 账号也能查看链路
+// 关闭函数等待尚未读完的流式输出结束后关闭文件，之后可以用 trace 子命令生成 HTML 页面
+func NewFileHandler(path string) (callbacks.Handler, CloseFn, error) {
+	t, err := NewFileTracer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := newHandler(t)
+	return h.handler(), func(ctx context.Context) {
+		h.wait()
+		_ = t.Close(ctx)
+	}, nil
+}
+
+// ReadSpans 读取 JSONL 文件中的所有 Span，按写入顺序（即结束顺序）返回
+func ReadSpans(r io.Reader) ([]*SpanRecord, error) {
+	sc := bufio.NewScanner(r)
+	// 输入输出各自最多 8192 个字符，加上属性和事件，一行可能超过默认的 64KB
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var spans []*SpanRecord
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec SpanRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		spans = append(spans, &rec)
+	}
+	return spans, sc.Err()
+}
+
+type fileTracer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+type fileSpanKey struct{}
+
+func (t *fileTracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	o := newSpanOptions(opts)
+	r := &SpanRecord{SpanID: randomID(8), Name: name, Kind: o.kind, Start: time.Now()}
+	if parent, ok := ctx.Value(fileSpanKey{}).(*fileSpan); ok {
+		r.TraceID, r.ParentID = parent.record.TraceID, parent.record.SpanID
+	} else {
+		r.TraceID = randomID(16)
+	}
+	s := &fileSpan{tracer: t, record: r}
+	s.SetAttributes(o.attrs...)
+	return context.WithValue(ctx, fileSpanKey{}, s), s
+}
+
+func (t *fileTracer) Close(ctx context.Context) error {
+	if t.closer == nil {
+		return nil
+	}
+	return t.closer.Close()
+}
+
+func (t *fileTracer) write(r *SpanRecord) {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = t.w.Write(append(bs, '\n'))
+}
+
+type fileSpan struct {
+	tracer *fileTracer
+	mu     sync.Mutex // 流式输出在单独的 goroutine 中结束 Span
+	record *SpanRecord
+	ended  bool
+}
+
+func (s *fileSpan) SetInput(input any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Input = truncate(toJSON(input))
+}
+
+func (s *fileSpan) SetOutput(output any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Output = truncate(toJSON(output))
+}
+
+func (s *fileSpan) SetAttributes(attrs ...Attr) {
+	if len(attrs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.record.Attributes == nil {
+		s.record.Attributes = make(map[string]any, len(attrs))
+	}
+	for _, a := range attrs {
+		s.record.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *fileSpan) AddEvent(name string, attrs ...Attr) {
+	e := EventRecord{Name: name, Time: time.Now()}
+	if len(attrs) > 0 {
+		e.Attributes = make(map[string]any, len(attrs))
+		for _, a := range attrs {
+			e.Attributes[a.Key] = a.Value
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Events = append(s.record.Events, e)
+}
+
+func (s *fileSpan) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Error = err.Error()
+}
+
+// End 只有第一次调用会写入
+func (s *fileSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.record.End = time.Now()
+	s.record.DurationMS = float64(s.record.End.Sub(s.record.Start).Microseconds()) / 1000
+	r := *s.record
+	s.mu.Unlock()
+	s.tracer.write(&r)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
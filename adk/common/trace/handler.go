@@ -0,0 +1,431 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/usage"
+)
+
+// GenAI 语义约定中的属性名，见 https://opentelemetry.io/docs/specs/semconv/gen-ai/gen-ai-spans/
+const (
+	AttrOperationName    = "gen_ai.operation.name"
+	AttrProviderName     = "gen_ai.provider.name"
+	AttrRequestModel     = "gen_ai.request.model"
+	AttrRequestTemp      = "gen_ai.request.temperature"
+	AttrRequestTopP      = "gen_ai.request.top_p"
+	AttrRequestMaxTokens = "gen_ai.request.max_tokens"
+	AttrRequestStop      = "gen_ai.request.stop_sequences"
+	AttrResponseModel    = "gen_ai.response.model"
+	AttrFinishReasons    = "gen_ai.response.finish_reasons"
+	AttrInputTokens      = "gen_ai.usage.input_tokens"
+	AttrOutputTokens     = "gen_ai.usage.output_tokens"
+	AttrCachedTokens     = "gen_ai.usage.cache_read.input_tokens"
+	AttrAgentName        = "gen_ai.agent.name"
+	AttrToolName         = "gen_ai.tool.name"
+	AttrToolType         = "gen_ai.tool.type"
+	AttrToolCallID       = "gen_ai.tool.call.id"
+	AttrErrorType        = "error.type"
+
+	// 以下为 eino 自身的信息
+	AttrComponent     = "eino.component"
+	AttrType          = "eino.type"
+	AttrNode          = "eino.node"
+	AttrRunPath       = "eino.run_path"
+	AttrRetrieverDocs = "eino.retriever.documents"
+)
+
+// GenAI 语义约定中的操作名
+const (
+	OpChat        = "chat"
+	OpEmbeddings  = "embeddings"
+	OpExecuteTool = "execute_tool"
+	OpInvokeAgent = "invoke_agent"
+)
+
+// EventFirstChunk 流式输出收到第一个 chunk 时记录的事件，ChatModel 即首 token 时间
+const EventFirstChunk = "first_chunk"
+
+// maxAttrLen 输入输出等内容按字符截断的长度
+const maxAttrLen = 8192
+
+// NewHandler 基于 Tracer 的组件回调，为模型、工具、检索器、图节点和 Agent 生成 Span，属性遵循 GenAI 语义约定
+//
+// Span 名称：ChatModel 为 "chat {model}"，Tool 为 "execute_tool {tool}"，
+// ChatModelAgent 内部以 Agent 名称命名的图为 "invoke_agent {agent}"，其他为 "{component} {name}"；
+// 输入输出通过 Span.SetInput/SetOutput 交给后端，是否记录由后端决定
+func NewHandler(t Tracer) callbacks.Handler {
+	return newHandler(t).handler()
+}
+
+type spanKey struct{}
+
+type handler struct {
+	tracer  Tracer
+	pending sync.WaitGroup // 尚未读完的流式输出
+}
+
+func newHandler(t Tracer) *handler {
+	return &handler{tracer: t}
+}
+
+func (h *handler) handler() callbacks.Handler {
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(h.onStart).
+		OnEndFn(h.onEnd).
+		OnErrorFn(h.onError).
+		OnStartWithStreamInputFn(h.onStartWithStreamInput).
+		OnEndWithStreamOutputFn(h.onEndWithStreamOutput).
+		Build()
+}
+
+// wait 等待流式输出读完，对应的 Span 结束后再关闭后端
+func (h *handler) wait() {
+	h.pending.Wait()
+}
+
+func (h *handler) onStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	ctx, span := h.startSpan(ctx, info, input)
+	setInput(span, info, input)
+	return ctx
+}
+
+func (h *handler) onStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	// 流式输入不读取内容；回调拿到的是副本，必须关闭
+	input.Close()
+	ctx, _ = h.startSpan(ctx, info, nil)
+	return ctx
+}
+
+func (h *handler) onEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return ctx
+	}
+	setOutput(span, info, output)
+	span.End()
+	return ctx
+}
+
+func (h *handler) onError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return ctx
+	}
+	span.SetAttributes(String(AttrErrorType, fmt.Sprintf("%T", err)))
+	span.SetError(err)
+	span.End()
+	return ctx
+}
+
+// onEndWithStreamOutput 在流读完后结束 Span，ChatModel 的输出拼接成完整消息后再记录
+func (h *handler) onEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		output.Close()
+		return ctx
+	}
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		defer output.Close()
+		var chunks []callbacks.CallbackOutput
+		for {
+			chunk, err := output.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				span.SetError(err)
+				break
+			}
+			if len(chunks) == 0 {
+				span.AddEvent(EventFirstChunk)
+			}
+			chunks = append(chunks, chunk)
+		}
+		if out := concatModelOutput(info, chunks); out != nil {
+			setOutput(span, info, out)
+		} else {
+			span.SetAttributes(Int("eino.stream.chunks", len(chunks)))
+		}
+		span.End()
+	}()
+	return ctx
+}
+
+func (h *handler) startSpan(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) (context.Context, Span) {
+	if info == nil {
+		info = &callbacks.RunInfo{}
+	}
+	addr := compose.GetCurrentAddress(ctx)
+	agent, node := "", ""
+	for _, seg := range addr {
+		switch seg.Type {
+		case adk.AddressSegmentAgent:
+			agent = seg.ID
+		case compose.AddressSegmentNode:
+			node = seg.ID
+		}
+	}
+
+	name, op, kind := spanName(string(info.Component), info.Name), "", KindNode
+	switch {
+	case info.Component == components.ComponentOfChatModel:
+		name, op, kind = OpChat, OpChat, KindModel
+		if in := model.ConvCallbackInput(input); in != nil && in.Config != nil {
+			name = spanName(OpChat, in.Config.Model)
+		}
+	case info.Component == components.ComponentOfEmbedding:
+		name, op, kind = OpEmbeddings, OpEmbeddings, KindEmbedding
+		if in := embedding.ConvCallbackInput(input); in != nil && in.Config != nil {
+			name = spanName(OpEmbeddings, in.Config.Model)
+		}
+	case info.Component == components.ComponentOfRetriever:
+		kind = KindRetriever
+	case info.Component == components.ComponentOfTool:
+		name, op, kind = spanName(OpExecuteTool, info.Name), OpExecuteTool, KindTool
+	case isAgentGraph(info, addr):
+		name, op, kind = spanName(OpInvokeAgent, info.Name), OpInvokeAgent, KindAgent
+	}
+
+	attrs := []Attr{
+		String(AttrComponent, string(info.Component)),
+		String(AttrRunPath, addr.String()),
+	}
+	if info.Type != "" {
+		attrs = append(attrs, String(AttrType, info.Type))
+	}
+	if node != "" {
+		attrs = append(attrs, String(AttrNode, node))
+	}
+	if op != "" {
+		attrs = append(attrs, String(AttrOperationName, op))
+	}
+	if agent != "" {
+		attrs = append(attrs, String(AttrAgentName, agent))
+	}
+	switch op {
+	case OpChat, OpEmbeddings:
+		if info.Type != "" {
+			attrs = append(attrs, String(AttrProviderName, strings.ToLower(info.Type)))
+		}
+	case OpExecuteTool:
+		attrs = append(attrs, String(AttrToolName, info.Name), String(AttrToolType, "function"))
+		if id := compose.GetToolCallID(ctx); id != "" {
+			attrs = append(attrs, String(AttrToolCallID, id))
+		}
+	}
+
+	ctx, span := h.tracer.StartSpan(ctx, name, WithKind(kind), WithAttributes(attrs...))
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// isAgentGraph ChatModelAgent 以 Agent 名称作为内部图（Chain）的名称，
+// 图的执行地址形如 agent:xxx;runnable:xxx，即图直接位于同名 Agent 之下
+func isAgentGraph(info *callbacks.RunInfo, addr compose.Address) bool {
+	switch info.Component {
+	case compose.ComponentOfGraph, compose.ComponentOfChain, compose.ComponentOfWorkflow:
+	default:
+		return false
+	}
+	if n := len(addr); n > 0 && addr[n-1].Type == compose.AddressSegmentRunnable {
+		addr = addr[:n-1]
+	}
+	if len(addr) == 0 {
+		return false
+	}
+	last := addr[len(addr)-1]
+	return last.Type == adk.AddressSegmentAgent && last.ID == info.Name
+}
+
+func spanName(op, name string) string {
+	if name == "" {
+		return op
+	}
+	return op + " " + name
+}
+
+func setInput(span Span, info *callbacks.RunInfo, input callbacks.CallbackInput) {
+	if info == nil {
+		return
+	}
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		in := model.ConvCallbackInput(input)
+		if in == nil {
+			return
+		}
+		if in.Config != nil {
+			span.SetAttributes(modelConfigAttrs(in.Config)...)
+		}
+		span.SetInput(in.Messages)
+	case components.ComponentOfEmbedding:
+		in := embedding.ConvCallbackInput(input)
+		if in == nil {
+			return
+		}
+		if in.Config != nil && in.Config.Model != "" {
+			span.SetAttributes(String(AttrRequestModel, in.Config.Model))
+		}
+		span.SetInput(in.Texts)
+	case components.ComponentOfRetriever:
+		if in := retriever.ConvCallbackInput(input); in != nil {
+			span.SetInput(in.Query)
+		}
+	case components.ComponentOfTool:
+		if in := tool.ConvCallbackInput(input); in != nil {
+			span.SetInput(in.ArgumentsInJSON)
+		}
+	default:
+		span.SetInput(input)
+	}
+}
+
+func setOutput(span Span, info *callbacks.RunInfo, output callbacks.CallbackOutput) {
+	if info == nil {
+		return
+	}
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		out := model.ConvCallbackOutput(output)
+		if out == nil {
+			return
+		}
+		if out.Config != nil {
+			span.SetAttributes(modelConfigAttrs(out.Config)...)
+			span.SetAttributes(String(AttrResponseModel, out.Config.Model))
+		}
+		if tu := usage.TokenUsage(out); tu != nil {
+			span.SetAttributes(Int(AttrInputTokens, tu.PromptTokens), Int(AttrOutputTokens, tu.CompletionTokens))
+			if cached := tu.PromptTokenDetails.CachedTokens; cached > 0 {
+				span.SetAttributes(Int(AttrCachedTokens, cached))
+			}
+		}
+		if msg := out.Message; msg != nil {
+			if msg.ResponseMeta != nil && msg.ResponseMeta.FinishReason != "" {
+				span.SetAttributes(Strings(AttrFinishReasons, []string{msg.ResponseMeta.FinishReason}))
+			}
+			span.SetOutput([]*schema.Message{msg})
+		}
+	case components.ComponentOfEmbedding:
+		out := embedding.ConvCallbackOutput(output)
+		if out == nil {
+			return
+		}
+		if out.TokenUsage != nil {
+			span.SetAttributes(Int(AttrInputTokens, out.TokenUsage.PromptTokens))
+		}
+		dim := 0
+		if len(out.Embeddings) > 0 {
+			dim = len(out.Embeddings[0])
+		}
+		span.SetOutput(fmt.Sprintf("%d vectors, dim=%d", len(out.Embeddings), dim))
+	case components.ComponentOfRetriever:
+		if out := retriever.ConvCallbackOutput(output); out != nil {
+			span.SetAttributes(Int(AttrRetrieverDocs, len(out.Docs)))
+			span.SetOutput(out.Docs)
+		}
+	case components.ComponentOfTool:
+		if out := tool.ConvCallbackOutput(output); out != nil {
+			span.SetOutput(out.Response)
+		}
+	default:
+		span.SetOutput(output)
+	}
+}
+
+// modelConfigAttrs 模型名称和采样参数，开始时没有提供配置的组件在结束时补上
+func modelConfigAttrs(cfg *model.Config) []Attr {
+	if cfg.Model == "" {
+		return nil
+	}
+	attrs := []Attr{String(AttrRequestModel, cfg.Model)}
+	if cfg.Temperature != 0 {
+		attrs = append(attrs, Float(AttrRequestTemp, float64(cfg.Temperature)))
+	}
+	if cfg.TopP != 0 {
+		attrs = append(attrs, Float(AttrRequestTopP, float64(cfg.TopP)))
+	}
+	if cfg.MaxTokens != 0 {
+		attrs = append(attrs, Int(AttrRequestMaxTokens, cfg.MaxTokens))
+	}
+	if len(cfg.Stop) > 0 {
+		attrs = append(attrs, Strings(AttrRequestStop, cfg.Stop))
+	}
+	return attrs
+}
+
+// concatModelOutput 把 ChatModel 的流式输出合并成一个 CallbackOutput，其他组件返回 nil
+// 用量一般只在最后一个 chunk 中，配置取第一个非空的
+func concatModelOutput(info *callbacks.RunInfo, chunks []callbacks.CallbackOutput) *model.CallbackOutput {
+	if info == nil || info.Component != components.ComponentOfChatModel {
+		return nil
+	}
+	merged := &model.CallbackOutput{}
+	var msgs []*schema.Message
+	for _, c := range chunks {
+		out := model.ConvCallbackOutput(c)
+		if out == nil {
+			continue
+		}
+		if out.Message != nil {
+			msgs = append(msgs, out.Message)
+		}
+		if out.Config != nil && merged.Config == nil {
+			merged.Config = out.Config
+		}
+		if out.TokenUsage != nil {
+			merged.TokenUsage = out.TokenUsage
+		}
+	}
+	if len(msgs) > 0 {
+		msg, err := schema.ConcatMessages(msgs)
+		if err != nil {
+			msg = msgs[len(msgs)-1]
+		}
+		merged.Message = msg
+	}
+	return merged
+}
+
+// toJSON 字符串原样返回，error 取错误信息，其他值序列化成 JSON
+func toJSON(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case error:
+		return x.Error()
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
+
+// truncate 按字符截断过长的内容，后端对单个属性的长度通常有限制
+func truncate(s string) string {
+	if utf8.RuneCountInString(s) <= maxAttrLen {
+		return s
+	}
+	rs := []rune(s)
+	return fmt.Sprintf("%s...(%d more)", string(rs[:maxAttrLen]), len(rs)-maxAttrLen)
+}
@@ -0,0 +1,229 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/callbacks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName OTel tracer 的名称
+const instrumentationName = "eino-learn/adk/common/trace"
+
+// GenAI 语义约定中记录内容的属性，只有 OTelConfig.CaptureContent 为 true 时才会设置
+const (
+	attrInputMessages     = "gen_ai.input.messages"
+	attrOutputMessages    = "gen_ai.output.messages"
+	attrToolCallArguments = "gen_ai.tool.call.arguments"
+	attrToolCallResult    = "gen_ai.tool.call.result"
+	attrInput             = "eino.input"
+	attrOutput            = "eino.output"
+)
+
+// OTelConfig OTel 后端的配置
+type OTelConfig struct {
+	// TracerProvider 为 nil 时使用 otel.GetTracerProvider()
+	TracerProvider oteltrace.TracerProvider
+	// CaptureContent 是否记录消息内容、工具参数和结果，语义约定中这些属于敏感数据，默认不记录
+	// 通过 StartSpanFn 创建的自定义 Span 由调用方决定输入输出，总是记录
+	CaptureContent bool
+}
+
+// NewOTelTracer 创建 OTel 后端
+// TracerProvider 由调用方创建和关闭，关闭后端时只在它是 SDK 的实现时导出尚未导出的 Span
+func NewOTelTracer(cfg *OTelConfig) Tracer {
+	return newOTelTracer(cfg)
+}
+
+func newOTelTracer(cfg *OTelConfig) *otelTracer {
+	tp := otel.GetTracerProvider()
+	t := &otelTracer{}
+	if cfg != nil {
+		if cfg.TracerProvider != nil {
+			tp = cfg.TracerProvider
+		}
+		t.capture = cfg.CaptureContent
+	}
+	t.provider = tp
+	t.tracer = tp.Tracer(instrumentationName)
+	return t
+}
+
+// NewOTelHandler 创建 OTel 回调，可以通过 callbacks.AppendGlobalHandlers 全局注册或者在单次运行时传入
+func NewOTelHandler(cfg *OTelConfig) callbacks.Handler {
+	return NewHandler(NewOTelTracer(cfg))
+}
+
+// AppendOTelCallbackIfConfigured 按 OTel 标准环境变量启用 OTel 后端，变量见 ConfigFromEnv
+func AppendOTelCallbackIfConfigured(ctx context.Context) (closeFn CloseFn, startSpanFn StartSpanFn) {
+	return appendIfConfigured(ctx, &Config{Backends: otelBackendsFromEnv()})
+}
+
+type otelTracer struct {
+	provider oteltrace.TracerProvider
+	tracer   oteltrace.Tracer
+	capture  bool
+	owned    *sdktrace.TracerProvider // newOTelTracerFromConfig 创建的 TracerProvider，关闭后端时一并关闭
+}
+
+func (t *otelTracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	o := newSpanOptions(opts)
+	kind := oteltrace.SpanKindInternal
+	switch o.kind {
+	case KindModel, KindEmbedding, KindRetriever:
+		kind = oteltrace.SpanKindClient
+	}
+	ctx, span := t.tracer.Start(ctx, name, oteltrace.WithSpanKind(kind), oteltrace.WithAttributes(otelAttrs(o.attrs)...))
+	return ctx, &otelSpan{span: span, kind: o.kind, capture: t.capture || o.kind == KindCustom}
+}
+
+func (t *otelTracer) Close(ctx context.Context) error {
+	if t.owned != nil {
+		return t.owned.Shutdown(ctx)
+	}
+	if sdk, ok := t.provider.(*sdktrace.TracerProvider); ok {
+		return sdk.ForceFlush(ctx)
+	}
+	return nil
+}
+
+type otelSpan struct {
+	span    oteltrace.Span
+	kind    SpanKind
+	capture bool
+}
+
+// SetInput 模型输入记录为 gen_ai.input.messages，工具参数记录为 gen_ai.tool.call.arguments，其他记录为 eino.input
+func (s *otelSpan) SetInput(input any) {
+	if !s.capture {
+		return
+	}
+	key := attrInput
+	switch s.kind {
+	case KindModel:
+		key = attrInputMessages
+	case KindTool:
+		key = attrToolCallArguments
+	}
+	s.span.SetAttributes(attribute.String(key, truncate(toJSON(input))))
+}
+
+func (s *otelSpan) SetOutput(output any) {
+	if !s.capture {
+		return
+	}
+	key := attrOutput
+	switch s.kind {
+	case KindModel:
+		key = attrOutputMessages
+	case KindTool:
+		key = attrToolCallResult
+	}
+	s.span.SetAttributes(attribute.String(key, truncate(toJSON(output))))
+}
+
+func (s *otelSpan) SetAttributes(attrs ...Attr) {
+	s.span.SetAttributes(otelAttrs(attrs)...)
+}
+
+func (s *otelSpan) AddEvent(name string, attrs ...Attr) {
+	s.span.AddEvent(name, oteltrace.WithAttributes(otelAttrs(attrs)...))
+}
+
+func (s *otelSpan) SetError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func otelAttrs(attrs []Attr) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			out = append(out, attribute.String(a.Key, v))
+		case bool:
+			out = append(out, attribute.Bool(a.Key, v))
+		case int:
+			out = append(out, attribute.Int(a.Key, v))
+		case int64:
+			out = append(out, attribute.Int64(a.Key, v))
+		case float64:
+			out = append(out, attribute.Float64(a.Key, v))
+		case []string:
+			out = append(out, attribute.StringSlice(a.Key, v))
+		default:
+			out = append(out, attribute.String(a.Key, toJSON(v)))
+		}
+	}
+	return out
+}
+
+// newOTelTracerFromConfig 按配置创建导出器和 TracerProvider，并设为全局 TracerProvider
+func newOTelTracerFromConfig(ctx context.Context, cfg BackendConfig) (Tracer, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(cfg.Exporter) {
+	case "", "otlp":
+		// 未指定地址时由 OTEL_EXPORTER_OTLP_* 环境变量决定，默认 http://localhost:4318
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown otel exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create otel %s exporter: %w", cfg.Exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME 和 OTEL_RESOURCE_ATTRIBUTES 优先于配置中的服务名
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "eino-learn"
+	}
+	res, err := resource.Merge(
+		resource.NewSchemaless(attribute.String("service.name", serviceName)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create otel resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t := newOTelTracer(&OTelConfig{TracerProvider: tp, CaptureContent: cfg.CaptureContent})
+	t.owned = tp
+	return t, nil
+}
+
+// otelBackendsFromEnv 按 OTel 标准环境变量生成后端配置，未配置导出时返回 nil
+func otelBackendsFromEnv() []BackendConfig {
+	exporter := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER"))
+	if exporter == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		exporter = "otlp"
+	}
+	if exporter == "" || exporter == "none" {
+		return nil
+	}
+	capture, _ := strconv.ParseBool(os.Getenv("OTEL_INSTRUMENTATION_GENAI_CAPTURE_MESSAGE_CONTENT"))
+	return []BackendConfig{{Type: BackendOTel, Exporter: exporter, CaptureContent: capture}}
+}
@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestOTelTracerCloseKeepsProvider 调用方传入的 TracerProvider 不随后端关闭，之后仍能记录 Span
+func TestOTelTracerCloseKeepsProvider(t *testing.T) {
+	ctx := context.Background()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(ctx)
+
+	tracer := NewOTelTracer(&OTelConfig{TracerProvider: tp})
+	_, span := tracer.StartSpan(ctx, "before")
+	span.End()
+	if err := tracer.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, span = NewOTelTracer(&OTelConfig{TracerProvider: tp}).StartSpan(ctx, "after")
+	span.End()
+	if n := len(rec.Ended()); n != 2 {
+		t.Fatalf("recorded %d spans, want 2", n)
+	}
+}
+
+func TestOTelTracerFromConfigOwnsProvider(t *testing.T) {
+	ctx := context.Background()
+	// newOTelTracerFromConfig 会替换全局 TracerProvider
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	tracer, err := newOTelTracerFromConfig(ctx, BackendConfig{Exporter: "stdout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := tracer.(*otelTracer).owned
+	if owned == nil {
+		t.Fatal("provider created from config is not owned by the tracer")
+	}
+	if err := tracer.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 已经关闭的 TracerProvider 创建的 Span 不再记录
+	if _, span := owned.Tracer("test").Start(ctx, "after"); span.IsRecording() {
+		t.Fatal("owned provider was not shut down")
+	}
+}
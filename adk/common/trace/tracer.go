@@ -0,0 +1,200 @@
+package trace
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/callbacks"
+)
+
+// Tracer 链路追踪后端，CozeLoop、OTel、本地文件等后端都实现该接口，可以通过 NewMultiTracer 同时启用多个
+type Tracer interface {
+	// StartSpan 启动一个 Span，返回的 context 中带有该 Span，之后在此 context 上启动的 Span 都是它的子 Span
+	StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span)
+	// Close 导出尚未上报的 Span 并释放资源
+	Close(ctx context.Context) error
+}
+
+// Span 链路中的一个操作，必须调用 End 才会上报
+type Span interface {
+	// SetInput 设置输入，后端自行决定是否记录以及如何序列化
+	SetInput(input any)
+	// SetOutput 设置输出
+	SetOutput(output any)
+	// SetAttributes 设置属性，同名属性覆盖
+	SetAttributes(attrs ...Attr)
+	// AddEvent 记录一个带时间戳的事件，例如流式输出的首个 chunk
+	AddEvent(name string, attrs ...Attr)
+	// SetError 记录错误并把 Span 标记为失败
+	SetError(err error)
+	// End 结束 Span
+	End()
+}
+
+// CallbackProvider 自带组件回调的后端，例如 CozeLoop 使用官方的 eino 回调记录模型和工具的 Span
+// Setup 为实现了该接口的后端注册它自己的回调，其他后端共用 NewHandler
+type CallbackProvider interface {
+	Handler() callbacks.Handler
+}
+
+// Attr Span 的属性，Value 为 string、bool、int、int64、float64 或 []string
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String 字符串属性
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+// Int 整数属性
+func Int(key string, value int) Attr { return Attr{Key: key, Value: value} }
+
+// Float 浮点数属性
+func Float(key string, value float64) Attr { return Attr{Key: key, Value: value} }
+
+// Bool 布尔属性
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// Strings 字符串数组属性
+func Strings(key string, value []string) Attr { return Attr{Key: key, Value: value} }
+
+// SpanKind Span 代表的操作类型，各后端据此选择 Span 类型（CozeLoop 的 span type、OTel 的 SpanKind 等）
+type SpanKind string
+
+const (
+	KindCustom    SpanKind = "custom"
+	KindAgent     SpanKind = "agent"
+	KindModel     SpanKind = "model"
+	KindTool      SpanKind = "tool"
+	KindEmbedding SpanKind = "embedding"
+	KindRetriever SpanKind = "retriever"
+	KindNode      SpanKind = "node" // 图节点、Lambda 等其他组件
+)
+
+// SpanOption StartSpan 的可选配置
+type SpanOption func(*spanOptions)
+
+type spanOptions struct {
+	kind  SpanKind
+	attrs []Attr
+}
+
+// WithKind 设置 Span 的类型，默认 KindCustom
+func WithKind(kind SpanKind) SpanOption {
+	return func(o *spanOptions) { o.kind = kind }
+}
+
+// WithAttributes 在启动时设置属性，采样等决策可以用到
+func WithAttributes(attrs ...Attr) SpanOption {
+	return func(o *spanOptions) { o.attrs = append(o.attrs, attrs...) }
+}
+
+func newSpanOptions(opts []SpanOption) spanOptions {
+	o := spanOptions{kind: KindCustom}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// NoopTracer 什么都不做的 Tracer，没有配置任何后端时使用
+type NoopTracer struct{}
+
+func (NoopTracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (NoopTracer) Close(ctx context.Context) error { return nil }
+
+type noopSpan struct{}
+
+func (noopSpan) SetInput(any)             {}
+func (noopSpan) SetOutput(any)            {}
+func (noopSpan) SetAttributes(...Attr)    {}
+func (noopSpan) AddEvent(string, ...Attr) {}
+func (noopSpan) SetError(error)           {}
+func (noopSpan) End()                     {}
+
+// NewMultiTracer 把 Span 同时发送给多个后端，没有后端时返回 NoopTracer，只有一个时直接返回它
+func NewMultiTracer(tracers ...Tracer) Tracer {
+	switch len(tracers) {
+	case 0:
+		return NoopTracer{}
+	case 1:
+		return tracers[0]
+	}
+	return multiTracer(tracers)
+}
+
+type multiTracer []Tracer
+
+// StartSpan 依次在各个后端启动 Span，各后端在 context 中使用各自的 key，因此可以串起来传递
+func (m multiTracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	spans := make(multiSpan, len(m))
+	for i, t := range m {
+		ctx, spans[i] = t.StartSpan(ctx, name, opts...)
+	}
+	return ctx, spans
+}
+
+func (m multiTracer) Close(ctx context.Context) error {
+	var errs []error
+	for _, t := range m {
+		errs = append(errs, t.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+type multiSpan []Span
+
+func (m multiSpan) SetInput(input any) {
+	for _, s := range m {
+		s.SetInput(input)
+	}
+}
+
+func (m multiSpan) SetOutput(output any) {
+	for _, s := range m {
+		s.SetOutput(output)
+	}
+}
+
+func (m multiSpan) SetAttributes(attrs ...Attr) {
+	for _, s := range m {
+		s.SetAttributes(attrs...)
+	}
+}
+
+func (m multiSpan) AddEvent(name string, attrs ...Attr) {
+	for _, s := range m {
+		s.AddEvent(name, attrs...)
+	}
+}
+
+func (m multiSpan) SetError(err error) {
+	for _, s := range m {
+		s.SetError(err)
+	}
+}
+
+func (m multiSpan) End() {
+	for _, s := range m {
+		s.End()
+	}
+}
+
+// NewStartSpanFn 基于 Tracer 构建 StartSpanFn；EndSpanFn 的 output 为 error 时记录为错误
+func NewStartSpanFn(t Tracer) StartSpanFn {
+	return func(ctx context.Context, name string, input any) (context.Context, EndSpanFn) {
+		ctx, span := t.StartSpan(ctx, name)
+		span.SetInput(input)
+		return ctx, func(ctx context.Context, output any) {
+			if err, ok := output.(error); ok {
+				span.SetError(err)
+			} else {
+				span.SetOutput(output)
+			}
+			span.End()
+		}
+	}
+}
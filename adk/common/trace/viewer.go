@@ -0,0 +1,259 @@
+package trace
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// viewTrace 页面中的一条链路，时间都是相对链路开始的毫秒数
+type viewTrace struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Start string     `json:"start"`
+	Dur   float64    `json:"dur"`
+	Spans []viewSpan `json:"spans"`
+}
+
+type viewSpan struct {
+	ID     string         `json:"id"`
+	Parent string         `json:"parent,omitempty"`
+	Name   string         `json:"name"`
+	Kind   SpanKind       `json:"kind"`
+	Start  float64        `json:"start"`
+	Dur    float64        `json:"dur"`
+	Attrs  map[string]any `json:"attrs,omitempty"`
+	Events []viewEvent    `json:"events,omitempty"`
+	Input  string         `json:"input,omitempty"`
+	Output string         `json:"output,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type viewEvent struct {
+	Name  string         `json:"name"`
+	At    float64        `json:"at"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// WriteHTML 把 Span 渲染成一个不依赖任何外部资源的 HTML 页面，每条链路显示为可折叠的 Span 树和时间轴
+// 点击 Span 查看属性、事件、输入输出和错误
+func WriteHTML(w io.Writer, spans []*SpanRecord) error {
+	var traces []viewTrace
+	for _, group := range groupByTrace(spans) {
+		start, end := group[0].Start, group[0].End
+		for _, s := range group {
+			if s.End.After(end) {
+				end = s.End
+			}
+		}
+		since := func(t time.Time) float64 { return float64(t.Sub(start).Microseconds()) / 1000 }
+
+		vt := viewTrace{
+			ID: group[0].TraceID, Name: group[0].Name,
+			Start: start.Local().Format(time.DateTime), Dur: since(end),
+			Spans: make([]viewSpan, 0, len(group)),
+		}
+		for _, s := range group {
+			vs := viewSpan{
+				ID: s.SpanID, Parent: s.ParentID, Name: s.Name, Kind: s.Kind,
+				Start: since(s.Start), Dur: since(s.End) - since(s.Start),
+				Attrs: s.Attributes, Input: s.Input, Output: s.Output, Error: s.Error,
+			}
+			for _, e := range s.Events {
+				vs.Events = append(vs.Events, viewEvent{Name: e.Name, At: since(e.Time), Attrs: e.Attributes})
+			}
+			vt.Spans = append(vt.Spans, vs)
+		}
+		traces = append(traces, vt)
+	}
+	return viewerTemplate.Execute(w, traces)
+}
+
+// viewerTemplate 数据在 <script> 中由 html/template 编码为 JSON，页面中的文本都通过 textContent 写入
+var viewerTemplate = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>Trace Viewer</title>
+<style>
+  body { margin: 0; font: 13px/1.5 -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2328; }
+  header { padding: 10px 16px; border-bottom: 1px solid #d0d7de; display: flex; gap: 8px; align-items: center; }
+  header h1 { font-size: 16px; margin: 0 12px 0 0; }
+  button { font: inherit; padding: 2px 10px; border: 1px solid #d0d7de; border-radius: 4px; background: #f6f8fa; cursor: pointer; }
+  main { display: flex; height: calc(100vh - 49px); }
+  #traces { flex: 1; overflow: auto; }
+  #detail { width: 38%; border-left: 1px solid #d0d7de; overflow: auto; padding: 8px 16px; }
+  #detail:empty::before { content: "点击 Span 查看详情"; color: #656d76; }
+  section > h2 { font-size: 14px; margin: 0; padding: 8px 16px; background: #f6f8fa; border-bottom: 1px solid #d0d7de; cursor: pointer; position: sticky; top: 0; z-index: 1; }
+  section > h2 small { color: #656d76; font-weight: normal; margin-left: 8px; }
+  .row { display: grid; grid-template-columns: minmax(280px, 40%) 80px 1fr; align-items: center; border-bottom: 1px solid #eaeef2; cursor: pointer; }
+  .row:hover { background: #f6f8fa; }
+  .row.selected { background: #ddf4ff; }
+  .name { white-space: nowrap; overflow: hidden; text-overflow: ellipsis; padding: 2px 0; }
+  .toggle { display: inline-block; width: 16px; text-align: center; color: #656d76; }
+  .kind { font-size: 11px; padding: 0 4px; margin-right: 4px; border-radius: 3px; color: #fff; }
+  .dur { text-align: right; padding-right: 8px; color: #656d76; font-variant-numeric: tabular-nums; }
+  .track { position: relative; height: 18px; margin-right: 16px; }
+  .bar { position: absolute; top: 4px; height: 10px; min-width: 1px; border-radius: 2px; }
+  .tick { position: absolute; top: 1px; width: 2px; height: 16px; background: #1f2328; }
+  .error .name { color: #cf222e; }
+  .error .bar { outline: 2px solid #cf222e; }
+  .k-agent { background: #8250df; } .k-model { background: #0969da; } .k-tool { background: #1a7f37; }
+  .k-retriever { background: #bc4c00; } .k-embedding { background: #9a6700; } .k-node { background: #6e7781; } .k-custom { background: #bf3989; }
+  #detail h3 { font-size: 14px; margin: 12px 0 4px; }
+  #detail table { border-collapse: collapse; width: 100%; }
+  #detail td { border: 1px solid #d0d7de; padding: 2px 6px; vertical-align: top; word-break: break-all; }
+  #detail td:first-child { white-space: nowrap; color: #656d76; width: 1%; }
+  pre { background: #f6f8fa; padding: 8px; margin: 0; white-space: pre-wrap; word-break: break-all; max-height: 480px; overflow: auto; }
+  .err { color: #cf222e; }
+</style>
+</head>
+<body>
+<header>
+  <h1>Trace Viewer</h1>
+  <button id="expand">全部展开</button>
+  <button id="collapse">全部折叠</button>
+</header>
+<main>
+  <div id="traces"></div>
+  <div id="detail"></div>
+</main>
+<script>
+const traces = {{.}} || [];
+
+function el(tag, cls, text) {
+  const e = document.createElement(tag);
+  if (cls) e.className = cls;
+  if (text !== undefined) e.textContent = text;
+  return e;
+}
+
+function fmtMs(ms) {
+  return ms >= 1000 ? (ms / 1000).toFixed(2) + " s" : ms.toFixed(ms < 10 ? 2 : 0) + " ms";
+}
+
+function pretty(s) {
+  try { return JSON.stringify(JSON.parse(s), null, 2); } catch (e) { return s; }
+}
+
+function kv(obj) {
+  const table = el("table");
+  Object.keys(obj).sort().forEach(function (k) {
+    const tr = el("tr");
+    const v = obj[k];
+    tr.append(el("td", "", k), el("td", "", typeof v === "string" ? v : JSON.stringify(v)));
+    table.append(tr);
+  });
+  return table;
+}
+
+let selected = null;
+
+function showDetail(span, row) {
+  if (selected) selected.classList.remove("selected");
+  selected = row;
+  row.classList.add("selected");
+  const d = document.getElementById("detail");
+  d.replaceChildren();
+  d.append(el("h3", "", span.name));
+  d.append(kv({kind: span.kind, span_id: span.id, start: "+" + fmtMs(span.start), duration: fmtMs(span.dur)}));
+  if (span.error) {
+    d.append(el("h3", "err", "错误"), el("pre", "err", span.error));
+  }
+  if (span.attrs) {
+    d.append(el("h3", "", "属性"), kv(span.attrs));
+  }
+  if (span.events) {
+    d.append(el("h3", "", "事件"));
+    const t = el("table");
+    span.events.forEach(function (e) {
+      const tr = el("tr");
+      tr.append(el("td", "", "+" + fmtMs(e.at)), el("td", "", e.name + (e.attrs ? " " + JSON.stringify(e.attrs) : "")));
+      t.append(tr);
+    });
+    d.append(t);
+  }
+  if (span.input) d.append(el("h3", "", "输入"), el("pre", "", pretty(span.input)));
+  if (span.output) d.append(el("h3", "", "输出"), el("pre", "", pretty(span.output)));
+}
+
+// setCollapsed 折叠时隐藏所有后代，展开时只显示未被折叠的后代
+function setCollapsed(node, collapsed) {
+  node.collapsed = collapsed;
+  if (node.children.length) node.toggle.textContent = collapsed ? "▸" : "▾";
+  (function walk(n, hidden) {
+    n.children.forEach(function (c) {
+      c.row.style.display = hidden ? "none" : "";
+      walk(c, hidden || c.collapsed);
+    });
+  })(node, collapsed);
+}
+
+const allNodes = [];
+
+function renderTrace(trace) {
+  const section = el("section");
+  const h2 = el("h2", "", trace.name);
+  h2.append(el("small", "", trace.start + " · " + fmtMs(trace.dur) + " · " + trace.spans.length + " spans · " + trace.id));
+  const body = el("div");
+  h2.onclick = function () { body.hidden = !body.hidden; };
+  section.append(h2, body);
+
+  // 父 Span 不在文件中时（例如程序中途退出，父 Span 尚未结束）作为根节点显示
+  const nodes = {};
+  trace.spans.forEach(function (s) { nodes[s.id] = {span: s, children: [], collapsed: false}; });
+  const roots = [];
+  trace.spans.forEach(function (s) {
+    const p = s.parent && nodes[s.parent];
+    (p ? p.children : roots).push(nodes[s.id]);
+  });
+
+  const total = trace.dur || 1;
+  (function render(list, depth) {
+    list.forEach(function (n) {
+      const s = n.span;
+      const row = el("div", "row" + (s.error ? " error" : ""));
+      const name = el("div", "name");
+      name.style.paddingLeft = (depth * 16 + 4) + "px";
+      n.toggle = el("span", "toggle", n.children.length ? "▾" : "");
+      n.toggle.onclick = function (ev) { ev.stopPropagation(); setCollapsed(n, !n.collapsed); };
+      name.append(n.toggle, el("span", "kind k-" + s.kind, s.kind), document.createTextNode(s.name));
+      name.title = s.name;
+
+      const track = el("div", "track");
+      const bar = el("div", "bar k-" + s.kind);
+      bar.style.left = (s.start / total * 100) + "%";
+      bar.style.width = (s.dur / total * 100) + "%";
+      track.append(bar);
+      (s.events || []).forEach(function (e) {
+        const tick = el("div", "tick");
+        tick.style.left = (e.at / total * 100) + "%";
+        tick.title = e.name + " +" + fmtMs(e.at);
+        track.append(tick);
+      });
+
+      row.append(name, el("div", "dur", fmtMs(s.dur)), track);
+      row.onclick = function () { showDetail(s, row); };
+      n.row = row;
+      body.append(row);
+      allNodes.push(n);
+      render(n.children, depth + 1);
+    });
+  })(roots, 0);
+  return section;
+}
+
+const container = document.getElementById("traces");
+traces.forEach(function (t) { container.append(renderTrace(t)); });
+if (!traces.length) container.append(el("p", "", "没有 Span"));
+
+document.getElementById("expand").onclick = function () {
+  allNodes.forEach(function (n) { n.collapsed = false; n.row.style.display = ""; if (n.children.length) n.toggle.textContent = "▾"; });
+};
+document.getElementById("collapse").onclick = function () {
+  allNodes.forEach(function (n) { if (n.children.length) setCollapsed(n, true); });
+};
+</script>
+</body>
+</html>
+`))
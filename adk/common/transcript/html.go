@@ -0,0 +1,134 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"eino-learn/adk/common/prints"
+)
+
+// htmlPage 页面数据，连续的同一 Agent 的事件只在第一个事件前显示 Agent 名称
+type htmlPage struct {
+	Header  Header
+	Created string
+	Items   []htmlItem
+}
+
+type htmlItem struct {
+	*Entry
+	Time      string
+	ShowAgent bool
+}
+
+// WriteHTML 把会话记录渲染成一个不依赖任何外部资源的 HTML 页面
+// 思考过程、工具调用参数和工具返回默认折叠，点击展开
+func WriteHTML(w io.Writer, t *Transcript) error {
+	page := htmlPage{Header: t.Header, Created: t.Header.CreatedAt.Local().Format(time.DateTime)}
+	lastAgent := ""
+	for _, e := range t.Entries {
+		item := htmlItem{Entry: e, Time: e.Time.Local().Format(time.TimeOnly)}
+		switch e.Type {
+		case TypeEvent:
+			if e.Event == nil {
+				continue
+			}
+			item.ShowAgent = e.Event.Agent != lastAgent
+			lastAgent = e.Event.Agent
+		case TypeInput, TypeChoice:
+			lastAgent = ""
+		default:
+			continue
+		}
+		page.Items = append(page.Items, item)
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"pretty": prettyJSON,
+	"label":  ActionLabel,
+	"path": func(r *prints.EventRecord) string {
+		return strings.Join(r.RunPath, " › ")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>会话记录 {{.Header.App}} {{.Created}}</title>
+<style>
+  body { margin: 0 auto; max-width: 960px; padding: 16px; font: 14px/1.6 -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2328; }
+  header { border-bottom: 1px solid #d0d7de; margin-bottom: 16px; }
+  header h1 { font-size: 20px; margin: 0 0 4px; }
+  .meta, .time, .path { color: #59636e; font-size: 12px; }
+  .item { margin: 8px 0; }
+  .input { background: #ddf4ff; border-radius: 6px; padding: 8px 12px; margin-top: 24px; }
+  .choice { border-top: 1px dashed #d0d7de; padding-top: 8px; margin-top: 16px; color: #6639ba; }
+  .agent { font-weight: 600; margin-top: 16px; color: #8250df; }
+  .text { white-space: pre-wrap; overflow-wrap: anywhere; }
+  details { margin: 4px 0; border: 1px solid #d0d7de; border-radius: 6px; padding: 4px 8px; }
+  details > summary { cursor: pointer; color: #0969da; }
+  details.reasoning > summary { color: #59636e; }
+  pre { margin: 4px 0; white-space: pre-wrap; overflow-wrap: anywhere; font: 12px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; background: #f6f8fa; padding: 8px; border-radius: 6px; }
+  .notice { color: #9a6700; }
+  .error { color: #cf222e; }
+</style>
+</head>
+<body>
+<header>
+  <h1>会话记录{{with .Header.App}} · {{.}}{{end}}</h1>
+  <div class="meta">ID {{.Header.ID}} · 开始于 {{.Created}} · 格式版本 {{.Header.Version}}</div>
+</header>
+{{range .Items}}
+{{- if eq .Type "input"}}
+<div class="item input"><span class="time">{{.Time}} 用户</span>{{with .SessionID}} <span class="meta">会话 {{.}}</span>{{end}}<div class="text">{{.Text}}</div></div>
+{{- else if eq .Type "choice"}}
+<div class="item choice"><span class="time">{{.Time}}</span> 第 {{.Round}} 轮后：{{label .Action}}{{with .Text}}<div class="text">{{.}}</div>{{end}}</div>
+{{- else}}
+{{- if .ShowAgent}}
+<div class="agent">● {{.Event.Agent}} <span class="path">{{path .Event}}</span></div>
+{{- end}}
+{{- with .Event}}
+<div class="item">
+  {{- with .Reasoning}}
+  <details class="reasoning"><summary>思考过程</summary><div class="text">{{.}}</div></details>
+  {{- end}}
+  {{- if eq .Role "tool"}}
+  <details><summary>工具返回 {{.ToolName}}</summary><pre>{{.Content}}</pre></details>
+  {{- else if .Content}}
+  <div class="text">{{.Content}}</div>
+  {{- end}}
+  {{- range .ToolCalls}}
+  <details><summary>调用工具 {{.Name}}</summary><pre>{{pretty .Arguments}}</pre></details>
+  {{- end}}
+  {{- with .TransferTo}}
+  <div class="notice">⇢ 转交给 {{.}}</div>
+  {{- end}}
+  {{- range .Interrupts}}{{if .RootCause}}
+  <div class="notice">⏸ 中断：<span class="text">{{.Info}}</span></div>
+  {{- end}}{{end}}
+  {{- if .Exit}}
+  <div class="notice">✓ 退出</div>
+  {{- end}}
+  {{- with .Error}}
+  <div class="error">错误：<span class="text">{{.}}</span></div>
+  {{- end}}
+</div>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// prettyJSON 工具参数是合法 JSON 时缩进显示
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,140 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/prints"
+)
+
+// Transcript 读取出的一份会话记录
+type Transcript struct {
+	Header  Header
+	Entries []*Entry
+}
+
+// ReadFile 读取会话记录文件
+func ReadFile(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Read 读取会话记录，版本高于 Version 时返回错误；文件末尾不完整的一行（例如进程被中止）会被忽略
+func Read(r io.Reader) (*Transcript, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	t := &Transcript{}
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty transcript")
+	}
+	if err := json.Unmarshal(sc.Bytes(), &t.Header); err != nil || t.Header.Type != TypeHeader {
+		return nil, errors.New("not a transcript: missing header")
+	}
+	if t.Header.Version > Version {
+		return nil, fmt.Errorf("transcript version %d is newer than supported version %d", t.Header.Version, Version)
+	}
+
+	var pending error
+	for line := 2; sc.Scan(); line++ {
+		if pending != nil {
+			return nil, pending
+		}
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		e := &Entry{}
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil {
+			// 只有最后一行允许不完整
+			pending = fmt.Errorf("line %d: %w", line, err)
+			continue
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	return t, sc.Err()
+}
+
+// Event 把记录还原成 AgentEvent，用于交给 prints.EventRenderer 重新渲染
+// 消息是非流式的完整消息，中断信息还原为字符串
+func Event(r *prints.EventRecord) *adk.AgentEvent {
+	event := &adk.AgentEvent{AgentName: r.Agent, RunPath: runSteps(r.RunPath)}
+	if r.Role != "" {
+		m := &schema.Message{
+			Role:             r.Role,
+			Content:          r.Content,
+			ReasoningContent: r.Reasoning,
+			ToolName:         r.ToolName,
+			ToolCallID:       r.ToolCallID,
+		}
+		for _, tc := range r.ToolCalls {
+			m.ToolCalls = append(m.ToolCalls, schema.ToolCall{
+				ID:       tc.ID,
+				Type:     "function",
+				Function: schema.FunctionCall{Name: tc.Name, Arguments: tc.Arguments},
+			})
+		}
+		event.Output = &adk.AgentOutput{MessageOutput: &adk.MessageVariant{
+			Message: m, Role: r.Role, ToolName: r.ToolName,
+		}}
+	}
+	if r.TransferTo != "" || len(r.Interrupts) > 0 || r.Exit {
+		event.Action = &adk.AgentAction{Exit: r.Exit}
+		if r.TransferTo != "" {
+			event.Action.TransferToAgent = &adk.TransferToAgentAction{DestAgentName: r.TransferTo}
+		}
+		if len(r.Interrupts) > 0 {
+			info := &adk.InterruptInfo{}
+			for _, it := range r.Interrupts {
+				info.InterruptContexts = append(info.InterruptContexts, &adk.InterruptCtx{
+					ID: it.ID, IsRootCause: it.RootCause, Info: it.Info,
+				})
+			}
+			event.Action.Interrupted = info
+		}
+	}
+	if r.Error != "" {
+		event.Err = errors.New(r.Error)
+	}
+	return event
+}
+
+// runSteps 还原运行路径
+// adk.RunStep 没有导出的构造函数，只能通过它的 gob 编码还原，编码格式与 eino 中的 runStepSerialization 一致
+func runSteps(names []string) []adk.RunStep {
+	if len(names) == 0 {
+		return nil
+	}
+	steps := make([]adk.RunStep, 0, len(names))
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(struct{ AgentName string }{name}); err != nil {
+			return nil
+		}
+		var step adk.RunStep
+		if err := step.GobDecode(buf.Bytes()); err != nil {
+			return nil
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
@@ -0,0 +1,117 @@
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"eino-learn/adk/common/prints"
+)
+
+// 导出格式
+const (
+	FormatText     = prints.FormatText
+	FormatMarkdown = prints.FormatMarkdown
+	FormatHTML     = "html"
+)
+
+// actionLabels 用户选择的显示名称
+var actionLabels = map[string]string{
+	ActionContinue:  "继续下一轮迭代",
+	ActionFeedback:  "提供反馈",
+	ActionEditQuery: "修改问题",
+	ActionDetails:   "查看详情",
+	ActionRewind:    "回到之前的迭代",
+	ActionQuit:      "退出循环",
+}
+
+// ActionLabel 用户选择的显示名称，未知的选择原样返回
+func ActionLabel(action string) string {
+	if l, ok := actionLabels[action]; ok {
+		return l
+	}
+	return action
+}
+
+// Render 按格式重新渲染会话记录：text 与运行时的终端输出相同，markdown 和 html 便于在评审中分享
+// 事件交给 prints 中对应格式的 EventRenderer 渲染，用户输入和选择插在事件之间
+func Render(w io.Writer, t *Transcript, format string) error {
+	var header, input, choice func(w io.Writer, e *Entry) error
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		format = FormatText
+		header = func(w io.Writer, _ *Entry) error {
+			_, err := fmt.Fprintf(w, "transcript: %s\napp: %s\ncreated: %s\n\n", t.Header.ID, t.Header.App, t.Header.CreatedAt.Local().Format(time.DateTime))
+			return err
+		}
+		input = func(w io.Writer, e *Entry) error {
+			_, err := fmt.Fprintf(w, "user: %s\nsession: %s\n\n", e.Text, e.SessionID)
+			return err
+		}
+		choice = func(w io.Writer, e *Entry) error {
+			_, err := fmt.Fprintf(w, "choice: 第 %d 轮后：%s\n", e.Round, ActionLabel(e.Action))
+			if err == nil && e.Text != "" {
+				_, err = fmt.Fprintf(w, "text: %s\n", e.Text)
+			}
+			if err == nil {
+				_, err = fmt.Fprintln(w)
+			}
+			return err
+		}
+	case FormatMarkdown, "md":
+		format = FormatMarkdown
+		header = func(w io.Writer, _ *Entry) error {
+			_, err := fmt.Fprintf(w, "# 会话记录\n\n- ID: `%s`\n- 应用: %s\n- 时间: %s\n\n", t.Header.ID, t.Header.App, t.Header.CreatedAt.Local().Format(time.DateTime))
+			return err
+		}
+		input = func(w io.Writer, e *Entry) error {
+			_, err := fmt.Fprintf(w, "## 用户\n\n%s\n\n", e.Text)
+			return err
+		}
+		choice = func(w io.Writer, e *Entry) error {
+			s := fmt.Sprintf("**第 %d 轮后：%s**", e.Round, ActionLabel(e.Action))
+			if e.Text != "" {
+				s += " " + e.Text
+			}
+			_, err := fmt.Fprintf(w, "---\n\n%s\n\n", s)
+			return err
+		}
+	case FormatHTML:
+		return WriteHTML(w, t)
+	default:
+		return fmt.Errorf("unknown transcript format %q", format)
+	}
+
+	if err := header(w, nil); err != nil {
+		return err
+	}
+	var r prints.EventRenderer
+	for _, e := range t.Entries {
+		var err error
+		switch e.Type {
+		case TypeEvent:
+			if e.Event == nil {
+				continue
+			}
+			if r == nil {
+				if r, err = prints.NewRenderer(format, w); err != nil {
+					return err
+				}
+			}
+			err = r.Render(Event(e.Event))
+		case TypeInput:
+			err = input(w, e)
+		case TypeChoice:
+			err = choice(w, e)
+		}
+		if err != nil {
+			return err
+		}
+		// 用户输入之后重新开始，Markdown 中下一个 Agent 的标题不会被省略
+		if e.Type != TypeEvent {
+			r = nil
+		}
+	}
+	return nil
+}
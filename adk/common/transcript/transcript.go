@@ -0,0 +1,238 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"eino-learn/adk/common/prints"
+	"eino-learn/adk/common/stream"
+)
+
+// Version 会话记录的格式版本，字段不兼容地变化时加一
+const Version = 1
+
+// 记录类型
+const (
+	TypeHeader = "transcript" // 文件第一行，记录格式版本和会话信息
+	TypeInput  = "input"      // 用户的问题，每次 runner.Run 之前记录
+	TypeChoice = "choice"     // 用户在两轮之间的选择，例如继续、提供反馈、修改问题
+	TypeEvent  = "event"      // 一个 AgentEvent，流式消息合并成完整消息
+)
+
+// 用户的选择
+const (
+	ActionContinue  = "continue"   // 继续下一轮迭代
+	ActionFeedback  = "feedback"   // 提供反馈，Text 为反馈内容
+	ActionEditQuery = "edit_query" // 修改问题，Text 为新问题
+	ActionDetails   = "details"    // 查看详情
+	ActionRewind    = "rewind"     // 回到之前的迭代，Round 为回到的轮数，Text 为新反馈
+	ActionQuit      = "quit"       // 退出
+)
+
+// Header 会话记录文件的第一行
+type Header struct {
+	Type      string    `json:"type"`
+	Version   int       `json:"version"`
+	ID        string    `json:"id"`
+	App       string    `json:"app,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Entry 会话记录中的一行
+type Entry struct {
+	Type      string              `json:"type"`
+	Time      time.Time           `json:"time"`
+	SessionID string              `json:"session_id,omitempty"` // 检查点会话 ID，修改问题或回到之前的迭代后会变化
+	Round     int                 `json:"round,omitempty"`
+	Action    string              `json:"action,omitempty"`
+	Text      string              `json:"text,omitempty"` // 问题、反馈或新问题
+	Event     *prints.EventRecord `json:"event,omitempty"`
+}
+
+// Recorder 把一次运行中的用户输入、选择和所有 AgentEvent 按顺序写成 JSON Lines
+//
+// 方法都可以在 nil 上调用，此时不记录任何内容，调用方不需要判断是否开启了记录
+type Recorder struct {
+	mu     sync.Mutex
+	enc    *json.Encoder // 由 Create 创建时，第一次写入前为 nil
+	closer io.Closer
+	dir    string
+	app    string
+	path   string
+	err    error
+	wg     sync.WaitGroup
+}
+
+// NewRecorder 写入文件头并返回 Recorder，closer 不为 nil 时 Close 会关闭它
+func NewRecorder(w io.Writer, closer io.Closer, app string) (*Recorder, error) {
+	r := &Recorder{closer: closer}
+	if err := r.start(w, uuid.NewString(), app); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Create 返回在 dir 下记录的 Recorder，文件在记录第一个问题时才创建，没有输入问题就退出时不留下文件
+// 文件名为创建时间、应用名和会话记录 ID 的前 8 位
+func Create(dir, app string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create transcript dir: %w", err)
+	}
+	return &Recorder{dir: dir, app: app}, nil
+}
+
+// NewRecorderFromEnv 设置了 TRANSCRIPT_DIR 时在该目录下创建会话记录，未设置时返回 nil
+func NewRecorderFromEnv(app string) (*Recorder, error) {
+	dir := os.Getenv("TRANSCRIPT_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	return Create(dir, app)
+}
+
+// Path 会话记录文件的路径，不是由 Create 创建或者还没有写入文件时为空
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.path
+}
+
+// Input 记录一个新问题，sessionID 为这次运行使用的检查点 ID
+func (r *Recorder) Input(sessionID, query string) {
+	r.write(&Entry{Type: TypeInput, SessionID: sessionID, Text: query})
+}
+
+// Choice 记录用户在第 round 轮结束后的选择
+func (r *Recorder) Choice(sessionID string, round int, action, text string) {
+	r.write(&Entry{Type: TypeChoice, SessionID: sessionID, Round: round, Action: action, Text: text})
+}
+
+// Wrap 返回一个内容相同的迭代器，经过它的每个事件都会被记录
+//
+// 流式消息复制一份后立即交给调用方，不影响边接收边输出；记录在读完副本后写入，
+// 返回的迭代器结束时这一轮的事件已经全部写入
+func (r *Recorder) Wrap(iter *adk.AsyncIterator[*adk.AgentEvent]) *adk.AsyncIterator[*adk.AgentEvent] {
+	if r == nil {
+		return iter
+	}
+	out, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer gen.Close()
+		for {
+			event, ok := iter.Next()
+			if !ok {
+				return
+			}
+			var s *schema.StreamReader[*schema.Message]
+			var m *schema.Message
+			if event.Output != nil && event.Output.MessageOutput != nil {
+				mo := event.Output.MessageOutput
+				if mo.MessageStream != nil {
+					ss := mo.MessageStream.Copy(2)
+					mo.MessageStream, s = ss[1], ss[0]
+				} else {
+					m = mo.Message
+				}
+			}
+			gen.Send(event)
+
+			var streamErr error
+			if s != nil {
+				m, streamErr = stream.Collect(s, nil)
+			}
+			rec := prints.NewEventRecord(event, m)
+			if streamErr != nil && rec.Error == "" {
+				rec.Error = streamErr.Error()
+			}
+			r.write(&Entry{Type: TypeEvent, Time: rec.Time, Event: rec})
+		}
+	}()
+	return out
+}
+
+func (r *Recorder) write(e *Entry) {
+	if r == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if r.enc == nil {
+		if r.err = r.create(); r.err != nil {
+			return
+		}
+	}
+	if err := r.enc.Encode(e); err != nil {
+		r.err = fmt.Errorf("write transcript: %w", err)
+	}
+}
+
+// create 在 dir 下创建会话记录文件并写入文件头，调用方需持有锁
+// 文件名带上 ID 的前缀，同一秒内启动的多次运行不会冲突
+func (r *Recorder) create() error {
+	id := uuid.NewString()
+	name := time.Now().Format("20060102-150405")
+	if r.app != "" {
+		name += "-" + r.app
+	}
+	path := filepath.Join(r.dir, name+"-"+id[:8]+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("create transcript: %w", err)
+	}
+	if err := r.start(f, id, r.app); err != nil {
+		f.Close()
+		return err
+	}
+	r.closer, r.path = f, path
+	return nil
+}
+
+// start 写入文件头，之后的记录都写到 w
+func (r *Recorder) start(w io.Writer, id, app string) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	h := &Header{Type: TypeHeader, Version: Version, ID: id, App: app, CreatedAt: time.Now()}
+	if err := enc.Encode(h); err != nil {
+		return fmt.Errorf("write transcript header: %w", err)
+	}
+	r.enc = enc
+	return nil
+}
+
+// Close 等待还在记录的事件写完后关闭文件，返回记录过程中的第一个写入错误
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+		r.closer = nil
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package transcript
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// events 一轮运行中的事件：流式的思考、回答和工具调用，非流式的工具返回，以及中断
+func events() []*adk.AgentEvent {
+	idx := 0
+	sr := schema.StreamReaderFromArray([]*schema.Message{
+		{Role: schema.Assistant, ReasoningContent: "先查资料"},
+		schema.AssistantMessage("马上", nil),
+		schema.AssistantMessage("查询", nil),
+		schema.AssistantMessage("", []schema.ToolCall{{Index: &idx, ID: "call_1", Function: schema.FunctionCall{Name: "search", Arguments: `{"q":`}}}),
+		schema.AssistantMessage("", []schema.ToolCall{{Index: &idx, Function: schema.FunctionCall{Arguments: `"eino"}`}}}),
+	})
+	reply := adk.EventFromMessage(nil, sr, schema.Assistant, "")
+	toolResp := adk.EventFromMessage(schema.ToolMessage("<b>结果</b>", "call_1", schema.WithToolName("search")), nil, schema.Tool, "search")
+	interrupt := &adk.AgentEvent{Action: &adk.AgentAction{Interrupted: &adk.InterruptInfo{InterruptContexts: []*adk.InterruptCtx{
+		{ID: "agent:writer;tool:call_1", Info: "需要确认", IsRootCause: true},
+	}}}}
+	all := []*adk.AgentEvent{reply, toolResp, interrupt}
+	for _, e := range all {
+		e.AgentName = "writer"
+		e.RunPath = runSteps([]string{"loop", "writer"})
+	}
+	return all
+}
+
+// record 用 Recorder 记录一轮运行，调用方边接收边读完流式消息，返回写入的文件路径
+func record(t *testing.T) string {
+	t.Helper()
+	r, err := Create(t.TempDir(), "loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Input("session-1", "介绍一下 eino")
+
+	iter, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	go func() {
+		defer gen.Close()
+		for _, e := range events() {
+			gen.Send(e)
+		}
+	}()
+	wrapped := r.Wrap(iter)
+	for {
+		event, ok := wrapped.Next()
+		if !ok {
+			break
+		}
+		// 记录不影响调用方读取完整的流
+		if event.Output != nil && event.Output.MessageOutput.IsStreaming {
+			m, _, err := adk.GetMessage(event)
+			if err != nil || m.Content != "马上查询" {
+				t.Fatalf("GetMessage = %v, %v", m, err)
+			}
+		}
+	}
+	r.Choice("session-1", 1, ActionFeedback, "再详细一点")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Path() == "" {
+		t.Fatal("Path is empty after recording")
+	}
+	return r.Path()
+}
+
+func TestRecordReadRender(t *testing.T) {
+	path := record(t)
+	if name := filepath.Base(path); !strings.Contains(name, "-loop-") || !strings.HasSuffix(name, ".jsonl") {
+		t.Errorf("file name = %q", name)
+	}
+	tr, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Header.Version != Version || tr.Header.App != "loop" || tr.Header.ID == "" {
+		t.Errorf("header = %+v", tr.Header)
+	}
+
+	var types []string
+	for _, e := range tr.Entries {
+		types = append(types, e.Type)
+	}
+	if got := strings.Join(types, ","); got != "input,event,event,event,choice" {
+		t.Fatalf("entries = %s", got)
+	}
+	if in := tr.Entries[0]; in.SessionID != "session-1" || in.Text != "介绍一下 eino" {
+		t.Errorf("input = %+v", in)
+	}
+	reply := tr.Entries[1].Event
+	if reply.Content != "马上查询" || reply.Reasoning != "先查资料" || !reply.Streaming ||
+		len(reply.ToolCalls) != 1 || reply.ToolCalls[0].Arguments != `{"q":"eino"}` ||
+		strings.Join(reply.RunPath, "/") != "loop/writer" {
+		t.Errorf("reply = %+v", reply)
+	}
+	if tool := tr.Entries[2].Event; tool.Role != schema.Tool || tool.ToolName != "search" || tool.ToolCallID != "call_1" {
+		t.Errorf("tool response = %+v", tool)
+	}
+	if in := tr.Entries[3].Event.Interrupts; len(in) != 1 || in[0].Info != "需要确认" || !in[0].RootCause {
+		t.Errorf("interrupts = %+v", in)
+	}
+	if c := tr.Entries[4]; c.Round != 1 || c.Action != ActionFeedback || c.Text != "再详细一点" {
+		t.Errorf("choice = %+v", c)
+	}
+
+	// 还原出的事件保留运行路径
+	if e := Event(reply); len(e.RunPath) != 2 || e.RunPath[1].String() != "writer" {
+		t.Errorf("RunPath = %v", e.RunPath)
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{FormatText, []string{
+			"app: loop\n",
+			"user: 介绍一下 eino\nsession: session-1\n",
+			"\nreasoning: 先查资料\nanswer: 马上查询\ntool name: search\narguments: {\"q\":\"eino\"}\n",
+			"\ntool response: <b>结果</b>\n",
+			"\n需要确认\n",
+			"choice: 第 1 轮后：提供反馈\ntext: 再详细一点\n",
+		}},
+		{FormatMarkdown, []string{
+			"- 应用: loop\n",
+			"## 用户\n\n介绍一下 eino\n\n## writer\n\n> 先查资料\n\n马上查询\n\n",
+			"**调用工具** `search`\n\n```json\n{\n  \"q\": \"eino\"\n}\n```\n",
+			"**工具返回** `search`\n\n```\n<b>结果</b>\n```\n",
+			"**中断**\n\n> 需要确认\n",
+			"---\n\n**第 1 轮后：提供反馈** 再详细一点\n",
+		}},
+		{FormatHTML, []string{
+			"<title>会话记录 loop ",
+			"<div class=\"text\">介绍一下 eino</div>",
+			"<summary>思考过程</summary><div class=\"text\">先查资料</div>",
+			// 工具返回需要转义
+			"<summary>工具返回 search</summary><pre>&lt;b&gt;结果&lt;/b&gt;</pre>",
+			"⏸ 中断：<span class=\"text\">需要确认</span>",
+			"第 1 轮后：提供反馈",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tr, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+	if err := Render(&bytes.Buffer{}, tr, "pdf"); err == nil {
+		t.Error("Render(pdf) should fail")
+	}
+}
+
+// TestReadTruncatedLastLine 进程中止时最后一行可能不完整，读取时忽略；中间的行损坏则报错
+func TestReadTruncatedLastLine(t *testing.T) {
+	data, err := os.ReadFile(record(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := append(bytes.Clone(data), `{"type":"event","time":"2026-10`...)
+	tr, err := Read(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatalf("Read truncated: %v", err)
+	}
+	if len(tr.Entries) != 5 {
+		t.Errorf("got %d entries, want 5", len(tr.Entries))
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	lines[2] = lines[2][:len(lines[2])/2] + "\n"
+	if _, err := Read(strings.NewReader(strings.Join(lines, ""))); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("Read with corrupt line 3 = %v, want an error for line 3", err)
+	}
+}
+
+func TestReadRejectsBadHeader(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"empty", ""},
+		{"no header", `{"type":"input","text":"hi"}` + "\n"},
+		{"newer version", `{"type":"transcript","version":99,"id":"x"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.data)); err == nil {
+				t.Fatal("Read should fail")
+			}
+		})
+	}
+}
+
+// TestCreateWithoutInput 没有记录任何内容就关闭时不创建文件
+func TestCreateWithoutInput(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Create(dir, "loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 || r.Path() != "" {
+		t.Fatalf("files = %v, path = %q", files, r.Path())
+	}
+}
+
+// TestNilRecorder 未开启记录时所有方法都不做任何事
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Input("s", "q")
+	r.Choice("s", 1, ActionQuit, "")
+	iter, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	gen.Close()
+	if r.Wrap(iter) != iter {
+		t.Error("nil Recorder should return the iterator unchanged")
+	}
+	if err := r.Close(); err != nil || r.Path() != "" {
+		t.Fatalf("Close = %v, Path = %q", err, r.Path())
+	}
+}
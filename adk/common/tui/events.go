@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/prints"
+	"eino-learn/adk/common/stream"
+)
+
+// 事件读取 goroutine 发给界面的消息，一个 AgentEvent 会拆成多条
+type (
+	// agentMsg 一个事件开始，用于更新运行路径
+	agentMsg struct {
+		agent string
+		path  []string
+	}
+	// chunkMsg 流式回答或思考过程的一段
+	chunkMsg struct {
+		reasoning bool
+		text      string
+	}
+	// messageMsg 一条完整的非工具消息，流式消息在流结束后发送
+	messageMsg struct {
+		msg *schema.Message
+	}
+	// toolCallMsg 模型发起的一次工具调用
+	toolCallMsg struct {
+		agent string
+		call  schema.ToolCall
+	}
+	// toolResultMsg 工具返回的结果，按 ToolCallID 对应到调用
+	toolResultMsg struct {
+		id, name, content string
+	}
+	transferMsg struct {
+		from, to string
+	}
+	// interruptMsg 中断，id 为根源中断点的 ID，恢复运行时使用
+	interruptMsg struct {
+		id, info string
+	}
+	exitMsg  struct{}
+	errorMsg struct {
+		err error
+	}
+	// roundDoneMsg 一轮运行的事件读完
+	roundDoneMsg struct{}
+)
+
+// pump 读取一轮运行的事件，转成界面消息发到 ch，读完后发送 roundDoneMsg 并关闭 ch
+// ctx 结束时不再发送，直接返回
+func pump(ctx context.Context, iter *adk.AsyncIterator[*adk.AgentEvent], ch chan<- tea.Msg) {
+	defer close(ch)
+	send := func(msg tea.Msg) error {
+		select {
+		case ch <- msg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for {
+		event, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err := sendEvent(event, send); err != nil {
+			return
+		}
+	}
+	_ = send(roundDoneMsg{})
+}
+
+func sendEvent(event *adk.AgentEvent, send func(tea.Msg) error) error {
+	if err := send(agentMsg{agent: event.AgentName, path: runPath(event)}); err != nil {
+		return err
+	}
+	if event.Output != nil && event.Output.MessageOutput != nil {
+		mo := event.Output.MessageOutput
+		// 工具结果显示在工具面板中，不逐段输出
+		var onChunk stream.ChunkFunc
+		if mo.Role != schema.Tool {
+			onChunk = func(chunk *schema.Message) error {
+				if chunk.ReasoningContent != "" {
+					if err := send(chunkMsg{reasoning: true, text: chunk.ReasoningContent}); err != nil {
+						return err
+					}
+				}
+				if chunk.Content != "" {
+					return send(chunkMsg{text: chunk.Content})
+				}
+				return nil
+			}
+		}
+		m, err := stream.EventMessage(event, onChunk)
+		if err != nil {
+			return send(errorMsg{err: err})
+		}
+		if m != nil {
+			if m.Role == schema.Tool {
+				if err := send(toolResultMsg{id: m.ToolCallID, name: m.ToolName, content: m.Content}); err != nil {
+					return err
+				}
+			} else {
+				if !mo.IsStreaming {
+					// 非流式消息没有经过 onChunk，整条作为一段输出
+					if m.ReasoningContent != "" {
+						if err := send(chunkMsg{reasoning: true, text: m.ReasoningContent}); err != nil {
+							return err
+						}
+					}
+					if m.Content != "" {
+						if err := send(chunkMsg{text: m.Content}); err != nil {
+							return err
+						}
+					}
+				}
+				if err := send(messageMsg{msg: m}); err != nil {
+					return err
+				}
+				for _, tc := range m.ToolCalls {
+					if err := send(toolCallMsg{agent: event.AgentName, call: tc}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	if a := event.Action; a != nil {
+		if a.TransferToAgent != nil {
+			if err := send(transferMsg{from: event.AgentName, to: a.TransferToAgent.DestAgentName}); err != nil {
+				return err
+			}
+		}
+		if a.Interrupted != nil {
+			msg := interruptMsg{}
+			var infos []string
+			for _, ic := range a.Interrupted.InterruptContexts {
+				if ic.IsRootCause {
+					msg.id = ic.ID
+					infos = append(infos, prints.InterruptInfo(ic))
+				}
+			}
+			msg.info = strings.Join(infos, "\n")
+			if err := send(msg); err != nil {
+				return err
+			}
+		}
+		if a.Exit {
+			if err := send(exitMsg{}); err != nil {
+				return err
+			}
+		}
+	}
+	if event.Err != nil {
+		return send(errorMsg{err: event.Err})
+	}
+	return nil
+}
+
+func runPath(event *adk.AgentEvent) []string {
+	path := make([]string, 0, len(event.RunPath))
+	for i := range event.RunPath {
+		path = append(path, event.RunPath[i].String())
+	}
+	return path
+}
@@ -0,0 +1,442 @@
+package tui
+
+import (
+	"context"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/stream"
+	"eino-learn/adk/common/usage"
+)
+
+// state 界面所处的阶段，决定哪些按键可用
+type state int
+
+const (
+	stateQuery    state = iota // 输入问题
+	stateRunning               // 运行中，流式输出
+	stateWaiting               // 人工反馈智能体中断，等待用户选择
+	stateFeedback              // 输入反馈
+	stateDone                  // 运行结束，只能修改问题或退出
+)
+
+// roundMsg 带轮次编号的事件消息，丢弃已经取消的轮次发来的消息
+type roundMsg struct {
+	round int
+	msg   tea.Msg
+}
+
+type model struct {
+	ctx     context.Context
+	session Session
+	opts    Options
+
+	state     state
+	prevState state // 输入问题时按 esc 回到的阶段
+	width     int
+	height    int
+
+	answerView viewport.Model
+	toolView   viewport.Model
+	input      textinput.Model
+
+	blocks      []*block
+	tools       []*toolEntry
+	showTools   bool
+	toolFocus   bool
+	toolCursor  int
+	agent       string   // 当前事件所属的 Agent
+	path        []string // 当前事件的运行路径
+	needHeader  bool     // 下一段输出前先显示 Agent 名称
+	textClosed  bool     // 上一条消息已结束，下一段输出另起一块
+	answer      string   // 最近一条非空回答
+	interruptID string
+	exited      bool
+	iteration   int
+
+	summary *usage.Summary
+	tokens  schema.TokenUsage // Session 不提供用量时按消息累加
+
+	round  int
+	events <-chan tea.Msg
+	cancel context.CancelFunc
+}
+
+// toolEntry 工具面板中的一次工具调用及其结果
+type toolEntry struct {
+	id, name, agent string
+	args, result    string
+	done            bool
+	expanded        bool
+}
+
+func newModel(ctx context.Context, s Session, opts Options) *model {
+	in := textinput.New()
+	in.Prompt = "> "
+	m := &model{
+		ctx:        ctx,
+		session:    s,
+		opts:       opts,
+		input:      in,
+		answerView: viewport.New(0, 0),
+		toolView:   viewport.New(0, 0),
+		showTools:  true,
+	}
+	if opts.InterruptID != "" {
+		m.addBlock(blockQuery, opts.Query)
+		if opts.Answer != "" {
+			m.addBlock(blockAnswer, opts.Answer)
+		}
+		m.answer, m.interruptID, m.iteration = opts.Answer, opts.InterruptID, 1
+		m.state = stateWaiting
+		return m
+	}
+	if len(opts.Examples) > 0 {
+		m.addBlock(blockNotice, "查询示例：\n"+numbered(opts.Examples))
+	}
+	m.askQuery()
+	return m
+}
+
+func (m *model) Init() tea.Cmd {
+	if m.opts.Query != "" && m.opts.InterruptID == "" {
+		return m.startQuery(m.opts.Query)
+	}
+	return textinput.Blink
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	case roundMsg:
+		if msg.round != m.round {
+			return m, nil
+		}
+		m.handleEvent(msg.msg)
+		if _, done := msg.msg.(roundDoneMsg); done {
+			return m, nil
+		}
+		return m, m.listen()
+	}
+	if m.state == stateQuery || m.state == stateFeedback {
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if key == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	switch m.state {
+	case stateQuery, stateFeedback:
+		switch key {
+		case "enter":
+			value := strings.TrimSpace(m.input.Value())
+			if m.state == stateFeedback {
+				return m, m.resume(value)
+			}
+			if value == "" {
+				value = m.opts.DefaultQuery
+			}
+			if value == "" {
+				return m, nil
+			}
+			return m, m.startQuery(value)
+		case "esc":
+			if m.state == stateFeedback {
+				m.state = stateWaiting
+				return m, nil
+			}
+			if m.iteration == 0 {
+				return m, tea.Quit
+			}
+			m.state = m.prevState
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch key {
+	case "q":
+		return m, tea.Quit
+	case "t":
+		m.showTools = !m.showTools
+		if !m.showTools {
+			m.toolFocus = false
+		}
+		m.layout()
+		return m, nil
+	case "tab":
+		if m.showTools {
+			m.toolFocus = !m.toolFocus
+			m.refreshTools()
+		}
+		return m, nil
+	case "up", "k":
+		m.scroll(-1)
+		return m, nil
+	case "down", "j":
+		m.scroll(1)
+		return m, nil
+	case "pgup":
+		m.answerView.PageUp()
+		return m, nil
+	case "pgdown":
+		m.answerView.PageDown()
+		return m, nil
+	case "home":
+		m.answerView.GotoTop()
+		return m, nil
+	case "end":
+		m.answerView.GotoBottom()
+		return m, nil
+	}
+	if m.toolFocus && (key == "enter" || key == " ") {
+		if m.toolCursor < len(m.tools) {
+			t := m.tools[m.toolCursor]
+			t.expanded = !t.expanded
+			m.refreshTools()
+		}
+		return m, nil
+	}
+
+	switch m.state {
+	case stateWaiting:
+		switch key {
+		case "c", "enter":
+			return m, m.resume("")
+		case "f":
+			m.state = stateFeedback
+			m.input.Placeholder = "输入反馈意见，enter 提交，esc 返回"
+			m.input.SetValue("")
+			return m, m.input.Focus()
+		case "e":
+			return m, m.editQuery()
+		}
+	case stateDone:
+		if key == "e" {
+			return m, m.editQuery()
+		}
+	}
+	return m, nil
+}
+
+// scroll 回答面板有焦点时滚动回答，否则在工具调用之间移动
+func (m *model) scroll(n int) {
+	if !m.toolFocus {
+		if n < 0 {
+			m.answerView.ScrollUp(-n)
+		} else {
+			m.answerView.ScrollDown(n)
+		}
+		return
+	}
+	m.toolCursor = max(0, min(len(m.tools)-1, m.toolCursor+n))
+	m.refreshTools()
+}
+
+func (m *model) handleEvent(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case agentMsg:
+		if msg.agent != m.agent {
+			m.agent, m.needHeader = msg.agent, true
+		}
+		m.path = msg.path
+	case chunkMsg:
+		kind := blockAnswer
+		if msg.reasoning {
+			kind = blockReasoning
+		}
+		m.appendText(kind, msg.text)
+	case messageMsg:
+		m.textClosed = true
+		if msg.msg.Content != "" && msg.msg.Role != schema.User {
+			m.answer = msg.msg.Content
+		}
+		if u := stream.Usage(msg.msg); u != nil {
+			m.tokens.PromptTokens += u.PromptTokens
+			m.tokens.CompletionTokens += u.CompletionTokens
+			m.tokens.TotalTokens += u.TotalTokens
+		}
+		m.summary = m.session.Usage()
+	case toolCallMsg:
+		m.tools = append(m.tools, &toolEntry{
+			id: msg.call.ID, name: msg.call.Function.Name, agent: msg.agent, args: msg.call.Function.Arguments,
+		})
+		m.addBlock(blockTool, "→ 调用工具 "+msg.call.Function.Name)
+		m.refreshTools()
+	case toolResultMsg:
+		t := m.findTool(msg.id, msg.name)
+		if t == nil {
+			t = &toolEntry{id: msg.id, name: msg.name, agent: m.agent}
+			m.tools = append(m.tools, t)
+		}
+		t.result, t.done = msg.content, true
+		m.addBlock(blockTool, "← "+msg.name+" 已返回")
+		m.summary = m.session.Usage()
+		m.refreshTools()
+	case transferMsg:
+		m.addBlock(blockNotice, "⇢ "+msg.from+" 转交给 "+msg.to)
+	case interruptMsg:
+		m.interruptID = msg.id
+		if msg.info != "" {
+			m.addBlock(blockNotice, msg.info)
+		}
+	case exitMsg:
+		m.exited = true
+	case errorMsg:
+		m.addBlock(blockError, "错误: "+msg.err.Error())
+	case roundDoneMsg:
+		m.stopRound()
+		m.summary = m.session.Usage()
+		switch {
+		case m.exited:
+			m.addBlock(blockNotice, "✓ 智能体认为已完成任务")
+			m.state = stateDone
+		case m.interruptID != "":
+			m.state = stateWaiting
+		default:
+			m.state = stateDone
+		}
+	}
+}
+
+// appendText 同一个 Agent 的同一条消息的连续输出合并到一块中
+func (m *model) appendText(kind blockKind, text string) {
+	if m.needHeader {
+		m.addBlock(blockAgent, m.agent)
+		m.needHeader = false
+	}
+	if last := m.lastBlock(); last != nil && last.kind == kind && !m.textClosed {
+		last.text += text
+		last.wrapped = ""
+		m.refreshAnswer()
+		return
+	}
+	m.textClosed = false
+	m.addBlock(kind, text)
+}
+
+// findTool 按 ID 查找还没有结果的工具调用，没有 ID 时按名称查找
+func (m *model) findTool(id, name string) *toolEntry {
+	for i := len(m.tools) - 1; i >= 0; i-- {
+		t := m.tools[i]
+		if t.done {
+			continue
+		}
+		if (id != "" && t.id == id) || (id == "" && t.name == name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// startQuery 开始一个新问题，清空之前的输出
+func (m *model) startQuery(query string) tea.Cmd {
+	m.blocks, m.tools, m.toolCursor = nil, nil, 0
+	m.answer, m.iteration, m.tokens, m.summary = "", 0, schema.TokenUsage{}, nil
+	m.agent, m.path = "", nil
+	m.addBlock(blockQuery, query)
+	return m.startRound(func(ctx context.Context) (*adk.AsyncIterator[*adk.AgentEvent], error) {
+		return m.session.Run(ctx, query)
+	})
+}
+
+// resume 从中断点恢复，feedback 为空时直接继续下一轮
+func (m *model) resume(feedback string) tea.Cmd {
+	id := m.interruptID
+	if feedback != "" {
+		m.addBlock(blockQuery, "反馈: "+feedback)
+	}
+	return m.startRound(func(ctx context.Context) (*adk.AsyncIterator[*adk.AgentEvent], error) {
+		return m.session.Resume(ctx, id, feedback)
+	})
+}
+
+func (m *model) startRound(start func(ctx context.Context) (*adk.AsyncIterator[*adk.AgentEvent], error)) tea.Cmd {
+	m.stopRound()
+	m.round++
+	m.iteration++
+	m.interruptID, m.exited, m.needHeader, m.textClosed = "", false, m.agent != "", true
+	m.input.Blur()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	iter, err := start(ctx)
+	if err != nil {
+		cancel()
+		m.addBlock(blockError, "错误: "+err.Error())
+		m.state = stateDone
+		return nil
+	}
+	ch := make(chan tea.Msg, 64)
+	go pump(ctx, iter, ch)
+	m.events, m.cancel = ch, cancel
+	m.state = stateRunning
+	m.refreshAnswer()
+	return m.listen()
+}
+
+// listen 等待当前轮次的下一条消息
+func (m *model) listen() tea.Cmd {
+	ch, round := m.events, m.round
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return roundMsg{round: round, msg: msg}
+	}
+}
+
+// stopRound 取消还在进行的一轮运行
+func (m *model) stopRound() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+func (m *model) editQuery() tea.Cmd {
+	m.prevState = m.state
+	m.askQuery()
+	return m.input.Focus()
+}
+
+func (m *model) askQuery() {
+	m.state = stateQuery
+	m.input.Placeholder = "输入问题或任务，enter 开始"
+	if m.opts.DefaultQuery != "" {
+		m.input.Placeholder = "输入问题或任务，留空使用默认示例：" + m.opts.DefaultQuery
+	}
+	m.input.SetValue("")
+	m.input.Focus()
+}
+
+func (m *model) addBlock(kind blockKind, text string) {
+	m.blocks = append(m.blocks, &block{kind: kind, text: text})
+	m.refreshAnswer()
+}
+
+func (m *model) lastBlock() *block {
+	if len(m.blocks) == 0 {
+		return nil
+	}
+	return m.blocks[len(m.blocks)-1]
+}
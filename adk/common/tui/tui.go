@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cloudwego/eino/adk"
+
+	"eino-learn/adk/common/usage"
+)
+
+// Session 一个可以中断、恢复的 Agent 会话，TUI 通过它发起运行并读取 AgentEvent
+//
+// Run 开始一个新问题，之前的会话作废；Resume 从中断点恢复，feedback 为空表示不提反馈直接继续
+// Usage 返回当前问题累计的用量，返回 nil 时按事件中消息的 token 用量累加显示
+type Session interface {
+	Run(ctx context.Context, query string) (*adk.AsyncIterator[*adk.AgentEvent], error)
+	Resume(ctx context.Context, interruptID, feedback string) (*adk.AsyncIterator[*adk.AgentEvent], error)
+	Usage() *usage.Summary
+}
+
+// Options 界面选项
+type Options struct {
+	Title        string
+	Examples     []string  // 输入问题时显示的示例
+	DefaultQuery string    // 问题留空时使用
+	Query        string    // 不为空时跳过输入，直接开始运行
+	Input        io.Reader // 默认标准输入
+	Output       io.Writer // 默认标准输出
+
+	// InterruptID 不为空时恢复已有的会话：显示 Query 和 Answer 后直接等待用户选择，不调用 Session.Run
+	InterruptID string
+	Answer      string // 恢复会话时上一轮的回答
+}
+
+// Run 以全屏终端界面运行会话，直到用户退出或 ctx 结束，返回最后一轮的回答
+//
+// 按键：
+//
+//	c / enter  继续下一轮迭代        f  提供反馈后继续
+//	e          修改问题重新开始      q  退出（运行中会先取消本轮）
+//	t          显示 / 隐藏工具面板    tab  在回答和工具面板之间切换焦点
+//	↑ ↓ pgup pgdown  滚动回答或选择工具   enter（工具面板）展开 / 折叠工具调用详情
+func Run(ctx context.Context, s Session, opts Options) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m := newModel(ctx, s, opts)
+	popts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithContext(ctx)}
+	if opts.Input != nil {
+		popts = append(popts, tea.WithInput(opts.Input))
+	}
+	if opts.Output != nil {
+		popts = append(popts, tea.WithOutput(opts.Output))
+	}
+	final, err := tea.NewProgram(m, popts...).Run()
+	if fm, ok := final.(*model); ok {
+		// 退出时取消还在进行的一轮运行
+		fm.stopRound()
+		m = fm
+	}
+	if err != nil {
+		return m.answer, fmt.Errorf("run tui: %w", err)
+	}
+	return m.answer, nil
+}
@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"eino-learn/adk/common/prints"
+)
+
+// blockKind 回答面板中一块内容的类型，决定显示样式
+type blockKind int
+
+const (
+	blockQuery     blockKind = iota // 用户的问题或反馈
+	blockAgent                      // Agent 名称，开始一段新的输出
+	blockReasoning                  // 思考过程
+	blockAnswer                     // 回答
+	blockTool                       // 工具调用的简要提示，详情在工具面板中
+	blockNotice                     // 转交、中断、完成等提示
+	blockError
+)
+
+// block 回答面板中的一块内容，按面板宽度折行后缓存
+type block struct {
+	kind    blockKind
+	text    string
+	wrapped string
+	width   int
+}
+
+var (
+	titleStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	pathStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	agentStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("13"))
+	queryStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	reasoningStyle = lipgloss.NewStyle().Faint(true).Italic(true)
+	toolStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	noticeStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	errorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	statusStyle    = lipgloss.NewStyle().Reverse(true)
+	helpStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	cursorStyle    = lipgloss.NewStyle().Reverse(true)
+	panelStyle     = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).MarginLeft(1).PaddingLeft(1)
+)
+
+func (b *block) render(width int) string {
+	if b.wrapped != "" && b.width == width {
+		return b.wrapped
+	}
+	var s string
+	switch b.kind {
+	case blockQuery:
+		s = queryStyle.Render(prints.Wrap("❯ "+b.text, width))
+	case blockAgent:
+		s = agentStyle.Render("● " + b.text)
+	case blockReasoning:
+		s = reasoningStyle.Render(prints.Wrap(b.text, width))
+	case blockTool:
+		s = toolStyle.Render(prints.Wrap(b.text, width))
+	case blockNotice:
+		s = noticeStyle.Render(prints.Wrap(b.text, width))
+	case blockError:
+		s = errorStyle.Render(prints.Wrap(b.text, width))
+	default:
+		s = prints.Wrap(b.text, width)
+	}
+	b.wrapped, b.width = s, width
+	return s
+}
+
+// 标题、状态和帮助各占一行
+const chromeHeight = 3
+
+// layout 按窗口大小分配回答面板和工具面板
+func (m *model) layout() {
+	if m.width == 0 {
+		return
+	}
+	h := max(1, m.height-chromeHeight)
+	aw := m.width
+	if m.showTools {
+		tw := max(24, m.width/3)
+		aw = max(10, m.width-tw)
+		m.toolView.Width, m.toolView.Height = max(1, m.width-aw-3), h
+	}
+	m.answerView.Width, m.answerView.Height = aw, h
+	m.input.Width = max(1, m.width-4)
+	m.refreshAnswer()
+	m.refreshTools()
+}
+
+// refreshAnswer 重新生成回答面板的内容，原来停在底部时继续跟随最新输出
+func (m *model) refreshAnswer() {
+	if m.answerView.Width == 0 {
+		return
+	}
+	follow := m.answerView.AtBottom() || m.answerView.TotalLineCount() == 0
+	parts := make([]string, 0, len(m.blocks))
+	for _, b := range m.blocks {
+		parts = append(parts, b.render(m.answerView.Width))
+	}
+	m.answerView.SetContent(strings.Join(parts, "\n"))
+	if follow {
+		m.answerView.GotoBottom()
+	}
+}
+
+// refreshTools 重新生成工具面板的内容，并保证选中的工具调用可见
+func (m *model) refreshTools() {
+	width := m.toolView.Width
+	if width == 0 {
+		return
+	}
+	var lines []string
+	cursorLine := 0
+	for i, t := range m.tools {
+		mark := "▸"
+		if t.expanded {
+			mark = "▾"
+		}
+		status := "…"
+		if t.done {
+			status = "✓"
+		}
+		title := fmt.Sprintf("%s %s %s", mark, status, t.name)
+		if t.agent != "" {
+			title += pathStyle.Render(" · " + t.agent)
+		}
+		if i == m.toolCursor {
+			cursorLine = len(lines)
+			if m.toolFocus {
+				title = cursorStyle.Render(title)
+			}
+		}
+		lines = append(lines, title)
+		if t.expanded {
+			lines = append(lines, pathStyle.Render("参数:"), prints.Wrap(prettyJSON(t.args), width))
+			if t.done {
+				lines = append(lines, pathStyle.Render("结果:"), prints.Wrap(t.result, width))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, pathStyle.Render("暂无工具调用"))
+	}
+	m.toolView.SetContent(strings.Join(lines, "\n"))
+	if cursorLine < m.toolView.YOffset || cursorLine >= m.toolView.YOffset+m.toolView.Height {
+		m.toolView.SetYOffset(cursorLine)
+	}
+}
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	line := lipgloss.NewStyle().MaxWidth(m.width)
+
+	header := titleStyle.Render(m.opts.Title) + pathStyle.Render(m.breadcrumb(m.width-lipgloss.Width(m.opts.Title)))
+
+	body := m.answerView.View()
+	if m.showTools {
+		panel := panelStyle.Height(m.toolView.Height).Render(m.toolView.View())
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, panel)
+	}
+
+	status := statusStyle.Width(m.width).Render(m.statusLine())
+
+	var footer string
+	if m.state == stateQuery || m.state == stateFeedback {
+		footer = m.input.View()
+	} else {
+		footer = helpStyle.Render(m.help())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, line.Render(header), body, line.Render(status), line.Render(footer))
+}
+
+// breadcrumb 当前的运行路径，超出 width 列时省略前面的 Agent，保证当前 Agent 可见
+func (m *model) breadcrumb(width int) string {
+	if len(m.path) == 0 {
+		return ""
+	}
+	for i := range m.path {
+		s := strings.Join(m.path[i:], " › ")
+		if i > 0 {
+			s = "… › " + s
+		}
+		if prints.Width(s)+2 <= width || i == len(m.path)-1 {
+			return "  " + s
+		}
+	}
+	return ""
+}
+
+// statusLine 运行状态、迭代轮数和 token 用量
+func (m *model) statusLine() string {
+	var st string
+	switch m.state {
+	case stateQuery:
+		st = "输入问题"
+	case stateRunning:
+		st = "运行中"
+	case stateWaiting:
+		st = "等待反馈"
+	case stateFeedback:
+		st = "输入反馈"
+	case stateDone:
+		st = "已结束"
+	}
+	s := fmt.Sprintf(" %s · 第 %d 轮", st, m.iteration)
+	if sum := m.summary; sum != nil {
+		t := sum.Total
+		s += fmt.Sprintf(" · tokens 输入 %d 输出 %d 合计 %d", t.PromptTokens, t.CompletionTokens, t.TotalTokens)
+		if t.Cost > 0 {
+			s += fmt.Sprintf(" · 费用 %.4f %s", t.Cost, sum.Currency)
+		}
+	} else {
+		t := m.tokens
+		s += fmt.Sprintf(" · tokens 输入 %d 输出 %d 合计 %d", t.PromptTokens, t.CompletionTokens, t.TotalTokens)
+	}
+	if len(m.tools) > 0 {
+		s += fmt.Sprintf(" · 工具调用 %d", len(m.tools))
+	}
+	return s
+}
+
+// help 当前阶段可用的按键
+func (m *model) help() string {
+	nav := "↑↓ 滚动  t 工具面板"
+	if m.showTools {
+		nav += "  tab 切换焦点"
+		if m.toolFocus {
+			nav = "↑↓ 选择  enter 展开/折叠  t 工具面板  tab 切换焦点"
+		}
+	}
+	switch m.state {
+	case stateRunning:
+		return nav + "  q 退出"
+	case stateWaiting:
+		return "c 继续  f 反馈  e 修改问题  q 退出  " + nav
+	default:
+		return "e 修改问题  q 退出  " + nav
+	}
+}
+
+// prettyJSON 工具参数是合法 JSON 时缩进显示
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+func numbered(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, item)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Price 一个模型的单价，单位为每百万 token
+type Price struct {
+	Input       float64 `yaml:"input"`        // 输入（未命中缓存部分）
+	CachedInput float64 `yaml:"cached_input"` // 命中服务端上下文缓存的输入，为 0 时按 Input 计价
+	Output      float64 `yaml:"output"`       // 输出，思考（reasoning）token 包含在输出中，按输出计价
+}
+
+// PriceTable 价格表，按模型名称查找单价
+type PriceTable struct {
+	Currency string            `yaml:"currency"` // 货币单位，仅用于展示
+	Models   map[string]*Price `yaml:"models"`   // 模型名称（或名称前缀）-> 单价
+}
+
+// LoadPriceTable 从 YAML 文件加载价格表
+func LoadPriceTable(path string) (*PriceTable, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read price table: %w", err)
+	}
+	t := &PriceTable{}
+	if err := yaml.Unmarshal(bs, t); err != nil {
+		return nil, fmt.Errorf("parse price table %q: %w", path, err)
+	}
+	return t, nil
+}
+
+// PriceTableFromEnv 加载 USAGE_PRICES 指定的价格表，未设置时返回 nil（只统计 token，不计费用）
+func PriceTableFromEnv() (*PriceTable, error) {
+	path := os.Getenv("USAGE_PRICES")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadPriceTable(path)
+}
+
+// Lookup 查找模型的单价：先按名称精确匹配，再按最长前缀匹配
+// 例如 doubao-seed-1-8 可以匹配 doubao-seed-1-8-251228
+func (t *PriceTable) Lookup(model string) (*Price, bool) {
+	if t == nil {
+		return nil, false
+	}
+	if p, ok := t.Models[model]; ok {
+		return p, true
+	}
+	var best *Price
+	bestLen := 0
+	for name, p := range t.Models {
+		if len(name) > bestLen && strings.HasPrefix(model, name) {
+			best, bestLen = p, len(name)
+		}
+	}
+	return best, best != nil
+}
+
+// Cost 按单价计算一次调用的费用
+func (p *Price) Cost(u *Usage) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	uncached := u.PromptTokens - u.CachedTokens
+	return (float64(uncached)*p.Input +
+		float64(u.CachedTokens)*cachedPrice +
+		float64(u.CompletionTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,369 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	ucb "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// ErrBudgetExceeded 累计用量超过预算，Tracker 会取消运行的 context
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// Usage 一组调用的累计用量
+type Usage struct {
+	Calls            int
+	PromptTokens     int
+	CachedTokens     int // 命中服务端上下文缓存的输入 token，包含在 PromptTokens 中
+	CompletionTokens int
+	ReasoningTokens  int // 思考 token，包含在 CompletionTokens 中
+	TotalTokens      int
+	Cost             float64
+	Unpriced         bool // 有调用的模型不在价格表中，Cost 偏低
+}
+
+func (u *Usage) add(o *Usage) {
+	u.Calls += o.Calls
+	u.PromptTokens += o.PromptTokens
+	u.CachedTokens += o.CachedTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.ReasoningTokens += o.ReasoningTokens
+	u.TotalTokens += o.TotalTokens
+	u.Cost += o.Cost
+	u.Unpriced = u.Unpriced || o.Unpriced
+}
+
+// Budget 一次运行的预算，0 值表示不限制对应的维度
+type Budget struct {
+	MaxCost   float64 // 按价格表计算的费用上限
+	MaxTokens int     // 总 token 数上限
+}
+
+// BudgetFromEnv 从 USAGE_BUDGET_COST、USAGE_BUDGET_TOKENS 读取预算，都未设置时返回 nil
+func BudgetFromEnv() (*Budget, error) {
+	b := &Budget{}
+	if v := os.Getenv("USAGE_BUDGET_COST"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse USAGE_BUDGET_COST: %w", err)
+		}
+		b.MaxCost = f
+	}
+	if v := os.Getenv("USAGE_BUDGET_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse USAGE_BUDGET_TOKENS: %w", err)
+		}
+		b.MaxTokens = n
+	}
+	if b.MaxCost == 0 && b.MaxTokens == 0 {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// Summary 一次运行的用量汇总
+type Summary struct {
+	Currency string
+	Total    Usage
+	ByAgent  map[string]*Usage // agent 名称 -> 用量，不在 agent 中的调用记在空字符串下
+	ByNode   map[string]*Usage // 图节点名称 -> 用量
+	ByModel  map[string]*Usage // 模型名称 -> 用量
+}
+
+// Tracker 通过回调统计模型和 Embedder 的 token 用量与费用
+//
+// 按 agent、图节点、模型三个维度分别累计；agent 和节点取自调用所在的执行地址（compose.GetCurrentAddress），
+// 模型取自组件回调中的 Config.Model，组件没有提供时使用组件类型
+type Tracker struct {
+	prices *PriceTable
+	budget *Budget
+
+	pending sync.WaitGroup // 尚未读完的流式响应
+
+	mu      sync.Mutex
+	summary *Summary
+	cancel  context.CancelCauseFunc
+	err     error
+}
+
+// NewTracker 创建 Tracker，prices 为 nil 时只统计 token，budget 为 nil 时不限制
+func NewTracker(prices *PriceTable, budget *Budget) *Tracker {
+	t := &Tracker{prices: prices, budget: budget}
+	t.Reset()
+	return t
+}
+
+// NewTrackerFromEnv 按 USAGE_PRICES、USAGE_BUDGET_COST、USAGE_BUDGET_TOKENS 创建 Tracker
+func NewTrackerFromEnv() (*Tracker, error) {
+	prices, err := PriceTableFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	budget, err := BudgetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewTracker(prices, budget), nil
+}
+
+// Attach 把 Tracker 的回调挂到 ctx 上，返回的 ctx 用于 runner.Run 等调用
+//
+// 超出预算时返回的 ctx 会以 ErrBudgetExceeded 为原因被取消，正在进行和后续的模型调用随之失败；
+// 调用方应在运行结束后调用 cancel 释放资源
+func (t *Tracker) Attach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	t.mu.Lock()
+	t.cancel = cancel
+	t.mu.Unlock()
+	ctx = callbacks.InitCallbacks(ctx, nil, t.Handler())
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// Handler 返回统计用量的回调，也可以通过 callbacks.AppendGlobalHandlers 全局注册（此时预算只记录不取消）
+func (t *Tracker) Handler() callbacks.Handler {
+	return ucb.NewHandlerHelper().
+		ChatModel(&ucb.ModelCallbackHandler{
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+				t.record(ctx, info, modelName(info, output.Config), chatUsage(TokenUsage(output)))
+				return ctx
+			},
+			OnEndWithStreamOutput: func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[*model.CallbackOutput]) context.Context {
+				t.pending.Add(1)
+				go func() {
+					defer t.pending.Done()
+					defer output.Close()
+					var cfg *model.Config
+					var tu *model.TokenUsage
+					for {
+						chunk, err := output.Recv()
+						if err != nil {
+							break
+						}
+						if chunk.Config != nil && cfg == nil {
+							cfg = chunk.Config
+						}
+						// 流式响应的用量一般只在最后一个 chunk 中
+						if u := TokenUsage(chunk); u != nil {
+							tu = u
+						}
+					}
+					t.record(ctx, info, modelName(info, cfg), chatUsage(tu))
+				}()
+				return ctx
+			},
+		}).
+		Embedding(&ucb.EmbeddingCallbackHandler{
+			OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *embedding.CallbackOutput) context.Context {
+				name := info.Type
+				if output.Config != nil && output.Config.Model != "" {
+					name = output.Config.Model
+				}
+				u := &Usage{Calls: 1}
+				if tu := output.TokenUsage; tu != nil {
+					u.PromptTokens, u.TotalTokens = tu.PromptTokens, tu.TotalTokens
+				}
+				t.record(ctx, info, name, u)
+				return ctx
+			},
+		}).
+		Handler()
+}
+
+func modelName(info *callbacks.RunInfo, cfg *model.Config) string {
+	if cfg != nil && cfg.Model != "" {
+		return cfg.Model
+	}
+	return info.Type
+}
+
+// TokenUsage 返回 ChatModel 回调输出中的用量，没有时返回 nil
+// 优先取回调中的 TokenUsage；组件自身不触发回调时由框架代为触发，只有消息中的 Usage
+func TokenUsage(o *model.CallbackOutput) *model.TokenUsage {
+	if o.TokenUsage != nil {
+		return o.TokenUsage
+	}
+	return MessageTokenUsage(o.Message)
+}
+
+// MessageTokenUsage 把消息中的 Usage 转换为回调使用的 TokenUsage，包含缓存和推理 token，没有时返回 nil
+func MessageTokenUsage(m *schema.Message) *model.TokenUsage {
+	if m == nil || m.ResponseMeta == nil || m.ResponseMeta.Usage == nil {
+		return nil
+	}
+	u := m.ResponseMeta.Usage
+	return &model.TokenUsage{
+		PromptTokens:       u.PromptTokens,
+		PromptTokenDetails: model.PromptTokenDetails{CachedTokens: u.PromptTokenDetails.CachedTokens},
+		CompletionTokens:   u.CompletionTokens,
+		TotalTokens:        u.TotalTokens,
+		CompletionTokensDetails: model.CompletionTokensDetails{
+			ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens,
+		},
+	}
+}
+
+func chatUsage(tu *model.TokenUsage) *Usage {
+	u := &Usage{Calls: 1}
+	if tu == nil {
+		return u
+	}
+	u.PromptTokens = tu.PromptTokens
+	u.CachedTokens = tu.PromptTokenDetails.CachedTokens
+	u.CompletionTokens = tu.CompletionTokens
+	u.ReasoningTokens = tu.CompletionTokensDetails.ReasoningTokens
+	u.TotalTokens = tu.TotalTokens
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return u
+}
+
+// location 从执行地址中取出最内层的 agent 和图节点
+func location(ctx context.Context, info *callbacks.RunInfo) (agent, node string) {
+	for _, seg := range compose.GetCurrentAddress(ctx) {
+		switch seg.Type {
+		case adk.AddressSegmentAgent:
+			agent = seg.ID
+		case compose.AddressSegmentNode:
+			node = seg.ID
+		}
+	}
+	if node == "" {
+		node = info.Name
+	}
+	return agent, node
+}
+
+func (t *Tracker) record(ctx context.Context, info *callbacks.RunInfo, modelName string, u *Usage) {
+	if p, ok := t.prices.Lookup(modelName); ok {
+		u.Cost = p.Cost(u)
+	} else if t.prices != nil && u.TotalTokens > 0 {
+		u.Unpriced = true
+	}
+	agent, node := location(ctx, info)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.summary
+	s.Total.add(u)
+	for _, kv := range []struct {
+		m   map[string]*Usage
+		key string
+	}{{s.ByAgent, agent}, {s.ByNode, node}, {s.ByModel, modelName}} {
+		if kv.m[kv.key] == nil {
+			kv.m[kv.key] = &Usage{}
+		}
+		kv.m[kv.key].add(u)
+	}
+
+	if t.err == nil && t.budget != nil &&
+		(t.budget.MaxCost > 0 && s.Total.Cost > t.budget.MaxCost ||
+			t.budget.MaxTokens > 0 && s.Total.TotalTokens > t.budget.MaxTokens) {
+		t.err = fmt.Errorf("%w: cost %.4f, tokens %d", ErrBudgetExceeded, s.Total.Cost, s.Total.TotalTokens)
+		if t.cancel != nil {
+			t.cancel(t.err)
+		}
+	}
+}
+
+// Err 超出预算时返回包装了 ErrBudgetExceeded 的错误，否则返回 nil
+func (t *Tracker) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Reset 清空已统计的用量和超预算状态，用于开始新的一次运行
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &Summary{
+		ByAgent: map[string]*Usage{},
+		ByNode:  map[string]*Usage{},
+		ByModel: map[string]*Usage{},
+	}
+	if t.prices != nil {
+		s.Currency = t.prices.Currency
+	}
+	t.summary = s
+	t.err = nil
+}
+
+// Summary 返回当前用量汇总的快照，会等待进行中的流式响应读完
+func (t *Tracker) Summary() *Summary {
+	t.pending.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &Summary{
+		Currency: t.summary.Currency,
+		Total:    t.summary.Total,
+		ByAgent:  copyUsages(t.summary.ByAgent),
+		ByNode:   copyUsages(t.summary.ByNode),
+		ByModel:  copyUsages(t.summary.ByModel),
+	}
+	return s
+}
+
+func copyUsages(m map[string]*Usage) map[string]*Usage {
+	c := make(map[string]*Usage, len(m))
+	for k, v := range m {
+		u := *v
+		c[k] = &u
+	}
+	return c
+}
+
+// Fprint 以表格形式输出汇总
+func (s *Summary) Fprint(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "维度\t名称\t调用\t输入\t缓存\t输出\t思考\t总计\t费用%s\n", currencySuffix(s.Currency))
+	for _, g := range []struct {
+		title string
+		m     map[string]*Usage
+	}{{"agent", s.ByAgent}, {"node", s.ByNode}, {"model", s.ByModel}} {
+		names := make([]string, 0, len(g.m))
+		for name := range g.m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if name == "" && g.title == "agent" {
+				writeRow(tw, g.title, "-", g.m[name])
+				continue
+			}
+			writeRow(tw, g.title, name, g.m[name])
+		}
+	}
+	writeRow(tw, "total", "", &s.Total)
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, dim, name string, u *Usage) {
+	cost := fmt.Sprintf("%.6f", u.Cost)
+	if u.Unpriced {
+		cost += "*" // 部分模型不在价格表中
+	}
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", dim, name, u.Calls, u.PromptTokens,
+		u.CachedTokens, u.CompletionTokens, u.ReasoningTokens, u.TotalTokens, cost)
+}
+
+func currencySuffix(c string) string {
+	if c == "" {
+		return ""
+	}
+	return "(" + c + ")"
+}
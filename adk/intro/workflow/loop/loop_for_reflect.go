@@ -6,114 +6,153 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
 
+	"eino-learn/adk/common/callbacklog"
+	"eino-learn/adk/common/metrics"
 	"eino-learn/adk/common/prints"
+	"eino-learn/adk/common/store"
+	"eino-learn/adk/common/trace"
+	"eino-learn/adk/common/transcript"
+	"eino-learn/adk/common/usage"
 	"eino-learn/adk/intro/workflow/loop/subagents"
 )
 
 func LoopAgent() {
 	ctx := context.Background()
 
-	fmt.Println("=== 人类参与的 Agent Loop ===")
-	fmt.Println("类似 Claude Code 的交互式智能体迭代模式")
+	// 按 TRACE_CONFIG、TRACE_BACKENDS 或各后端的环境变量上报链路，CozeLoop、OTel 和本地文件可以同时启用
+	closeTrace, startSpan := trace.AppendTracingIfConfigured(ctx)
+	defer closeTrace(ctx)
 
-	// 获取用户初始问题
-	fmt.Println("\n========== 查询示例 ==========")
-	fmt.Println("1. 帮我查看当前目录下有哪些文件")
-	fmt.Println("2. 帮我查看 main.go 文件的内容")
-	fmt.Println("3. 帮我查看系统的内存使用情况")
-	fmt.Println("4. 帮我搜索 main.go 中包含 'func' 的行")
-	fmt.Println("5. 帮我做系统健康检查")
-	fmt.Println("================================")
+	// 设置了 CALLBACK_LOG 时记录每个组件的输入输出、耗时和 token 用量
+	logHandler, closeLog, err := callbacklog.NewHandlerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeLog()
+	if logHandler != nil {
+		callbacks.AppendGlobalHandlers(logHandler)
+	}
 
-	query := getUserInput("请输入您的问题或任务（留空使用默认示例）：")
+	// 设置了 METRICS_ADDR 时在 /metrics 上提供模型、工具、检索器和图节点的 Prometheus 指标
+	metricsHandler, closeMetrics, err := metrics.NewHandlerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeMetrics()
+	if metricsHandler != nil {
+		callbacks.AppendGlobalHandlers(metricsHandler)
+	}
 
-	// 默认示例查询
-	if query == "" {
-		query = "帮我查看当前目录下有哪些文件，并查看 main.go 的前 10 行"
-		fmt.Printf("\n使用默认查询：%s\n\n", query)
+	// 设置了 TRANSCRIPT_DIR 时保存问题、每轮的事件和用户的选择，之后可以用 replay 子命令重新渲染或导出
+	rec, err := transcript.NewRecorderFromEnv("loop")
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer closeTranscript(rec)
 
-	if query == "" {
-		fmt.Println("未输入问题，退出")
+	// LOOP_UI=tui 时以全屏终端界面运行，事件在界面中显示，不使用 EVENT_FORMAT
+	if os.Getenv("LOOP_UI") == "tui" {
+		runTUI(ctx, startSpan, rec)
 		return
 	}
 
-	// 创建 LoopAgent
-	a, err := adk.NewLoopAgent(ctx, &adk.LoopAgentConfig{
-		Name:          "reflection_agent",
-		Description:   "反思型智能体，包含主智能体和改进智能体，用于迭代式任务解决",
-		SubAgents:     []adk.Agent{subagents.NewMainAgent(), subagents.NewCritiqueAgent()},
-		MaxIterations: 5,
-	})
+	// EVENT_FORMAT=jsonl|markdown|quiet 时以机器可读或对话记录的格式输出事件，EVENT_OUTPUT 指定输出位置
+	renderer, closeRenderer, err := prints.NewRendererFromEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer closeRenderer()
 
-	// 创建 Runner
-	runner := adk.NewRunner(ctx, adk.RunnerConfig{
-		EnableStreaming: true,
-		Agent:           a,
-	})
+	fmt.Println("=== 人类参与的 Agent Loop ===")
+	fmt.Println("类似 Claude Code 的交互式智能体迭代模式")
 
-	// 初始运行
-	messages := []adk.Message{schema.UserMessage(query)}
+	runner, ckptStore, err := newRunner(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	history, _ := ckptStore.(*store.HistoryStore)
 
-	// 开始迭代循环
-	iteration := 0
-	for {
-		iteration++
+	// 统计每个问题的 token 用量和费用，设置了 USAGE_BUDGET_* 时超出预算会中止运行
+	tracker, err := usage.NewTrackerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 设置了 LOOP_SESSION 时从该会话的检查点继续，直接进入等待反馈的选择，否则输入新问题
+	var (
+		sessionID = os.Getenv("LOOP_SESSION")
+		query     string
+		round     *roundResult
+		iter      *adk.AsyncIterator[*adk.AgentEvent]
+		cancel    context.CancelFunc
+		iteration int
+	)
+	if sessionID != "" {
+		var answer, interruptID string
+		query, answer, interruptID, err = loadSession(ctx, ckptStore, sessionID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\n恢复会话 %s，问题：%s\n", sessionID, query)
+		printResult("当前结果", answer)
+		round = &roundResult{result: answer, interruptID: interruptID}
+		iteration = 1
+	} else {
+		query = askQuery()
+		if query == "" {
+			fmt.Println("未输入问题，退出")
+			return
+		}
+		// 会话 ID 即检查点 ID
+		sessionID = uuid.NewString()
+	}
 
-		// 运行智能体
-		iter := runner.Run(ctx, messages)
+	// 整个会话作为一个根 span，各轮的 Agent、模型和工具 span 都挂在它下面
+	ctx, endSpan := startSpan(ctx, "human_in_the_loop", query)
+	defer endSpan(ctx, nil)
 
-		var currentResult string
-		var hasToolCall bool
+	rec.Input(sessionID, query)
+	if round == nil {
+		// 初始运行；运行的 ctx 带上 Agent 名称和会话 ID，SQLite、Redis 存储会随检查点一起记录
+		var runCtx context.Context
+		runCtx, cancel = tracker.Attach(store.WithMetadata(ctx, "human_in_the_loop", sessionID))
+		iter = rec.Wrap(runner.Run(runCtx, []adk.Message{schema.UserMessage(query)}, adk.WithCheckPointID(sessionID)))
+	}
 
-		// 收集智能体的响应
-		for {
-			event, ok := iter.Next()
-			if !ok {
-				break
-			}
-			if event.Err != nil {
-				fmt.Printf("❌ 错误: %v\n", event.Err)
-				break
+	// 开始迭代循环
+	for {
+		if round == nil {
+			iteration++
+
+			// 收集智能体的响应，直到人工反馈智能体中断
+			round = collectRound(iter, renderer)
+			cancel()
+			if round.exited {
+				printUsage(tracker)
+				fmt.Println("\n✓ 智能体认为已完成任务，退出循环")
+				printResult("最终结果", round.result)
+				return
 			}
 
-			prints.Event(event)
-			if event.Output != nil {
-				msg, _, err := adk.GetMessage(event)
-				if err != nil {
-					fmt.Printf("❌ 获取消息错误: %v\n", err)
-					break
-				}
-
-				if msg.Content != "" {
-					currentResult = msg.Content
-				}
-
-				// 检查是否有工具调用
-				if len(msg.ToolCalls) > 0 {
-					hasToolCall = true
-				}
-
-				// 检查是否有特殊动作（如退出循环）
-				if event.Action != nil {
-					if event.Action.Exit {
-						fmt.Println("\n✓ 智能体认为已完成任务，退出循环")
-						fmt.Println("╔═══════════════════════════════════════╗")
-						fmt.Println("║              最终结果                    ║")
-						fmt.Println("╚═══════════════════════════════════════╝")
-						fmt.Printf("%s\n", currentResult)
-						return
-					}
-				}
+			printUsage(tracker)
+			if err := tracker.Err(); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			if round.interruptID == "" {
+				// 出错或者外层循环已结束，没有可以恢复的检查点
+				printResult("当前结果", round.result)
+				return
 			}
 		}
 
@@ -124,23 +163,31 @@ func LoopAgent() {
 		fmt.Println("3. 修改问题 (调整原始需求)")
 		fmt.Println("4. 查看详情 (展开完整输出)")
 		fmt.Println("5. 退出循环 (接受当前结果)")
+		if history != nil {
+			fmt.Println("6. 回到之前的迭代 (从历史检查点分叉，换一个反馈重新开始)")
+		}
 		fmt.Println()
 
 		// 获取用户选择
-		choice := getUserInput("请选择操作 [1-5]（默认1）：")
+		maxChoice := 5
+		if history != nil {
+			maxChoice = 6
+		}
+		choice := getUserInput(fmt.Sprintf("请选择操作 [1-%d]（默认1）：", maxChoice))
 
+		// 恢复运行时传给人工反馈智能体的数据，空字符串表示不提反馈
+		feedback := ""
 		switch choice {
 		case "1", "":
 			// 继续下一轮迭代
+			rec.Choice(sessionID, iteration, transcript.ActionContinue, "")
 			fmt.Println("\n✓ 继续下一轮迭代...")
 
 		case "2":
 			// 提供反馈
-			feedback := getUserInput("请输入您的反馈意见：")
+			feedback = getUserInput("请输入您的反馈意见：")
+			rec.Choice(sessionID, iteration, transcript.ActionFeedback, feedback)
 			if feedback != "" {
-				messages = append(messages,
-					schema.UserMessage(fmt.Sprintf("用户反馈：%s\n请根据这个反馈继续改进您的方案。", feedback)),
-				)
 				fmt.Println("\n✓ 已加入反馈，继续下一轮迭代...")
 			}
 
@@ -148,18 +195,25 @@ func LoopAgent() {
 			// 修改问题
 			newQuery := getUserInput("请输入新的问题：")
 			if newQuery != "" {
-				messages = []adk.Message{schema.UserMessage(newQuery)}
+				rec.Choice(sessionID, iteration, transcript.ActionEditQuery, newQuery)
 				iteration = 0
+				tracker.Reset()
 				fmt.Println("\n✓ 已更新问题，重新开始...")
+				sessionID = uuid.NewString()
+				rec.Input(sessionID, newQuery)
+				var runCtx context.Context
+				runCtx, cancel = tracker.Attach(store.WithMetadata(ctx, "human_in_the_loop", sessionID))
+				iter = rec.Wrap(runner.Run(runCtx, []adk.Message{schema.UserMessage(newQuery)}, adk.WithCheckPointID(sessionID)))
+				round = nil
+				continue
 			}
+			rec.Choice(sessionID, iteration, transcript.ActionContinue, "")
 
 		case "4":
 			// 查看详情
-			if currentResult != "" {
-				fmt.Println("\n╔═══════════════════════════════════════╗")
-				fmt.Println("║              完整输出                    ║")
-				fmt.Println("╚═══════════════════════════════════════╝")
-				fmt.Println(currentResult)
+			rec.Choice(sessionID, iteration, transcript.ActionDetails, "")
+			if round.result != "" {
+				printResult("完整输出", round.result)
 				fmt.Println()
 				return
 			} else {
@@ -168,39 +222,310 @@ func LoopAgent() {
 
 		case "5":
 			// 退出循环
+			rec.Choice(sessionID, iteration, transcript.ActionQuit, "")
 			fmt.Println("\n✓ 用户选择退出")
-			fmt.Println("╔═══════════════════════════════════════╗")
-			fmt.Println("║              当前结果                    ║")
-			fmt.Println("╚═══════════════════════════════════════╝")
-			if currentResult != "" {
-				fmt.Printf("%s\n", currentResult)
-			}
+			printResult("当前结果", round.result)
 			return
 
+		case "6":
+			if history == nil {
+				fmt.Println("❌ 无效选项，退出")
+				return
+			}
+			n, forkID, interruptID, err := rewind(ctx, history, sessionID)
+			if err != nil {
+				fmt.Printf("❌ 回到之前的迭代失败: %v\n", err)
+				continue
+			}
+			feedback = getUserInput(fmt.Sprintf("请输入第 %d 轮之后的新反馈：", n))
+			fmt.Printf("\n✓ 已从第 %d 轮分叉出新会话 %s，之前的工具调用结果不会重新执行...\n", n, forkID)
+			rec.Choice(forkID, n, transcript.ActionRewind, feedback)
+			iteration = n
+			sessionID = forkID
+			round.interruptID = interruptID
+
 		default:
 			fmt.Println("❌ 无效选项，退出")
 			return
 		}
 
 		// 如果有工具调用被中断，需要将工具响应加入对话
-		if hasToolCall {
+		if round.hasToolCall {
 			fmt.Println("⚠️ 检测到工具调用，请等待工具执行完成...")
 		}
 
 		// 检查是否达到最大迭代次数
-		if iteration >= 5 {
-			fmt.Println("\n⚠️ 已达到最大迭代次数（5轮）")
-			fmt.Println("╔═══════════════════════════════════════╗")
-			fmt.Println("║              最终结果                    ║")
-			fmt.Println("╚═══════════════════════════════════════╝")
-			if currentResult != "" {
-				fmt.Printf("%s\n", currentResult)
-			}
+		if iteration >= maxIterations {
+			fmt.Printf("\n⚠️ 已达到最大迭代次数（%d轮）\n", maxIterations)
+			printResult("最终结果", round.result)
 			return
 		}
 
 		time.Sleep(500 * time.Millisecond)
+
+		// 从检查点恢复，把反馈交给人工反馈智能体
+		var runCtx context.Context
+		runCtx, cancel = tracker.Attach(store.WithMetadata(ctx, "human_in_the_loop", sessionID))
+		iter, err = runner.ResumeWithParams(runCtx, sessionID, &adk.ResumeParams{
+			Targets: map[string]any{round.interruptID: feedback},
+		})
+		if err != nil {
+			cancel()
+			fmt.Printf("❌ 恢复运行失败: %v\n", err)
+			if err := store.Release(ctx, ckptStore, sessionID); err != nil {
+				fmt.Printf("❌ 释放检查点失败: %v\n", err)
+			}
+			return
+		}
+		iter = rec.Wrap(iter)
+		round = nil
+	}
+}
+
+// askQuery 显示查询示例并读取用户的问题，留空时使用默认示例
+func askQuery() string {
+	fmt.Println("\n========== 查询示例 ==========")
+	for i, q := range exampleQueries {
+		fmt.Printf("%d. %s\n", i+1, q)
+	}
+	fmt.Println("================================")
+
+	query := getUserInput("请输入您的问题或任务（留空使用默认示例）：")
+	if query == "" {
+		query = defaultQuery
+		fmt.Printf("\n使用默认查询：%s\n\n", query)
 	}
+	return query
+}
+
+// newRunner 创建带检查点的人机协作循环，同时返回它使用的 CheckPointStore
+// 设置 CHECKPOINT_HISTORY=true 时返回的是保留每一轮检查点的 *store.HistoryStore
+func newRunner(ctx context.Context) (*adk.Runner, compose.CheckPointStore, error) {
+	// 创建 LoopAgent：主智能体和反馈智能体反复迭代，直到反馈智能体认为结果令人满意
+	reflection, err := adk.NewLoopAgent(ctx, &adk.LoopAgentConfig{
+		Name:          "reflection_agent",
+		Description:   "反思型智能体，包含主智能体和改进智能体，用于迭代式任务解决",
+		SubAgents:     []adk.Agent{subagents.NewMainAgent(), subagents.NewCritiqueAgent()},
+		MaxIterations: 5,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// 外层 LoopAgent 每轮反思结束后由人工反馈智能体中断，等待用户选择
+	a, err := adk.NewLoopAgent(ctx, &adk.LoopAgentConfig{
+		Name:          "human_in_the_loop",
+		Description:   "每轮反思结束后等待用户反馈的人机协作循环",
+		SubAgents:     []adk.Agent{reflection, subagents.NewFeedbackAgent()},
+		MaxIterations: maxIterations,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 每次中断都会保存检查点；设置 CHECKPOINT_HISTORY=true 时保留每一轮的检查点，可以回到之前的迭代
+	ckptStore, err := store.NewStoreFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 创建 Runner
+	runner := adk.NewRunner(ctx, adk.RunnerConfig{
+		EnableStreaming: true,
+		Agent:           a,
+		CheckPointStore: ckptStore,
+	})
+	return runner, ckptStore, nil
+}
+
+// exampleQueries 查询示例
+var exampleQueries = []string{
+	"帮我查看当前目录下有哪些文件",
+	"帮我查看 main.go 文件的内容",
+	"帮我查看系统的内存使用情况",
+	"帮我搜索 main.go 中包含 'func' 的行",
+	"帮我做系统健康检查",
+}
+
+// defaultQuery 未输入问题时使用的默认示例
+const defaultQuery = "帮我查看当前目录下有哪些文件，并查看 main.go 的前 10 行"
+
+// maxIterations 人工反馈的最大轮数
+const maxIterations = 5
+
+// roundResult 一轮运行的结果
+type roundResult struct {
+	result      string
+	hasToolCall bool
+	exited      bool
+	interruptID string // 人工反馈智能体的中断 ID，恢复运行时作为 ResumeParams 的 key
+}
+
+// collectRound 打印一轮运行的事件并收集结果
+func collectRound(iter *adk.AsyncIterator[*adk.AgentEvent], renderer prints.EventRenderer) *roundResult {
+	r := &roundResult{}
+	for {
+		event, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if event.Err != nil {
+			fmt.Printf("❌ 错误: %v\n", event.Err)
+			break
+		}
+
+		if err := renderer.Render(event); err != nil {
+			fmt.Printf("❌ 输出事件错误: %v\n", err)
+			break
+		}
+		if event.Action != nil && event.Action.Interrupted != nil {
+			r.interruptID = rootInterruptID(event.Action.Interrupted.InterruptContexts)
+		}
+		if event.Output != nil {
+			msg, _, err := adk.GetMessage(event)
+			if err != nil {
+				fmt.Printf("❌ 获取消息错误: %v\n", err)
+				break
+			}
+
+			if msg.Content != "" && msg.Role != schema.User {
+				r.result = msg.Content
+			}
+
+			// 检查是否有工具调用
+			if len(msg.ToolCalls) > 0 {
+				r.hasToolCall = true
+			}
+
+			// 检查是否有特殊动作（如退出循环）
+			if event.Action != nil && event.Action.Exit {
+				r.exited = true
+				return r
+			}
+		}
+	}
+	return r
+}
+
+// rootInterruptID 返回中断链中根源的 ID，即人工反馈智能体的中断点
+func rootInterruptID(contexts []*adk.InterruptCtx) string {
+	for _, ic := range contexts {
+		if ic.IsRootCause {
+			return ic.ID
+		}
+	}
+	return ""
+}
+
+// rewind 列出会话的历史检查点，让用户选择一轮并从该轮分叉出新会话
+// 返回选择的轮数、新会话 ID，以及该检查点中人工反馈智能体的中断 ID
+func rewind(ctx context.Context, history *store.HistoryStore, sessionID string) (int, string, string, error) {
+	versions, err := history.Versions(ctx, sessionID)
+	if err != nil {
+		return 0, "", "", err
+	}
+	fmt.Println("\n========== 历史检查点 ==========")
+	for _, v := range versions {
+		fmt.Printf("第 %d 轮  %d 字节  %s\n", v.N, v.Size, v.CreatedAt.Format(time.TimeOnly))
+	}
+	n, err := strconv.Atoi(getUserInput("请选择要回到第几轮之后："))
+	if err != nil {
+		return 0, "", "", fmt.Errorf("无效的轮数: %w", err)
+	}
+
+	bs, ok, err := history.GetVersion(ctx, sessionID, n)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if !ok {
+		return 0, "", "", fmt.Errorf("没有第 %d 轮的检查点", n)
+	}
+	snap, err := store.DecodeCheckpoint(bs)
+	if err != nil {
+		return 0, "", "", err
+	}
+	interruptID := ""
+	for _, it := range snap.Interrupts {
+		if it.IsRootCause {
+			interruptID = it.ID
+		}
+	}
+
+	forkID := uuid.NewString()
+	if err := history.Fork(ctx, sessionID, n, forkID); err != nil {
+		return 0, "", "", err
+	}
+	return n, forkID, interruptID, nil
+}
+
+// loadSession 读取已有会话的检查点，返回原始问题、最近一轮的回答和人工反馈智能体的中断 ID
+// 只读取不占用检查点，随后的 ResumeWithParams 才会占用
+func loadSession(ctx context.Context, ckptStore compose.CheckPointStore, sessionID string) (string, string, string, error) {
+	bs, ok, err := ckptStore.Get(store.WithReadOnly(ctx), sessionID)
+	if err != nil {
+		return "", "", "", err
+	}
+	if !ok {
+		return "", "", "", fmt.Errorf("没有找到会话 %s 的检查点", sessionID)
+	}
+	snap, err := store.DecodeCheckpoint(bs)
+	if err != nil {
+		return "", "", "", err
+	}
+	interruptID := ""
+	for _, it := range snap.Interrupts {
+		if it.IsRootCause {
+			interruptID = it.ID
+		}
+	}
+	if interruptID == "" {
+		return "", "", "", fmt.Errorf("会话 %s 没有等待反馈的中断", sessionID)
+	}
+
+	query := ""
+	for _, m := range snap.Input {
+		if m.Role == schema.User {
+			query = m.Content
+		}
+	}
+	// 与 collectRound 一致，取最后一条非用户消息的内容作为当前结果
+	answer := ""
+	for _, m := range snap.Messages {
+		if m.Message != nil && m.Message.Content != "" && m.Message.Role != schema.User {
+			answer = m.Message.Content
+		}
+	}
+	return query, answer, interruptID, nil
+}
+
+// printResult 输出带标题的结果
+func printResult(title, result string) {
+	fmt.Println("╔═══════════════════════════════════════╗")
+	fmt.Printf("║              %s                    ║\n", title)
+	fmt.Println("╚═══════════════════════════════════════╝")
+	if result != "" {
+		fmt.Printf("%s\n", result)
+	}
+}
+
+// closeTranscript 写完会话记录并输出文件路径，没有输入过问题时不会创建文件，也不输出
+func closeTranscript(rec *transcript.Recorder) {
+	if rec == nil {
+		return
+	}
+	if err := rec.Close(); err != nil {
+		fmt.Printf("❌ 保存会话记录失败: %v\n", err)
+		return
+	}
+	if rec.Path() == "" {
+		return
+	}
+	fmt.Printf("\n会话记录已保存到 %s，可以用 go run . replay 重新查看\n", rec.Path())
+}
+
+// printUsage 输出当前问题累计的 token 用量和费用
+func printUsage(tracker *usage.Tracker) {
+	fmt.Println("\n========== Token 用量 ==========")
+	_ = tracker.Summary().Fprint(os.Stdout)
 }
 
 // getUserInput 获取用户输入
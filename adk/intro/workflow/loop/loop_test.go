@@ -0,0 +1,237 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/store"
+)
+
+// eventSummary 一个 AgentEvent 中需要断言的部分
+type eventSummary struct {
+	agent     string
+	role      schema.RoleType
+	toolCall  string // 发起的工具调用名称
+	toolName  string // 工具结果对应的工具名称
+	content   string
+	interrupt bool
+}
+
+// runRound 读完一轮运行的所有事件，返回事件摘要和根中断 ID
+func runRound(t *testing.T, iter *adk.AsyncIterator[*adk.AgentEvent]) ([]eventSummary, string) {
+	t.Helper()
+	var events []eventSummary
+	var interruptID string
+	for {
+		event, ok := iter.Next()
+		if !ok {
+			return events, interruptID
+		}
+		if event.Err != nil {
+			t.Fatalf("event error: %v", event.Err)
+		}
+		s := eventSummary{agent: event.AgentName}
+		if event.Output != nil {
+			msg, _, err := adk.GetMessage(event)
+			if err != nil {
+				t.Fatalf("get message: %v", err)
+			}
+			s.role, s.toolName = msg.Role, msg.ToolName
+			// 工具结果是命令的实际输出，不参与比较
+			if msg.Role != schema.Tool {
+				s.content = msg.Content
+			}
+			for _, tc := range msg.ToolCalls {
+				s.toolCall = tc.Function.Name
+			}
+		}
+		if event.Action != nil && event.Action.Interrupted != nil {
+			s.interrupt = true
+			interruptID = rootInterruptID(event.Action.Interrupted.InterruptContexts)
+		}
+		events = append(events, s)
+	}
+}
+
+// useFixture 让所有智能体按 fake_fixture.yaml 回复，检查点保存在内存中
+func useFixture(t *testing.T) {
+	t.Helper()
+	t.Setenv("MODEL_TYPE", "fake")
+	t.Setenv("FAKE_MODEL_FIXTURE", "fake_fixture.yaml")
+	t.Setenv("MODEL_CONFIG", "")
+	t.Setenv("MODEL_CASSETTE", "")
+	t.Setenv("CHECKPOINT_SQLITE", "")
+	t.Setenv("CHECKPOINT_REDIS", "")
+	t.Setenv("CHECKPOINT_DIR", "")
+	t.Setenv("CHECKPOINT_KEYS", "")
+	t.Setenv("CHECKPOINT_HISTORY", "")
+}
+
+func assertEvents(t *testing.T, got, want []eventSummary) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestLoopAgentFixture 按 fake_fixture.yaml 离线运行两轮：第一轮主智能体执行命令后被评审通过，
+// 人工反馈智能体中断；提供反馈后第二轮主智能体补充说明，再次中断等待反馈
+func TestLoopAgentFixture(t *testing.T) {
+	useFixture(t)
+
+	ctx := context.Background()
+	runner, _, err := newRunner(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const sessionID = "test-session"
+	got, interruptID := runRound(t, runner.Run(ctx, []adk.Message{schema.UserMessage(defaultQuery)}, adk.WithCheckPointID(sessionID)))
+	assertEvents(t, got, []eventSummary{
+		{agent: "main_agent", role: schema.Assistant, toolCall: "execute_command", content: "我先查看当前目录的文件。"},
+		{agent: "main_agent", role: schema.Tool, toolName: "execute_command"},
+		{agent: "main_agent", role: schema.Assistant, content: "当前目录下的文件已经列出，main.go 是程序入口。"},
+		{agent: "critique_agent", role: schema.Assistant, toolCall: "submit_critique"},
+		{agent: "critique_agent", role: schema.Tool, toolName: "submit_critique"},
+		{agent: "human_feedback", interrupt: true},
+	})
+	if interruptID == "" {
+		t.Fatal("round 1 did not interrupt for feedback")
+	}
+
+	iter, err := runner.ResumeWithParams(ctx, sessionID, &adk.ResumeParams{
+		Targets: map[string]any{interruptID: "请补充各目录的用途"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, interruptID = runRound(t, iter)
+	assertEvents(t, got, []eventSummary{
+		{agent: "human_feedback", role: schema.User, content: "用户反馈：请补充各目录的用途\n请根据这个反馈继续改进您的方案。"},
+		{agent: "main_agent", role: schema.Assistant, content: "补充说明：adk 目录是智能体示例，compose 目录是编排示例。"},
+		{agent: "critique_agent", role: schema.Assistant, toolCall: "submit_critique"},
+		{agent: "critique_agent", role: schema.Tool, toolName: "submit_critique"},
+		{agent: "human_feedback", interrupt: true},
+	})
+	if interruptID == "" {
+		t.Fatal("round 2 did not interrupt for feedback")
+	}
+}
+
+// withStdin 让 getUserInput 在 fn 执行期间读到 input
+func withStdin(t *testing.T, input string, fn func()) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+		r.Close()
+	}()
+	fn()
+}
+
+// TestLoopAgentRewind 开启检查点历史后运行两轮，再回到第一轮之后换一个反馈继续，原会话不受影响
+func TestLoopAgentRewind(t *testing.T) {
+	useFixture(t)
+	t.Setenv("CHECKPOINT_HISTORY", "true")
+
+	ctx := context.Background()
+	runner, ckptStore, err := newRunner(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, ok := ckptStore.(*store.HistoryStore)
+	if !ok {
+		t.Fatalf("checkpoint store = %T, want *store.HistoryStore", ckptStore)
+	}
+
+	const sessionID = "test-session"
+	_, interruptID := runRound(t, runner.Run(ctx, []adk.Message{schema.UserMessage(defaultQuery)}, adk.WithCheckPointID(sessionID)))
+	iter, err := runner.ResumeWithParams(ctx, sessionID, &adk.ResumeParams{
+		Targets: map[string]any{interruptID: "请补充各目录的用途"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runRound(t, iter)
+
+	// 每次中断保存一个版本
+	versions, err := history.Versions(ctx, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("versions = %+v, want 2", versions)
+	}
+	query, answer, _, err := loadSession(ctx, history, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与 collectRound 一致，当前结果为最后一条非用户消息，即评审通过时的总结
+	if query != defaultQuery || answer != "已按用户反馈补充各目录的用途。" {
+		t.Fatalf("session = %q, %q", query, answer)
+	}
+
+	var n int
+	var forkID, forkInterruptID string
+	withStdin(t, "1\n", func() {
+		n, forkID, forkInterruptID, err = rewind(ctx, history, sessionID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || forkID == "" || forkID == sessionID || forkInterruptID == "" {
+		t.Fatalf("rewind = %d, %q, %q", n, forkID, forkInterruptID)
+	}
+	// 分叉出的会话停在第一轮之后
+	_, answer, loadedID, err := loadSession(ctx, history, forkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "已列出当前目录下的文件，main.go 为程序入口。" || loadedID != forkInterruptID {
+		t.Fatalf("fork session = %q, %q; want round 1 and interrupt %q", answer, loadedID, forkInterruptID)
+	}
+
+	iter, err = runner.ResumeWithParams(ctx, forkID, &adk.ResumeParams{
+		Targets: map[string]any{forkInterruptID: "换个角度说明 main.go"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, interruptID := runRound(t, iter)
+	assertEvents(t, got, []eventSummary{
+		{agent: "human_feedback", role: schema.User, content: "用户反馈：换个角度说明 main.go\n请根据这个反馈继续改进您的方案。"},
+		{agent: "main_agent", role: schema.Assistant, content: "换个角度：main.go 只负责加载配置并启动示例。"},
+		{agent: "critique_agent", role: schema.Assistant, toolCall: "submit_critique"},
+		{agent: "critique_agent", role: schema.Tool, toolName: "submit_critique"},
+		{agent: "human_feedback", interrupt: true},
+	})
+	if interruptID == "" {
+		t.Fatal("forked round did not interrupt for feedback")
+	}
+
+	// 原会话仍停在第二轮之后
+	if _, answer, _, err := loadSession(ctx, history, sessionID); err != nil || answer != "已按用户反馈补充各目录的用途。" {
+		t.Fatalf("original session = %q, %v", answer, err)
+	}
+	if _, _, _, err := loadSession(ctx, history, "missing"); err == nil {
+		t.Fatal("want error for a missing session")
+	}
+}
@@ -12,7 +12,8 @@ import (
 	"github.com/cloudwego/eino/components/tool/utils"
 	"github.com/cloudwego/eino/compose"
 
-	"eino-learn/adk/common/model"
+	"eino-learn/adk/common/middleware"
+	"eino-learn/adk/common/structured"
 )
 
 func NewMainAgent() adk.Agent {
@@ -60,6 +61,11 @@ func NewMainAgent() adk.Agent {
 		log.Fatalf("创建命令行工具失败，name=%v, err=%v", "execute_command", err)
 	}
 
+	cm, err := middleware.NewAgentChatModel(context.Background(), "main_agent")
+	if err != nil {
+		log.Fatalf("create chat model failed, agent=%v, err=%v", "main_agent", err)
+	}
+
 	a, err := adk.NewChatModelAgent(context.Background(), &adk.ChatModelAgentConfig{
 		Name:        "main_agent",
 		Description: "主智能体，负责尝试解决用户的任务",
@@ -74,7 +80,7 @@ func NewMainAgent() adk.Agent {
 - 如果收到反馈智能体的改进建议，请认真对待并在下一轮中改进
 - 不断优化你的答案，直到提供完整、准确的解决方案
 - 使用命令工具时，确保命令格式正确，特别是引号和特殊字符`,
-		Model: model.NewChatModel(),
+		Model: cm,
 		ToolsConfig: adk.ToolsConfig{
 			ToolsNodeConfig: compose.ToolsNodeConfig{
 				Tools: []tool.BaseTool{
@@ -91,13 +97,18 @@ func NewMainAgent() adk.Agent {
 }
 
 func NewCritiqueAgent() adk.Agent {
-	exitAndSummarizeTool, err := utils.InferTool("exit_and_summarize", "退出循环并提供最终总结",
-		func(ctx context.Context, req *exitAndSummarize) (string, error) {
-			_ = adk.SendToolGenAction(ctx, "exit_and_summarize", adk.NewBreakLoopAction("critique_agent"))
-			return req.Summary, nil
-		})
+	submitCritiqueTool, err := utils.InferTool("submit_critique", "提交对主智能体回答的结构化评审结果", submitCritique)
+	if err != nil {
+		log.Fatalf("create tool failed, name=%v, err=%v", "submit_critique", err)
+	}
+	cm, err := middleware.NewAgentChatModel(context.Background(), "critique_agent")
+	if err != nil {
+		log.Fatalf("create chat model failed, agent=%v, err=%v", "critique_agent", err)
+	}
+	// 评审结果不符合 schema（例如评分超出范围）时带着错误原因让模型重新提交，不把无效结果交给工具
+	cm, err = structured.NewToolGuard[Critique](cm, &structured.Config{Name: "submit_critique"})
 	if err != nil {
-		log.Fatalf("create tool failed, name=%v, err=%v", "exit_and_summarize", err)
+		log.Fatalf("create chat model failed, agent=%v, err=%v", "critique_agent", err)
 	}
 	a, err := adk.NewChatModelAgent(context.Background(), &adk.ChatModelAgentConfig{
 		Name:        "critique_agent",
@@ -107,21 +118,24 @@ func NewCritiqueAgent() adk.Agent {
 你的任务：
 1. 审查主智能体的方案和执行结果
 2. 如果发现问题或需要改进，提供具体、明确的反馈意见
-3. 如果主智能体的回答令人满意，调用 'exit_and_summarize' 工具并总结最终结果
+3. 审查完成后调用 'submit_critique' 工具提交评审结果：
+   - 回答令人满意时 verdict 为 pass，并在 summary 中总结最终结果
+   - 需要改进时 verdict 为 revise，在 issues 中逐条列出问题
+   - score 为 0 到 10 的质量评分
 
 重要：
-- 你输出的反馈会直接传递给主智能体，用于下一轮改进
-- 反馈要具体明确，指出问题和改进方向
+- 需要改进时 issues 会直接传递给主智能体，用于下一轮改进
+- 每条问题要具体明确，指出问题和改进方向
 - 不要只是重复问题，要给出建设性建议`,
-		Model: model.NewChatModel(),
+		Model: cm,
 		ToolsConfig: adk.ToolsConfig{
 			ToolsNodeConfig: compose.ToolsNodeConfig{
 				Tools: []tool.BaseTool{
-					exitAndSummarizeTool,
+					submitCritiqueTool,
 				},
 			},
 			ReturnDirectly: map[string]bool{
-				"exit_and_summarize": true,
+				"submit_critique": true,
 			},
 		},
 	})
@@ -131,10 +145,6 @@ func NewCritiqueAgent() adk.Agent {
 	return a
 }
 
-type exitAndSummarize struct {
-	Summary string `json:"summary" jsonschema_description:"解决方案的最终总结"`
-}
-
 // ExecuteCommandRequest 执行命令的请求参数
 type ExecuteCommandRequest struct {
 	Command string `json:"command" jsonschema_description:"要执行的命令（如：ls -la, pwd, cat file.txt）"`
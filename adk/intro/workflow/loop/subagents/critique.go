@@ -0,0 +1,61 @@
+package subagents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+)
+
+// 评审结论
+const (
+	VerdictPass   = "pass"
+	VerdictRevise = "revise"
+)
+
+// Critique critique_agent 提交的结构化评审结果，作为 submit_critique 工具的参数
+// 结论、评分和问题列表会随工具调用记录在事件和会话记录中，供需要机器可读结论的流程使用
+type Critique struct {
+	Verdict string   `json:"verdict" jsonschema:"enum=pass,enum=revise,description=pass 表示回答令人满意，revise 表示需要改进"`
+	Score   int      `json:"score" jsonschema:"minimum=0,maximum=10,description=回答质量评分，0 到 10"`
+	Issues  []string `json:"issues" jsonschema:"description=需要改进的具体问题，verdict 为 pass 时可以为空数组"`
+	Summary string   `json:"summary,omitempty" jsonschema:"description=verdict 为 pass 时对最终结果的总结"`
+}
+
+// Validate 评分必须在 0 到 10 之间，需要改进时必须指出具体问题
+func (c *Critique) Validate() error {
+	if c.Score < 0 || c.Score > 10 {
+		return fmt.Errorf("score %d 超出 0 到 10 的范围", c.Score)
+	}
+	switch c.Verdict {
+	case VerdictPass:
+		return nil
+	case VerdictRevise:
+		if len(c.Issues) == 0 {
+			return errors.New("verdict 为 revise 时 issues 不能为空")
+		}
+		return nil
+	}
+	return fmt.Errorf("未知的 verdict %q", c.Verdict)
+}
+
+// submitCritique submit_critique 工具的实现
+// 通过时退出反思循环并返回总结；需要改进时把问题列表作为反馈交给下一轮的主智能体
+// 模型经 structured.NewToolGuard 重新提问后仍提交了无效结果时，本轮按需要改进处理
+func submitCritique(ctx context.Context, c *Critique) (string, error) {
+	if err := c.Validate(); err != nil {
+		return fmt.Sprintf("评审结果无效（%v），本轮视为需要改进，请主智能体重新检查回答。", err), nil
+	}
+	if c.Verdict == VerdictPass {
+		_ = adk.SendToolGenAction(ctx, "submit_critique", adk.NewBreakLoopAction("critique_agent"))
+		return c.Summary, nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "评审结论：需要改进（评分 %d/10）\n", c.Score)
+	for i, issue := range c.Issues {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, issue)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
@@ -0,0 +1,47 @@
+package subagents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// FeedbackAgentName 人工反馈智能体的名称
+const FeedbackAgentName = "human_feedback"
+
+// NewFeedbackAgent 创建人工反馈智能体：每轮反思结束后中断，等待用户通过 Runner.ResumeWithParams 给出反馈
+//
+// 恢复数据为用户的反馈文本，非空时作为用户消息写入会话历史，下一轮主智能体即可看到；
+// 为空表示不提反馈直接进入下一轮。之前各轮的工具调用结果保存在检查点的会话历史中，恢复时不会重新执行
+func NewFeedbackAgent() adk.ResumableAgent {
+	return &feedbackAgent{}
+}
+
+type feedbackAgent struct{}
+
+func (f *feedbackAgent) Name(ctx context.Context) string {
+	return FeedbackAgentName
+}
+
+func (f *feedbackAgent) Description(ctx context.Context) string {
+	return "人工反馈智能体，每轮结束后等待用户给出改进意见"
+}
+
+func (f *feedbackAgent) Run(ctx context.Context, input *adk.AgentInput, opts ...adk.AgentRunOption) *adk.AsyncIterator[*adk.AgentEvent] {
+	iter, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	gen.Send(adk.Interrupt(ctx, "请审阅本轮结果并给出反馈"))
+	gen.Close()
+	return iter
+}
+
+func (f *feedbackAgent) Resume(ctx context.Context, info *adk.ResumeInfo, opts ...adk.AgentRunOption) *adk.AsyncIterator[*adk.AgentEvent] {
+	iter, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	if feedback, _ := info.ResumeData.(string); info.IsResumeTarget && feedback != "" {
+		msg := schema.UserMessage(fmt.Sprintf("用户反馈：%s\n请根据这个反馈继续改进您的方案。", feedback))
+		gen.Send(adk.EventFromMessage(msg, nil, schema.User, ""))
+	}
+	gen.Close()
+	return iter
+}
@@ -0,0 +1,148 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"eino-learn/adk/common/store"
+	"eino-learn/adk/common/trace"
+	"eino-learn/adk/common/transcript"
+	"eino-learn/adk/common/tui"
+	"eino-learn/adk/common/usage"
+)
+
+// runTUI 以全屏终端界面运行人机协作循环，退出后输出最后一轮的回答和用量
+// 界面中不支持回到之前的迭代，需要时使用文本界面；设置了 LOOP_SESSION 时从该会话的检查点继续
+func runTUI(ctx context.Context, startSpan trace.StartSpanFn, rec *transcript.Recorder) {
+	runner, ckptStore, err := newRunner(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tracker, err := usage.NewTrackerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &loopSession{base: ctx, runner: runner, ckptStore: ckptStore, tracker: tracker, startSpan: startSpan, rec: rec}
+	defer s.close()
+	opts := tui.Options{
+		Title:        "人类参与的 Agent Loop",
+		Examples:     exampleQueries,
+		DefaultQuery: defaultQuery,
+	}
+	if id := os.Getenv("LOOP_SESSION"); id != "" {
+		if opts.Query, opts.Answer, opts.InterruptID, err = loadSession(ctx, ckptStore, id); err != nil {
+			log.Fatal(err)
+		}
+		s.resume(id, opts.Query)
+	}
+	answer, err := tui.Run(ctx, s, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if s.sessionID == "" {
+		return
+	}
+	rec.Choice(s.sessionID, s.iteration, transcript.ActionQuit, "")
+	printUsage(tracker)
+	printResult("当前结果", answer)
+}
+
+// loopSession 把人机协作循环包装成 tui.Session
+// 每个问题使用新的检查点会话和新的根 span，各轮运行都挂在根 span 下
+type loopSession struct {
+	base      context.Context
+	runner    *adk.Runner
+	ckptStore compose.CheckPointStore
+	tracker   *usage.Tracker
+	startSpan trace.StartSpanFn
+	rec       *transcript.Recorder
+
+	ctx       context.Context // 当前问题的根 span 所在的 context
+	endSpan   trace.EndSpanFn
+	cancel    context.CancelFunc
+	sessionID string
+	iteration int
+}
+
+func (s *loopSession) Run(ctx context.Context, query string) (*adk.AsyncIterator[*adk.AgentEvent], error) {
+	if s.sessionID != "" {
+		s.rec.Choice(s.sessionID, s.iteration, transcript.ActionEditQuery, query)
+	}
+	s.close()
+	s.ctx, s.endSpan = s.startSpan(s.base, "human_in_the_loop", query)
+	s.tracker.Reset()
+	s.sessionID = uuid.NewString()
+	s.iteration = 1
+	s.rec.Input(s.sessionID, query)
+	iter := s.runner.Run(s.attach(ctx), []adk.Message{schema.UserMessage(query)}, adk.WithCheckPointID(s.sessionID))
+	return s.rec.Wrap(iter), nil
+}
+
+// resume 接着已有的会话，之后的 Resume 从该会话的检查点恢复
+func (s *loopSession) resume(sessionID, query string) {
+	s.ctx, s.endSpan = s.startSpan(s.base, "human_in_the_loop", query)
+	s.sessionID = sessionID
+	s.iteration = 1
+	s.rec.Input(s.sessionID, query)
+}
+
+func (s *loopSession) Resume(ctx context.Context, interruptID, feedback string) (*adk.AsyncIterator[*adk.AgentEvent], error) {
+	if s.iteration >= maxIterations {
+		return nil, fmt.Errorf("已达到最大迭代次数（%d轮）", maxIterations)
+	}
+	if feedback == "" {
+		s.rec.Choice(s.sessionID, s.iteration, transcript.ActionContinue, "")
+	} else {
+		s.rec.Choice(s.sessionID, s.iteration, transcript.ActionFeedback, feedback)
+	}
+	s.iteration++
+	iter, err := s.runner.ResumeWithParams(s.attach(ctx), s.sessionID, &adk.ResumeParams{
+		Targets: map[string]any{interruptID: feedback},
+	})
+	if err != nil {
+		s.cancel()
+		if rerr := store.Release(s.base, s.ckptStore, s.sessionID); rerr != nil {
+			return nil, fmt.Errorf("恢复运行失败: %w（释放检查点失败: %v）", err, rerr)
+		}
+		return nil, fmt.Errorf("恢复运行失败: %w", err)
+	}
+	return s.rec.Wrap(iter), nil
+}
+
+func (s *loopSession) Usage() *usage.Summary {
+	return s.tracker.Summary()
+}
+
+// attach 返回一轮运行使用的 context：带有根 span、用量统计和检查点的元数据，ctx 结束时一起取消
+func (s *loopSession) attach(ctx context.Context) context.Context {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	runCtx, cancel := s.tracker.Attach(store.WithMetadata(s.ctx, "human_in_the_loop", s.sessionID))
+	stop := context.AfterFunc(ctx, cancel)
+	s.cancel = func() {
+		stop()
+		cancel()
+	}
+	return runCtx
+}
+
+// close 取消还在进行的运行并结束根 span
+func (s *loopSession) close() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	if s.endSpan != nil {
+		s.endSpan(s.ctx, nil)
+		s.endSpan = nil
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/middleware"
 )
 
 func ChatStream() {
@@ -15,7 +17,7 @@ func ChatStream() {
 
 	timeout := 30 * time.Second
 	// 初始化模型
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
+	arkModel, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
 		APIKey:  os.Getenv("ARK_API_KEY"),
 		Model:   "doubao-seed-1-8-251228",
 		Timeout: &timeout,
@@ -23,6 +25,11 @@ func ChatStream() {
 	if err != nil {
 		panic(err)
 	}
+	// 按 providers.ark 的策略限流、重试；设置了 MODEL_CACHE 时相同输入直接返回缓存的响应
+	model, err := middleware.WrapChatModel("ark", "doubao-seed-1-8-251228", arkModel)
+	if err != nil {
+		panic(err)
+	}
 
 	// 准备消息
 	messages := []*schema.Message{
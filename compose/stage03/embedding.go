@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/cloudwego/eino-ext/components/embedding/ark"
+
+	"eino-learn/adk/common/middleware"
 )
 
 func EmbedText() {
@@ -14,7 +16,7 @@ func EmbedText() {
 	// 初始化嵌入器
 	timeout := 30 * time.Second
 	apiType := ark.APITypeMultiModal
-	embedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
+	arkEmbedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
 		APIKey:  os.Getenv("ARK_API_KEY"),
 		Model:   "doubao-embedding-vision-250615",
 		APIType: &apiType,
@@ -23,6 +25,11 @@ func EmbedText() {
 	if err != nil {
 		panic(err)
 	}
+	// 按 providers.ark 的策略限流、重试；设置了 MODEL_CACHE 时缓存向量，MODEL_CASSETTE 时录制或回放
+	embedder, err := middleware.WrapEmbedder("ark", "doubao-embedding-vision-250615", arkEmbedder)
+	if err != nil {
+		panic(err)
+	}
 
 	// 生成文本向量
 	texts := []string{
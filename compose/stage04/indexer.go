@@ -11,6 +11,8 @@ import (
 	"github.com/cloudwego/eino-ext/components/indexer/milvus"
 	"github.com/cloudwego/eino/schema"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+
+	"eino-learn/adk/common/middleware"
 )
 
 var collection = "tt" // 表会自动创建, 并且自动load
@@ -49,7 +51,7 @@ func IndexerRAG(docs []*schema.Document) {
 	// 初始化嵌入器
 	timeout := 30 * time.Second
 	apiType := ark.APITypeMultiModal
-	embedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
+	arkEmbedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
 		APIKey:  os.Getenv("ARK_API_KEY"),
 		Model:   "doubao-embedding-vision-250615",
 		APIType: &apiType,
@@ -58,6 +60,11 @@ func IndexerRAG(docs []*schema.Document) {
 	if err != nil {
 		panic(err)
 	}
+	// 按 providers.ark 的策略限流、重试；设置了 MODEL_CACHE 时缓存向量，MODEL_CASSETTE 时录制或回放
+	embedder, err := middleware.WrapEmbedder("ark", "doubao-embedding-vision-250615", arkEmbedder)
+	if err != nil {
+		panic(err)
+	}
 
 	indexer, err := milvus.NewIndexer(ctx, &milvus.IndexerConfig{
 		Client:     MilvusCli,
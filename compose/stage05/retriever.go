@@ -2,6 +2,7 @@ package stage05
 
 import (
 	"context"
+	"eino-learn/adk/common/middleware"
 	"eino-learn/compose/stage04"
 	"os"
 	"time"
@@ -15,7 +16,7 @@ func RetrieverRAG(query string) []*schema.Document {
 	ctx := context.Background()
 	timeout := 30 * time.Second
 	apiType := ark.APITypeMultiModal
-	embedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
+	arkEmbedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
 		APIKey:  os.Getenv("ARK_API_KEY"),
 		Model:   "doubao-embedding-vision-250615",
 		APIType: &apiType,
@@ -24,6 +25,11 @@ func RetrieverRAG(query string) []*schema.Document {
 	if err != nil {
 		panic(err)
 	}
+	// 按 providers.ark 的策略限流、重试；设置了 MODEL_CACHE 时缓存向量，MODEL_CASSETTE 时录制或回放
+	embedder, err := middleware.WrapEmbedder("ark", "doubao-embedding-vision-250615", arkEmbedder)
+	if err != nil {
+		panic(err)
+	}
 	retriever, err := milvus.NewRetriever(ctx, &milvus.RetrieverConfig{
 		Client:      stage04.MilvusCli,
 		Collection:  "tt",
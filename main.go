@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"eino-learn/adk/common/inspect"
 	"eino-learn/adk/intro/workflow/loop"
+	"errors"
+	"io"
+	"io/fs"
 	"log"
+	"os"
 
 	// ccb "github.com/cloudwego/eino-ext/callbacks/cozeloop"
 	// "github.com/cloudwego/eino/callbacks"
@@ -11,8 +17,35 @@ import (
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
+	// 所有子命令和示例都先加载 .env；子命令在 .env 不存在时直接使用环境变量，格式错误时一律退出
+	envErr := godotenv.Load()
+	if envErr != nil && !errors.Is(envErr, fs.ErrNotExist) {
+		log.Fatalf("Error loading .env file: %v", envErr)
+	}
+
+	// 子命令：
+	//   go run . checkpoint list / show <id> / diff <id1> <id2>
+	//   go run . trace html|chrome [traces.jsonl]
+	//   go run . replay [-format text|markdown|html] [-o 输出文件] <会话记录文件>
+	if len(os.Args) > 1 {
+		var run func(ctx context.Context, args []string, w io.Writer) error
+		switch os.Args[1] {
+		case "checkpoint":
+			run = inspect.RunCheckpoint
+		case "trace":
+			run = inspect.RunTrace
+		case "replay":
+			run = inspect.RunReplay
+		}
+		if run != nil {
+			if err := run(context.Background(), os.Args[2:], os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	if envErr != nil {
 		log.Fatal("Error loading .env file")
 	}
 
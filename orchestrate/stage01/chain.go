@@ -9,13 +9,15 @@ import (
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
+
+	"eino-learn/adk/common/middleware"
 )
 
 func OrcChain() {
 	ctx := context.Background()
 	timeout := 30 * time.Second
 	// 初始化模型
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
+	arkModel, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
 		APIKey:  os.Getenv("ARK_API_KEY"),
 		Model:   "doubao-seed-1-8-251228",
 		Timeout: &timeout,
@@ -23,6 +25,11 @@ func OrcChain() {
 	if err != nil {
 		panic(err)
 	}
+	// 按 providers.ark 的策略限流、重试；设置了 MODEL_CACHE 时相同输入直接返回缓存的响应
+	model, err := middleware.WrapChatModel("ark", "doubao-seed-1-8-251228", arkModel)
+	if err != nil {
+		panic(err)
+	}
 	// 创建一个Lambda节点，用于处理输入的文本
 	// 注意节点之间输入和输出的类型要匹配
 	lambda := compose.InvokableLambda(func(ctx context.Context, input string) (output []*schema.Message, err error) {
@@ -2,7 +2,6 @@ package stage01
 
 import (
 	"context"
-	"eino-learn/compose/stage07"
 	"fmt"
 	"log"
 	"os"
@@ -15,6 +14,9 @@ import (
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	callbackHelpers "github.com/cloudwego/eino/utils/callbacks"
+
+	"eino-learn/adk/common/middleware"
+	"eino-learn/compose/stage07"
 )
 
 func SimpleAgent() {
@@ -47,12 +49,15 @@ func SimpleAgent() {
 		ChatModel(modelHandler).
 		Tool(toolHandler).
 		Handler()
-	// 初始化模型
+	// 初始化模型，按 providers.ark 的策略限流、重试
+	// MODEL_TYPE=fake 时按 FAKE_MODEL_FIXTURE（如 orchestrate/stage01/fake_fixture.yaml）中 simple_agent 的脚本回复
 	timeout := 30 * time.Second
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
-		APIKey:  os.Getenv("ARK_API_KEY"),
-		Model:   "doubao-seed-1-8-251228",
-		Timeout: &timeout,
+	chatModel, err := middleware.NewChatModel(ctx, "simple_agent", "ark", "doubao-seed-1-8-251228", func() (model.ToolCallingChatModel, error) {
+		return ark.NewChatModel(ctx, &ark.ChatModelConfig{
+			APIKey:  os.Getenv("ARK_API_KEY"),
+			Model:   "doubao-seed-1-8-251228",
+			Timeout: &timeout,
+		})
 	})
 	if err != nil {
 		panic(err)
@@ -65,7 +70,7 @@ func SimpleAgent() {
 	infos := []*schema.ToolInfo{
 		info,
 	}
-	err = model.BindTools(infos)
+	chatModel, err = chatModel.WithTools(infos)
 	if err != nil {
 		panic(err)
 	}
@@ -80,7 +85,7 @@ func SimpleAgent() {
 	}
 	//创建完整的处理链
 	chain := compose.NewChain[[]*schema.Message, []*schema.Message]().
-		AppendChatModel(model, compose.WithNodeName("chat_model")).
+		AppendChatModel(chatModel, compose.WithNodeName("chat_model")).
 		AppendToolsNode(ToolsNode, compose.WithNodeName("tools"))
 
 	// 编译并运行 chain
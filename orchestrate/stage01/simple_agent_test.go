@@ -0,0 +1,60 @@
+package stage01
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useFakeModel 让示例中的模型按 fake_fixture.yaml 回复，并切换到临时目录
+func useFakeModel(t *testing.T) {
+	t.Helper()
+	fixture, err := filepath.Abs("fake_fixture.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MODEL_TYPE", "fake")
+	t.Setenv("FAKE_MODEL_FIXTURE", fixture)
+	t.Setenv("MODEL_CONFIG", "")
+	t.Setenv("MODEL_CASSETTE", "")
+	t.Setenv("MODEL_CACHE", "")
+	t.Chdir(t.TempDir())
+}
+
+// captureStdout 返回 fn 执行期间输出到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		bs, _ := io.ReadAll(r)
+		done <- string(bs)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// TestSimpleAgentWithFakeModel 模型按脚本调用 get_game，tools 节点返回王者荣耀的 URL
+func TestSimpleAgentWithFakeModel(t *testing.T) {
+	useFakeModel(t)
+	out := captureStdout(t, SimpleAgent)
+	for _, want := range []string{
+		"用户想知道王者荣耀的网址，我调用 get_game 工具查询。",
+		`开始执行工具，参数: {"name": "王者荣耀"}`,
+		"https://pvp.qq.com/",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -3,9 +3,7 @@ package stage02
 import (
 	"context"
 	"fmt"
-	"os"
 
-	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -75,10 +73,7 @@ func OrcGraphWithCallback() {
 		return input, nil
 	}
 
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
-		APIKey: os.Getenv("ARK_API_KEY"),
-		Model:  "doubao-seed-1-8-251228",
-	})
+	model, err := newChatModel(ctx, "graph_with_callback")
 	if err != nil {
 		panic(err)
 	}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
@@ -76,10 +75,7 @@ func GenOrcGraphWithGraph(ctx context.Context) *compose.Graph[map[string]string,
 		return input, nil
 	}
 	// 创建模型节点
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
-		APIKey: os.Getenv("ARK_API_KEY"),
-		Model:  "doubao-seed-1-8-251228",
-	})
+	model, err := newChatModel(ctx, "graph_with_graph")
 	if err != nil {
 		panic(err)
 	}
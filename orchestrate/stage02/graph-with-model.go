@@ -3,9 +3,7 @@ package stage02
 import (
 	"context"
 	"fmt"
-	"os"
 
-	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
@@ -54,10 +52,7 @@ func OrcGraphWithModel() {
 		}, nil
 	})
 
-	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
-		APIKey: os.Getenv("ARK_API_KEY"),
-		Model:  "doubao-seed-1-8-251228",
-	})
+	model, err := newChatModel(ctx, "graph_with_model")
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,84 @@
+package stage02
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useFakeModel 让 newChatModel 按 fake_fixture.yaml 回复，并切换到临时目录，示例写出的文件不会留在源码目录中
+func useFakeModel(t *testing.T) {
+	t.Helper()
+	fixture, err := filepath.Abs("fake_fixture.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MODEL_TYPE", "fake")
+	t.Setenv("FAKE_MODEL_FIXTURE", fixture)
+	t.Setenv("MODEL_CONFIG", "")
+	t.Setenv("MODEL_CASSETTE", "")
+	t.Setenv("MODEL_CACHE", "")
+	t.Chdir(t.TempDir())
+}
+
+// captureStdout 返回 fn 执行期间输出到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		bs, _ := io.ReadAll(r)
+		done <- string(bs)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestGraphsWithFakeModel(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func()
+		want []string
+	}{
+		{"model", OrcGraphWithModel, []string{"哼，才、才不是特意来回答你的呢，你好就是了！"}},
+		{"state", OrcGraphWithState, []string{"嘻嘻，被摸头好开心呀，你好你好～"}},
+		{"callback", OrcGraphWithCallback, []string{
+			"你、你在说什么傻话！本小姐才不会因为这个就高兴呢。",
+			"当前ChatModel节点输入",
+		}},
+		{"graph", OutSideOrcGraph, []string{"已经写入文件，请前往文件内查看内容"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeModel(t)
+			out := captureStdout(t, tt.run)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestOutSideOrcGraphWritesAnswer(t *testing.T) {
+	useFakeModel(t)
+	captureStdout(t, OutSideOrcGraph)
+	bs, err := os.ReadFile("orc_graph_withgraph.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "你好啊～今天也要开开心心的哦！\n---\n"; string(bs) != want {
+		t.Fatalf("file = %q, want %q", bs, want)
+	}
+}
@@ -0,0 +1,26 @@
+package stage02
+
+import (
+	"context"
+	"os"
+
+	"github.com/cloudwego/eino-ext/components/model/ark"
+	"github.com/cloudwego/eino/components/model"
+
+	"eino-learn/adk/common/middleware"
+)
+
+const arkModelName = "doubao-seed-1-8-251228"
+
+// newChatModel 创建示例 graph 中的模型节点，name 为离线脚本中对应的名称
+//
+// 按 providers.ark 的策略限流、重试，设置了 MODEL_CACHE 时相同输入直接返回缓存的响应；
+// MODEL_TYPE=fake 时按 FAKE_MODEL_FIXTURE（如 orchestrate/stage02/fake_fixture.yaml）中的脚本回复
+func newChatModel(ctx context.Context, name string) (model.ToolCallingChatModel, error) {
+	return middleware.NewChatModel(ctx, name, "ark", arkModelName, func() (model.ToolCallingChatModel, error) {
+		return ark.NewChatModel(ctx, &ark.ChatModelConfig{
+			APIKey: os.Getenv("ARK_API_KEY"),
+			Model:  arkModelName,
+		})
+	})
+}